@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+type AggregateFunc string
+
+const (
+	AggCount AggregateFunc = "COUNT"
+	AggSum   AggregateFunc = "SUM"
+	AggAvg   AggregateFunc = "AVG"
+	AggMin   AggregateFunc = "MIN"
+	AggMax   AggregateFunc = "MAX"
+)
+
+// aggregate column of grouped query, Field is nil mean COUNT(*),
+// As is the result column name, it match the result struct field by sql name convert
+// e.g Aggregate{AggSum, Offsetof(Product{}.Price), "total_price"} => SUM(price) AS total_price
+type Aggregate struct {
+	Func  AggregateFunc
+	Field FieldSelection
+	As    string
+}
+
+// raw sql expression use as select column
+type exprColumn string
+
+func (c exprColumn) Column() string {
+	return string(c)
+}
+
+func aggregateExpr(fn AggregateFunc, field Field) string {
+	if field == nil {
+		return fmt.Sprintf("%s(*)", fn)
+	}
+	return fmt.Sprintf("%s(%s)", fn, field.Column())
+}
+
+// scan rows to *[]struct, *[]*struct or *[]map[string]interface{}, the struct field
+// match the column with sql name convert, the column which not match any field will discard
+func scanAggregateRows(rows *sql.Rows, v interface{}) error {
+	vValue := reflect.ValueOf(v)
+	if vValue.Kind() != reflect.Ptr || vValue.Elem().Kind() != reflect.Slice {
+		panic(ErrInvalidRecordType{})
+	}
+	sliceValue := vValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	isMap := elemType == reflect.TypeOf(map[string]interface{}{})
+	isPtr := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if isPtr {
+		structType = elemType.Elem()
+	}
+	if isMap == false && structType.Kind() != reflect.Struct {
+		panic(ErrInvalidRecordType{})
+	}
+	// column index => struct field index
+	fieldIndex := make([][]int, len(columns))
+	if isMap == false {
+		for i, column := range columns {
+			for j := 0; j < structType.NumField(); j++ {
+				if f := structType.Field(j); f.PkgPath == "" && SqlNameConvert(f.Name) == column {
+					fieldIndex[i] = f.Index
+					break
+				}
+			}
+		}
+	}
+
+	for rows.Next() {
+		dest := make([]interface{}, len(columns))
+		var elem reflect.Value
+		if isMap {
+			for i := range dest {
+				dest[i] = new(interface{})
+			}
+		} else {
+			elem = reflect.New(structType).Elem()
+			for i := range dest {
+				if fieldIndex[i] != nil {
+					dest[i] = elem.FieldByIndex(fieldIndex[i]).Addr().Interface()
+				} else {
+					dest[i] = new(interface{})
+				}
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if isMap {
+			m := map[string]interface{}{}
+			for i, column := range columns {
+				val := *(dest[i].(*interface{}))
+				if b, ok := val.([]byte); ok {
+					val = string(b)
+				}
+				m[column] = val
+			}
+			sliceValue.Set(reflect.Append(sliceValue, reflect.ValueOf(m)))
+		} else if isPtr {
+			sliceValue.Set(reflect.Append(sliceValue, elem.Addr()))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, elem))
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// array condition, postgres only
+const (
+	ExprContains SearchExpr = "@>"  // brick.Where(ExprContains, Offsetof(User{}.Tags), []string{"a", "b"}) // WHERE tags @> '{a,b}'
+	ExprOverlap  SearchExpr = "&&"  // brick.Where(ExprOverlap, Offsetof(User{}.Tags), []string{"a", "b"}) // WHERE tags && '{a,b}'
+	ExprAny      SearchExpr = "ANY" // brick.Where(ExprAny, Offsetof(User{}.Tags), "a") // WHERE 'a' = ANY(tags)
+)
+
+// the sql type of array element in postgres
+func postgresArrayType(elemType reflect.Type) string {
+	switch toSqlKind(elemType) {
+	case sqlKindBool:
+		return "BOOLEAN[]"
+	case sqlKindInt:
+		return "INTEGER[]"
+	case sqlKindBigInt, sqlKindUBigInt:
+		return "BIGINT[]"
+	case sqlKindFloat:
+		return "REAL[]"
+	case sqlKindDouble:
+		return "DOUBLE PRECISION[]"
+	case sqlKindString:
+		return "TEXT[]"
+	}
+	return ""
+}
+
+// array field value, it will encode to postgres array literal, e.g {"a","b"}
+// other database store the literal as text
+type arrayValue struct {
+	value reflect.Value
+}
+
+func (v arrayValue) Value() (driver.Value, error) {
+	if v.value.Kind() == reflect.Slice && v.value.IsNil() {
+		return nil, nil
+	}
+	buff := bytes.Buffer{}
+	buff.WriteByte('{')
+	for i := 0; i < v.value.Len(); i++ {
+		if i != 0 {
+			buff.WriteByte(',')
+		}
+		elem := v.value.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				buff.WriteString("NULL")
+				continue
+			}
+			elem = elem.Elem()
+		}
+		switch elem.Kind() {
+		case reflect.String:
+			s := strings.Replace(elem.String(), `\`, `\\`, -1)
+			buff.WriteString(`"` + strings.Replace(s, `"`, `\"`, -1) + `"`)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			buff.WriteString(strconv.FormatInt(elem.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			buff.WriteString(strconv.FormatUint(elem.Uint(), 10))
+		case reflect.Float32, reflect.Float64:
+			buff.WriteString(strconv.FormatFloat(elem.Float(), 'g', -1, 64))
+		case reflect.Bool:
+			buff.WriteString(strconv.FormatBool(elem.Bool()))
+		default:
+			return nil, fmt.Errorf("not support array element type %s", elem.Type())
+		}
+	}
+	buff.WriteByte('}')
+	return buff.String(), nil
+}
+
+func (v arrayValue) MarshalJSON() ([]byte, error) {
+	return jsonValue{v.value}.MarshalJSON()
+}
+
+var arrayValueType = reflect.TypeOf(arrayValue{})
+
+// parse postgres array literal to elements, nil element is NULL
+func parseArray(s string) ([]*string, error) {
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal %s", s)
+	}
+	s = s[1 : len(s)-1]
+	var elems []*string
+	for i := 0; i < len(s); {
+		var elem string
+		if s[i] == '"' {
+			var buff bytes.Buffer
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+				if i < len(s) {
+					buff.WriteByte(s[i])
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("invalid array literal {%s}", s)
+			}
+			i++
+			elem = buff.String()
+			elems = append(elems, &elem)
+		} else {
+			end := strings.IndexByte(s[i:], ',')
+			if end == -1 {
+				end = len(s) - i
+			}
+			elem = strings.TrimSpace(s[i : i+end])
+			if s[i] == '{' {
+				return nil, fmt.Errorf("not support multi-dimensional array {%s}", s)
+			}
+			if strings.ToUpper(elem) == "NULL" {
+				elems = append(elems, nil)
+			} else {
+				elems = append(elems, &elem)
+			}
+			i += end
+		}
+		if i < len(s) {
+			if s[i] != ',' {
+				return nil, fmt.Errorf("invalid array literal {%s}", s)
+			}
+			i++
+		}
+	}
+	return elems, nil
+}
+
+func setArrayElem(elem reflect.Value, s string) error {
+	if elem.Kind() == reflect.Ptr {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		elem = elem.Elem()
+	}
+	switch elem.Kind() {
+	case reflect.String:
+		elem.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		elem.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		elem.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		elem.SetFloat(v)
+	case reflect.Bool:
+		elem.SetBool(s == "t" || s == "true")
+	default:
+		return fmt.Errorf("not support array element type %s", elem.Type())
+	}
+	return nil
+}
+
+// decode the array literal to slice field, NULL will set nil slice
+type arrayScanner struct {
+	dest    reflect.Value // the pointer of field
+	missing func()
+}
+
+func (s arrayScanner) Scan(src interface{}) error {
+	elem := s.dest.Elem()
+	if elem.Kind() == reflect.Ptr {
+		if src != nil {
+			elem.Set(reflect.New(elem.Type().Elem()))
+			return arrayScanner{elem, s.missing}.Scan(src)
+		}
+	}
+	var str string
+	switch v := src.(type) {
+	case nil:
+		elem.Set(reflect.Zero(elem.Type()))
+		if s.missing != nil {
+			s.missing()
+		}
+		return nil
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	default:
+		return fmt.Errorf("cannot scan %T to array field %s", src, elem.Type())
+	}
+	list, err := parseArray(str)
+	if err != nil {
+		return err
+	}
+	slice := reflect.MakeSlice(elem.Type(), len(list), len(list))
+	for i, e := range list {
+		if e != nil {
+			if err := setArrayElem(slice.Index(i), *e); err != nil {
+				return err
+			}
+		}
+	}
+	elem.Set(slice)
+	return nil
+}
@@ -273,6 +273,17 @@ func makeRange(min, max int) (l []int) {
 	return
 }
 
+// the sorted keys of map with string key, use to iterate map in stable order
+func sortedMapKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
+
 func getFieldsWithRecords(fields []Field, records ModelRecordFieldTypes) []Field {
 	var selectFields []Field
 	for _, field := range fields {
@@ -540,31 +551,65 @@ func joinSwap(swap *JoinSwap, brick *ToyBrick) *JoinSwap {
 
 // get columns and scanner generator
 func FindColumnFactory(fieldTypes ModelRecordFieldTypes, brick *ToyBrick) ([]Column, func(ModelRecord) []interface{}) {
+	columns, fn := findColumnFactory(fieldTypes, brick, false, "")
+	return columns, func(record ModelRecord) []interface{} {
+		return fn(record, nil)
+	}
+}
+
+// nullable mean the record maybe missing in outer join, its columns will scan with nullableScanner,
+// missing will call when the keyName field scan NULL
+func findColumnFactory(fieldTypes ModelRecordFieldTypes, brick *ToyBrick, nullable bool, keyName string) ([]Column, func(record ModelRecord, missing func()) []interface{}) {
 	columns := brick.getSelectFields(fieldTypes).ToColumnList()
 	names := make([]string, 0, len(brick.JoinMap))
 	for name := range brick.JoinMap {
 		names = append(names, name)
+		// right join record will fill NULL in main model columns when it missing
+		if brick.JoinMap[name].MainNullable() {
+			nullable = true
+		}
 	}
 	sort.Strings(names)
-	nameFnMap := map[string]func(records ModelRecord) []interface{}{}
+	nameFnMap := map[string]func(ModelRecord, func()) []interface{}{}
 	for _, name := range names {
+		join := brick.JoinMap[name]
 		joinBrick := brick.Join(name)
-		subRecord := MakeRecord(brick.JoinMap[name].SubModel, LoopTypeIndirect(fieldTypes.GetFieldType(name)))
+		subRecord := MakeRecord(join.SubModel, LoopTypeIndirect(fieldTypes.GetFieldType(name)))
 
 		var subColumns []Column
-		subColumns, nameFnMap[name] = FindColumnFactory(subRecord, joinBrick)
+		subColumns, nameFnMap[name] = findColumnFactory(subRecord, joinBrick, nullable || join.SubNullable(), join.OnSub.Name())
 		columns = append(columns, subColumns...)
 	}
-	var fn func(records ModelRecord) []interface{}
-	fn = func(record ModelRecord) []interface{} {
+	var fn func(ModelRecord, func()) []interface{}
+	fn = func(record ModelRecord, missing func()) []interface{} {
 		var scanners []interface{}
 		for _, field := range brick.getScanFields(record) {
 			value := record.FieldAddress(field.Name())
-			scanners = append(scanners, value.Interface())
+			if nullable {
+				var missingFn func()
+				if field.Name() == keyName {
+					missingFn = missing
+				}
+				if scanner := encodedFieldScanner(field, value, missingFn); scanner != nil {
+					scanners = append(scanners, scanner)
+				} else {
+					scanners = append(scanners, nullableScanner{dest: value, missing: missingFn})
+				}
+			} else {
+				scanners = append(scanners, fieldScanner(field, value))
+			}
 		}
 		for _, name := range names {
-			subRecord := NewRecord(brick.JoinMap[name].SubModel, LoopIndirectAndNew(record.Field(name)))
-			scanners = append(scanners, nameFnMap[name](subRecord)...)
+			container := record.Field(name)
+			subRecord := NewRecord(brick.JoinMap[name].SubModel, LoopIndirectAndNew(container))
+			// set pointer container to nil when sub record missing
+			var subMissing func()
+			if container.Kind() == reflect.Ptr {
+				subMissing = func() {
+					container.Set(reflect.Zero(container.Type()))
+				}
+			}
+			scanners = append(scanners, nameFnMap[name](subRecord, subMissing)...)
 		}
 
 		return scanners
@@ -610,6 +655,130 @@ func insertValuesFormat(model *Model, columnValues []ColumnNameValue) (string, s
 	return string(fieldBytes), string(qBytes), args
 }
 
+// e.g return "a,b", "(?,?),(?,?)", [1,2,3,4]
+// all of values must have same insert columns
+func insertMultipleValuesFormat(model *Model, valuesList [][]ColumnNameValue) (string, string, []interface{}) {
+	var fieldStr string
+	var qList []string
+	var args []interface{}
+	for _, values := range valuesList {
+		var qStr string
+		var _args []interface{}
+		fieldStr, qStr, _args = insertValuesFormat(model, values)
+		qList = append(qList, "("+qStr+")")
+		args = append(args, _args...)
+	}
+	return fieldStr, strings.Join(qList, ","), args
+}
+
+const (
+	// default max records size of one insert statement, use brick.BatchSize to change it
+	DefaultBatchSize = 100
+	// the max args number of one insert statement, sqlite3 default variable limit is 999
+	maxInsertBatchArgs = 999
+)
+
+// group the consecutive records which have same insert columns, every group will insert in one statement
+// group size not more than batchSize and group args not more than maxInsertBatchArgs
+func insertBatchGroup(model *Model, valuesList [][]ColumnNameValue, batchSize int) [][]int {
+	var batches [][]int
+	var lastFieldStr string
+	var argsCount int
+	for i, values := range valuesList {
+		fieldStr, _, args := insertValuesFormat(model, values)
+		if n := len(batches); n != 0 &&
+			fieldStr == lastFieldStr &&
+			len(batches[n-1]) < batchSize &&
+			argsCount+len(args) <= maxInsertBatchArgs {
+			batches[n-1] = append(batches[n-1], i)
+			argsCount += len(args)
+		} else {
+			batches = append(batches, []int{i})
+			lastFieldStr, argsCount = fieldStr, len(args)
+		}
+	}
+	return batches
+}
+
+// set auto increment primary key with batch insert id list
+func setBatchInsertId(model *Model, records []ModelRecord, batch []int, ids []int64) error {
+	if len(model.GetPrimary()) != 1 {
+		return nil
+	}
+	primaryKey := model.GetOnePrimary()
+	primaryKeyName := primaryKey.Name()
+	if IntKind(primaryKey.StructField().Type.Kind()) == false {
+		return nil
+	}
+	// records in same batch have same insert columns, so their primary key either all zero or all not zero
+	if fieldValue := records[batch[0]].Field(primaryKeyName); fieldValue.IsValid() && !IsZero(fieldValue) {
+		return nil
+	}
+	if len(ids) != len(batch) {
+		return ErrLastInsertId{}
+	}
+	for j, i := range batch {
+		records[i].SetField(primaryKeyName, reflect.ValueOf(ids[j]))
+	}
+	return nil
+}
+
+// the insert fields need update when upsert conflict, conflict columns and primary key not update
+func upsertUpdateFields(model *Model, columnValues []ColumnNameValue, conflict []Column) []Field {
+	ignoreColumns := map[string]bool{}
+	for _, c := range conflict {
+		ignoreColumns[c.Column()] = true
+	}
+	for _, p := range model.GetPrimary() {
+		ignoreColumns[p.Column()] = true
+	}
+	// insertValuesFormat will ignore zero primary key and zero default field, so find the columns from it
+	fieldStr, _, _ := insertValuesFormat(model, columnValues)
+	var fields []Field
+	for _, column := range strings.Split(fieldStr, ",") {
+		if ignoreColumns[column] == false {
+			fields = append(fields, model.SqlFieldMap[column])
+		}
+	}
+	return fields
+}
+
+// e.g return " ON CONFLICT(name) DO UPDATE SET data = EXCLUDED.data,count = count + EXCLUDED.count" [args...]
+func onConflictFormat(model *Model, columnValues []ColumnNameValue, conflict []Column, strategies map[string]UpsertStrategy) (string, []interface{}) {
+	var conflictList []string
+	for _, c := range conflict {
+		conflictList = append(conflictList, c.Column())
+	}
+	var recordList []string
+	var args []interface{}
+	for _, r := range upsertUpdateFields(model, columnValues, conflict) {
+		strategy := strategies[r.Name()]
+		switch strategy.Action {
+		case UpsertOverwrite:
+			recordList = append(recordList, r.Column()+" = EXCLUDED."+r.Column())
+		case UpsertExpr:
+			recordList = append(recordList, r.Column()+" = "+strategy.Expr)
+			args = append(args, strategy.Args...)
+		}
+	}
+	if len(recordList) == 0 {
+		return fmt.Sprintf(" ON CONFLICT(%s) DO NOTHING", strings.Join(conflictList, ",")), nil
+	}
+	return fmt.Sprintf(" ON CONFLICT(%s) DO UPDATE SET %s", strings.Join(conflictList, ","), strings.Join(recordList, ",")), args
+}
+
+// e.g return "0xc420010000 isolation:Repeatable Read read only"
+func txDebugInfo(tx *sql.Tx, opts *sql.TxOptions) string {
+	s := fmt.Sprintf("%p", tx)
+	if tx != nil && opts != nil {
+		s += " isolation:" + opts.Isolation.String()
+		if opts.ReadOnly {
+			s += " read only"
+		}
+	}
+	return s
+}
+
 func IntKind(kind reflect.Kind) bool {
 	return kind >= reflect.Int && kind <= reflect.Uint64
 }
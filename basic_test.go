@@ -7,9 +7,11 @@
 package toyorm
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"reflect"
 	"testing"
+	"time"
 	. "unsafe"
 )
 
@@ -151,3 +153,186 @@ func TestLoopGetElemAndPtr(t *testing.T) {
 	t.Log(val.Interface())
 	assert.Equal(t, val.Type(), reflect.TypeOf(TestData{}))
 }
+
+func TestDialectSqlType(t *testing.T) {
+	type TestSqlTypeDecimal struct {
+		Value string
+	}
+	RegisterSqlType(TestSqlTypeDecimal{}, func(dialect string, size, precision, scale int) string {
+		if dialect == "sqlite3" {
+			return "TEXT"
+		}
+		return fmt.Sprintf("DECIMAL(%d,%d)", precision, scale)
+	})
+	type TestSqlTypeTable struct {
+		ID      uint64 `toyorm:"primary key"`
+		Name    string `toyorm:"size:64"`
+		Score   float64
+		Rate    float32
+		Data    []byte `toyorm:"size:100000"`
+		Created time.Time
+		Updated *time.Time         `toyorm:"precision:3"`
+		Price   TestSqlTypeDecimal `toyorm:"precision:10,2"`
+		Extra   string             `toyorm:"type:TEXT"`
+	}
+	model := NewModel(reflect.ValueOf(TestSqlTypeTable{}))
+	for _, d := range []struct {
+		dialect Dialect
+		types   []string
+	}{
+		{DefaultDialect{}, []string{"BIGINT", "VARCHAR(64)", "FLOAT", "FLOAT", "VARCHAR(255)", "TIMESTAMP", "TIMESTAMP", "DECIMAL(10,2)", "TEXT"}},
+		{MySqlDialect{}, []string{"BIGINT UNSIGNED", "VARCHAR(64)", "DOUBLE", "FLOAT", "MEDIUMBLOB", "TIMESTAMP", "TIMESTAMP(3)", "DECIMAL(10,2)", "TEXT"}},
+		{PostgreSqlDialect{}, []string{"BIGINT", "VARCHAR(64)", "DOUBLE PRECISION", "REAL", "BYTEA", "TIMESTAMP", "TIMESTAMP(3)", "DECIMAL(10,2)", "TEXT"}},
+		{Sqlite3Dialect{}, []string{"BIGINT", "VARCHAR(64)", "FLOAT", "FLOAT", "BLOB", "TIMESTAMP", "TIMESTAMP", "TEXT", "TEXT"}},
+	} {
+		var types []string
+		for _, field := range model.GetSqlFields() {
+			types = append(types, d.dialect.SqlType(field))
+		}
+		assert.Equal(t, d.types, types, "%T", d.dialect)
+	}
+}
+
+func TestArrayLiteral(t *testing.T) {
+	{
+		v, err := arrayValue{reflect.ValueOf([]string{"a", `b"c`, `d\e`, "f,g"})}.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, `{"a","b\"c","d\\e","f,g"}`, v)
+		list, err := parseArray(v.(string))
+		assert.Nil(t, err)
+		var elems []string
+		for _, e := range list {
+			elems = append(elems, *e)
+		}
+		assert.Equal(t, []string{"a", `b"c`, `d\e`, "f,g"}, elems)
+	}
+	{
+		v, err := arrayValue{reflect.ValueOf([]int64{1, -2, 3})}.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, "{1,-2,3}", v)
+		var dest []int64
+		assert.Nil(t, arrayScanner{dest: reflect.ValueOf(&dest)}.Scan([]byte("{1,NULL,3}")))
+		assert.Equal(t, []int64{1, 0, 3}, dest)
+	}
+	{
+		v, err := arrayValue{reflect.ValueOf([]string(nil))}.Value()
+		assert.Nil(t, err)
+		assert.Nil(t, v)
+		_, err = parseArray("{{1,2},{3,4}}")
+		assert.NotNil(t, err)
+		_, err = parseArray(`{"a`)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestArrayAndEnumSqlType(t *testing.T) {
+	type TestArrayEnumTable struct {
+		ID     uint32    `toyorm:"primary key"`
+		Tags   []string  `toyorm:"array"`
+		Scores []float64 `toyorm:"array"`
+		Status TestEnumStatus
+	}
+	model := NewModel(reflect.ValueOf(TestArrayEnumTable{}))
+	for _, d := range []struct {
+		dialect Dialect
+		types   []string
+	}{
+		{DefaultDialect{}, []string{"INTEGER", "TEXT", "TEXT", "VARCHAR(255)"}},
+		{MySqlDialect{}, []string{"INTEGER", "TEXT", "TEXT", "VARCHAR(255)"}},
+		{PostgreSqlDialect{}, []string{"INTEGER", "TEXT[]", "DOUBLE PRECISION[]", "toyorm_test_enum_status"}},
+		{Sqlite3Dialect{}, []string{"INTEGER", "TEXT", "TEXT", "VARCHAR(255)"}},
+	} {
+		var types []string
+		for _, field := range model.GetSqlFields() {
+			types = append(types, d.dialect.SqlType(field))
+		}
+		assert.Equal(t, d.types, types, "%T", d.dialect)
+	}
+	execs := PostgreSqlDialect{}.CreateTable(model, nil)
+	assert.Equal(t, `DO $$ BEGIN CREATE TYPE toyorm_test_enum_status AS ENUM ('active','disabled'); EXCEPTION WHEN duplicate_object THEN NULL; END $$`, execs[0].Query())
+	assert.NotContains(t, execs[1].Query(), "CHECK")
+	// migrate add the new value to exist enum type, and cast column type when change to enum
+	execs = PostgreSqlDialect{}.EnumTypeExecs(model.GetSqlFields(), map[string][]string{"toyorm_test_enum_status": {"active"}})
+	if assert.Equal(t, 1, len(execs)) {
+		assert.Equal(t, `ALTER TYPE toyorm_test_enum_status ADD VALUE IF NOT EXISTS 'disabled'`, execs[0].Query())
+	}
+	assert.Nil(t, PostgreSqlDialect{}.EnumTypeExecs(model.GetSqlFields(), map[string][]string{"toyorm_test_enum_status": {"active", "disabled"}}))
+	assert.Equal(t, []interface{}{"toyorm_test_enum_status"}, PostgreSqlDialect{}.EnumLabelsExec(model.GetSqlFields()).Args())
+	assert.Contains(t,
+		PostgreSqlDialect{}.AlterColumnExec(model, model.GetFieldWithName("Status")).Query(),
+		"ALTER COLUMN status TYPE toyorm_test_enum_status USING status::toyorm_test_enum_status",
+	)
+	assert.Nil(t, Sqlite3Dialect{}.EnumLabelsExec(model.GetSqlFields()))
+	execs = Sqlite3Dialect{}.CreateTable(model, nil)
+	assert.Contains(t, execs[0].Query(), "CHECK (status IN ('active','disabled'))")
+}
+
+func TestConstraintTag(t *testing.T) {
+	type TestConstraintUser struct {
+		ID uint32 `toyorm:"primary key"`
+	}
+	type TestConstraintOrder struct {
+		ID     uint32  `toyorm:"primary key"`
+		UserID uint32  `toyorm:"foreign key:on_delete=cascade,on_update=restrict;constraint:fk_order_user"`
+		Price  float64 `toyorm:"check:price > 0;constraint:chk_order_price"`
+		Count  int     `toyorm:"check:count::numeric >= 0"`
+		Start  string  `check:"start >= '12:00' AND start <> 'a;b'"`
+	}
+	user := NewModel(reflect.ValueOf(TestConstraintUser{}))
+	order := NewModel(reflect.ValueOf(TestConstraintOrder{}))
+	userID := order.GetFieldWithName("UserID")
+	assert.Equal(t, "CASCADE", userID.OnDelete())
+	assert.Equal(t, "RESTRICT", userID.OnUpdate())
+	assert.Equal(t, "fk_order_user", userID.Constraint())
+	assert.Equal(t, 0, len(userID.Attrs()))
+
+	foreign := map[string]ForeignKey{"UserID": {user, user.GetOnePrimary()}}
+	for _, d := range []struct {
+		dialect Dialect
+		foreign string
+	}{
+		{DefaultDialect{}, "CONSTRAINT fk_order_user FOREIGN KEY (user_id) REFERENCES `test_constraint_user`(id) ON DELETE CASCADE ON UPDATE RESTRICT"},
+		{MySqlDialect{}, "CONSTRAINT fk_order_user FOREIGN KEY (user_id) REFERENCES `test_constraint_user`(id) ON DELETE CASCADE ON UPDATE RESTRICT"},
+		{PostgreSqlDialect{}, `CONSTRAINT fk_order_user FOREIGN KEY (user_id) REFERENCES "test_constraint_user"(id) ON DELETE CASCADE ON UPDATE RESTRICT`},
+		{Sqlite3Dialect{}, "CONSTRAINT fk_order_user FOREIGN KEY (user_id) REFERENCES test_constraint_user(id) ON DELETE CASCADE ON UPDATE RESTRICT"},
+	} {
+		query := d.dialect.CreateTable(order, foreign)[0].Query()
+		assert.Contains(t, query, d.foreign, "%T", d.dialect)
+		assert.Contains(t, query, " CONSTRAINT chk_order_price CHECK (price > 0)", "%T", d.dialect)
+		assert.Contains(t, query, " CHECK (count::numeric >= 0)", "%T", d.dialect)
+		assert.Contains(t, query, " CHECK (start >= '12:00' AND start <> 'a;b')", "%T", d.dialect)
+	}
+	assert.Equal(t,
+		"ALTER TABLE `test_constraint_order` ADD CONSTRAINT fk_order_user FOREIGN KEY (user_id) REFERENCES `test_constraint_user`(id) ON DELETE CASCADE ON UPDATE RESTRICT",
+		MySqlDialect{}.AddForeignKey(order, user, userID).Query(),
+	)
+	assert.Equal(t, "ALTER TABLE `test_constraint_order` DROP FOREIGN KEY fk_order_user", MySqlDialect{}.DropForeignKey(order, userID).Query())
+	assert.Equal(t, `ALTER TABLE "test_constraint_order" DROP CONSTRAINT fk_order_user`, PostgreSqlDialect{}.DropForeignKey(order, userID).Query())
+
+	assert.Panics(t, func() {
+		type TestConstraintInvalid struct {
+			UserID uint32 `toyorm:"foreign key:on_delete=explode"`
+		}
+		NewModel(reflect.ValueOf(TestConstraintInvalid{}))
+	})
+}
+
+func TestSplitSqlStatements(t *testing.T) {
+	for _, d := range []struct {
+		sql        string
+		statements []string
+	}{
+		{"SELECT 1; SELECT ';'; -- comment;\nSELECT 2", []string{"SELECT 1", "SELECT ';'", "SELECT 2"}},
+		{"/* comment; */SELECT 1;/* multiple\nline; comment */ SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{
+			"CREATE FUNCTION f() RETURNS INTEGER AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS INTEGER AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			"DO $body$ BEGIN PERFORM '$$;'; END $body$; SELECT $1",
+			[]string{"DO $body$ BEGIN PERFORM '$$;'; END $body$", "SELECT $1"},
+		},
+	} {
+		assert.Equal(t, d.statements, splitSqlStatements(d.sql), d.sql)
+	}
+}
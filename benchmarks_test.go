@@ -8,6 +8,7 @@ package toyorm
 
 import (
 	"github.com/stretchr/testify/assert"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -71,6 +72,7 @@ func BenchmarkInsert(b *testing.B) {
 	brick := TestDB.Model(&TestBenchmarkTable{})
 	createTableUnit(brick)(b)
 	b.StartTimer()
+	b.ReportAllocs()
 	now := time.Now()
 	for n := 0; n < b.N; n++ {
 		result, err := brick.Insert(getTestBenchmarkTable(now))
@@ -158,6 +160,7 @@ func BenchmarkFind(b *testing.B) {
 		}
 	}
 	//b.StartTimer()
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		var data []TestBenchmarkTable
 		result, err := brick.Find(&data)
@@ -246,3 +249,62 @@ func BenchmarkUpdate(b *testing.B) {
 		}
 	}
 }
+
+// create brick with toy.Model every time, it's the common usage in web handler
+func BenchmarkModelInsert(b *testing.B) {
+	createTableUnit(TestDB.Model(&TestBenchmarkTable{}))(b)
+	b.ReportAllocs()
+	now := time.Now()
+	for n := 0; n < b.N; n++ {
+		result, err := TestDB.Model(&TestBenchmarkTable{}).Insert(getTestBenchmarkTable(now))
+		if err != nil {
+			b.Error(err)
+			b.FailNow()
+		}
+		if result.Err() != nil {
+			b.Error(result.Err())
+			b.FailNow()
+		}
+	}
+}
+
+func BenchmarkModelFind(b *testing.B) {
+	brick := TestDB.Model(&TestBenchmarkTable{})
+	createTableUnit(brick)(b)
+	result, err := brick.Insert(getTestBenchmarkTable(time.Now()))
+	if err != nil {
+		b.Error(err)
+		b.FailNow()
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var data []TestBenchmarkTable
+		result, err = TestDB.Model(&TestBenchmarkTable{}).Find(&data)
+		if err != nil {
+			b.Error(err)
+			b.FailNow()
+		}
+		if result.Err() != nil {
+			b.Error(result.Err())
+			b.FailNow()
+		}
+	}
+}
+
+func BenchmarkGetModel(b *testing.B) {
+	val := reflect.ValueOf(TestBenchmarkTable{})
+	b.Run("Cache", func(b *testing.B) {
+		kernel := ToyKernel{models: newModelCache()}
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			kernel.GetModel(val)
+		}
+	})
+	b.Run("NoCache", func(b *testing.B) {
+		kernel := ToyKernel{}
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			kernel.GetModel(val)
+		}
+	})
+}
@@ -70,6 +70,16 @@ func (t *BrickCommon) ToDesc(v interface{}) Field {
 	return t.TempField(v, "%s DESC")
 }
 
+// aggregate function wrapper of field, use for HAVING condition, e.g
+// AggregateField(AggSum, Offsetof(Product{}.Price)) => SUM(price)
+// v is nil mean count the primary key
+func (t *BrickCommon) AggregateField(fn AggregateFunc, v interface{}) Field {
+	if v == nil {
+		v = t.Model.GetOnePrimary()
+	}
+	return t.TempField(v, string(fn)+"(%s)")
+}
+
 func (t *BrickCommon) TempField(v interface{}, temp string) Field {
 	field := t.Model.fieldSelect(v)
 
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is the backend of query result cache, ttl <= 0 means never expire
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration)
+	Delete(key string)
+}
+
+// in-memory cache, the least recently used entry will be evicted when it's full
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key    string
+	value  interface{}
+	expire time.Time
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if ok == false {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if entry.expire.IsZero() == false && time.Now().After(entry.expire) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expire = value, expire
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key, value, expire})
+	for c.size > 0 && c.ll.Len() > c.size {
+		last := c.ll.Back()
+		c.ll.Remove(last)
+		delete(c.items, last.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// the toy side of cache, every model have a version, write model will increase it,
+// the cached entry is invalid when any model version of it is changed
+type queryCache struct {
+	Cache
+	mu sync.Mutex
+	// map[model name]version
+	versions map[string]uint64
+}
+
+// the cached value with the version of models which the query used
+type queryCacheEntry struct {
+	versions map[string]uint64
+	value    interface{}
+}
+
+func newQueryCache(cache Cache) *queryCache {
+	return &queryCache{Cache: cache, versions: map[string]uint64{}}
+}
+
+func (q *queryCache) get(key string) (interface{}, bool) {
+	value, ok := q.Get(key)
+	if ok == false {
+		return nil, false
+	}
+	entry, ok := value.(queryCacheEntry)
+	if ok == false {
+		return nil, false
+	}
+	q.mu.Lock()
+	for name, version := range entry.versions {
+		if q.versions[name] != version {
+			ok = false
+			break
+		}
+	}
+	q.mu.Unlock()
+	if ok == false {
+		q.Delete(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// the versions of models which the query used, it must be got before query,
+// so the write during query will invalidate the result
+func (q *queryCache) snapshot(models []string) map[string]uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	versions := make(map[string]uint64, len(models))
+	for _, name := range models {
+		versions[name] = q.versions[name]
+	}
+	return versions
+}
+
+func (q *queryCache) set(versions map[string]uint64, key string, value interface{}, ttl time.Duration) {
+	q.Set(key, queryCacheEntry{versions, value}, ttl)
+}
+
+func (q *queryCache) invalidate(model *Model) {
+	q.mu.Lock()
+	q.versions[model.Name]++
+	q.mu.Unlock()
+}
+
+// the models written in transaction, the non-transaction query may cache the old data before commit,
+// so invalidate them after commit
+type txWrites struct {
+	mu     sync.Mutex
+	models map[string]*Model
+}
+
+func (w *txWrites) add(model *Model) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.models == nil {
+		w.models = map[string]*Model{}
+	}
+	w.models[model.Name] = model
+}
+
+func (w *txWrites) invalidate(cache *queryCache) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, model := range w.models {
+		cache.invalidate(model)
+	}
+	w.models = nil
+}
+
+// the brick use cache when toy have cache and brick set ttl, transaction will not use cache
+func (t *ToyBrick) useCache() bool {
+	return t.Toy.cache != nil && t.cacheTTL != 0 && t.tx == nil
+}
+
+// cache key made with model, record type, query, args and preload conditions
+func (t *ToyBrick) cacheKey(action string, elemType reflect.Type, exec ExecValue) string {
+	key := fmt.Sprintf("%s|%s|%v|%s|%s", action, t.Model.Name, elemType, exec.Query(), exec.JsonArgs())
+	return key + preloadCacheKey(t)
+}
+
+func preloadCacheKey(brick *ToyBrick) string {
+	names := make([]string, 0, len(brick.MapPreloadBrick))
+	for name := range brick.MapPreloadBrick {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var key string
+	for _, name := range names {
+		sub := brick.MapPreloadBrick[name]
+		exec := sub.ConditionExec()
+		var fieldNames []string
+		for _, mode := range []Mode{ModeDefault, ModeSelect, ModeScan} {
+			for _, field := range sub.FieldsSelector[mode] {
+				fieldNames = append(fieldNames, field.Name())
+			}
+		}
+		key += fmt.Sprintf("|%s(%s%s%s%s)", name, strings.Join(fieldNames, ","), exec.Query(), exec.JsonArgs(), preloadCacheKey(sub))
+	}
+	return key
+}
+
+// the model names of brick used, contain join models, sub query models and preload models if preload is true
+func (t *ToyBrick) cacheModels(preload bool) []string {
+	models := []string{t.Model.Name}
+	for name := range t.JoinMap {
+		models = append(models, t.Join(name).cacheModels(preload)...)
+	}
+	for _, sub := range t.subQueryBricks() {
+		models = append(models, sub.cacheModels(false)...)
+	}
+	if preload {
+		for _, preload := range t.ManyToManyPreload {
+			models = append(models, preload.MiddleModel.Name)
+		}
+		for _, sub := range t.MapPreloadBrick {
+			models = append(models, sub.cacheModels(true)...)
+		}
+	}
+	return models
+}
+
+// deep copy the cached data, avoid the modification of user effect the cache
+func cacheCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.New(v.Type().Elem())
+		n.Elem().Set(cacheCopy(v.Elem()))
+		return n
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(cacheCopy(v.Index(i)))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeMap(v.Type())
+		for _, k := range v.MapKeys() {
+			n.SetMapIndex(k, cacheCopy(v.MapIndex(k)))
+		}
+		return n
+	case reflect.Struct:
+		n := reflect.New(v.Type()).Elem()
+		n.Set(v)
+		for i := 0; i < n.NumField(); i++ {
+			if n.Field(i).CanSet() {
+				n.Field(i).Set(cacheCopy(v.Field(i)))
+			}
+		}
+		return n
+	default:
+		return v
+	}
+}
+
+// set the query result cache of Find/Count, nil will disable it, the brick use it with brick.Cache(ttl)
+func (t *Toy) SetCache(cache Cache) {
+	if cache == nil {
+		t.cache = nil
+	} else {
+		t.cache = newQueryCache(cache)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sync"
 )
 
 type DBValSelector interface {
@@ -46,6 +47,9 @@ type ToyCollection struct {
 	dbs                      []*sql.DB
 	DefaultHandlerChain      map[string]CollectionHandlersChain
 	DefaultModelHandlerChain map[reflect.Type]map[string]CollectionHandlersChain
+	// the handler changes of model, they apply to the current default chain when get the model handlers
+	modelHandlerDeltas map[reflect.Type]map[string][]collectionHandlerDelta
+	handlerMu          sync.RWMutex
 	ToyKernel
 }
 
@@ -53,24 +57,27 @@ func OpenCollection(driverName string, dataSourceName ...string) (*ToyCollection
 	t := ToyCollection{
 		ToyKernel: ToyKernel{
 			Logger: os.Stdout,
+			models: newModelCache(),
 		},
 		DefaultHandlerChain: map[string]CollectionHandlersChain{
 			"CreateTable":              {CollectionHandlerSimplePreload("CreateTable"), CollectionHandlerAssignToAllDb, CollectionHandlerCreateTable},
 			"CreateTableIfNotExist":    {CollectionHandlerSimplePreload("CreateTableIfNotExist"), CollectionHandlerAssignToAllDb, CollectionHandlerExistTableAbort, CollectionHandlerCreateTable},
 			"DropTableIfExist":         {CollectionHandlerDropTablePreload("DropTableIfExist"), CollectionHandlerAssignToAllDb, CollectionHandlerNotExistTableAbort, CollectionHandlerDropTable},
 			"DropTable":                {CollectionHandlerDropTablePreload("DropTable"), CollectionHandlerAssignToAllDb, CollectionHandlerDropTable},
-			"Insert":                   {CollectionHandlerPreloadContainerCheck, CollectionHandlerPreloadInsertOrSave("Insert"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertTimeGenerate, CollectionHandlerInsertAssignDbIndex, CollectionHandlerInsert},
-			"Save":                     {CollectionHandlerPreloadContainerCheck, CollectionHandlerPreloadInsertOrSave("Save"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertAssignDbIndex, CollectionHandlerSaveTimeGenerate, CollectionHandlerSave},
-			"USave":                    {CollectionHandlerPreloadContainerCheck, CollectionHandlerPreloadInsertOrSave("USave"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertAssignDbIndex, CollectionHandlerSaveTimeGenerate, CollectionHandlerUSave},
-			"Find":                     {CollectionHandlerPreloadContainerCheck, CollectionHandlerSoftDeleteCheck, CollectionHandlerPreloadFind, CollectionHandlerAssignToAllDb, CollectionHandlerFind},
-			"FindOne":                  {CollectionHandlerPreloadContainerCheck, CollectionHandlerSoftDeleteCheck, CollectionHandlerPreloadFind, CollectionHandlerFindOneAssignDbIndex, CollectionHandlerFindOne},
-			"Update":                   {CollectionHandlerSoftDeleteCheck, CollectionHandlerUpdateTimeGenerate, CollectionHandlerAssignToAllDb, CollectionHandlerUpdate},
-			"HardDelete":               {HandlerCollectionPreloadDelete, CollectionHandlerAssignToAllDb, HandlerCollectionHardDelete},
-			"SoftDelete":               {HandlerCollectionPreloadDelete, CollectionHandlerAssignToAllDb, HandlerCollectionSoftDelete},
-			"HardDeleteWithPrimaryKey": {HandlerCollectionPreloadDelete, HandlerCollectionSearchWithPrimaryKey, CollectionHandlerAssignToAllDb, HandlerCollectionHardDelete},
-			"SoftDeleteWithPrimaryKey": {HandlerCollectionPreloadDelete, HandlerCollectionSearchWithPrimaryKey, CollectionHandlerAssignToAllDb, HandlerCollectionSoftDelete},
+			"Insert":                   {CollectionHandlerPreloadContainerCheck, CollectionHandlerBeforeInsertHook, CollectionHandlerAfterInsertHook, CollectionHandlerPreloadInsertOrSave("Insert"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertTimeGenerate, CollectionHandlerInsertAssignDbIndex, CollectionHandlerInsert},
+			"Save":                     {CollectionHandlerPreloadContainerCheck, CollectionHandlerBeforeSaveHook, CollectionHandlerAfterSaveHook, CollectionHandlerPreloadInsertOrSave("Save"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertAssignDbIndex, CollectionHandlerSaveTimeGenerate, CollectionHandlerSave},
+			"USave":                    {CollectionHandlerPreloadContainerCheck, CollectionHandlerBeforeSaveHook, CollectionHandlerAfterSaveHook, CollectionHandlerPreloadInsertOrSave("USave"), HandlerCollectionCasVersionPushOne, CollectionHandlerInsertAssignDbIndex, CollectionHandlerSaveTimeGenerate, CollectionHandlerUSave},
+			"Find":                     {CollectionHandlerPreloadContainerCheck, CollectionHandlerSoftDeleteCheck, CollectionHandlerAfterFindHook, CollectionHandlerPreloadFind, CollectionHandlerAssignToAllDb, CollectionHandlerFind},
+			"FindOne":                  {CollectionHandlerPreloadContainerCheck, CollectionHandlerSoftDeleteCheck, CollectionHandlerAfterFindHook, CollectionHandlerPreloadFind, CollectionHandlerFindOneAssignDbIndex, CollectionHandlerFindOne},
+			"Update":                   {CollectionHandlerSoftDeleteCheck, CollectionHandlerBeforeUpdateHook, CollectionHandlerAfterUpdateHook, CollectionHandlerUpdateTimeGenerate, CollectionHandlerUpdateVersion, CollectionHandlerAssignToAllDb, CollectionHandlerUpdate},
+			"HardDelete":               {CollectionHandlerBeforeDeleteHook, CollectionHandlerAfterDeleteHook, HandlerCollectionPreloadDelete, CollectionHandlerAssignToAllDb, HandlerCollectionHardDelete},
+			"SoftDelete":               {CollectionHandlerBeforeDeleteHook, CollectionHandlerAfterDeleteHook, HandlerCollectionPreloadDelete, CollectionHandlerAssignToAllDb, HandlerCollectionSoftDelete},
+			"HardDeleteWithPrimaryKey": {CollectionHandlerBeforeDeleteHook, CollectionHandlerAfterDeleteHook, HandlerCollectionPreloadDelete, HandlerCollectionSearchWithPrimaryKey, CollectionHandlerAssignToAllDb, HandlerCollectionHardDelete},
+			"SoftDeleteWithPrimaryKey": {CollectionHandlerBeforeDeleteHook, CollectionHandlerAfterDeleteHook, HandlerCollectionPreloadDelete, HandlerCollectionSearchWithPrimaryKey, CollectionHandlerAssignToAllDb, HandlerCollectionSoftDelete},
+			"RestoreWithPrimaryKey":    {HandlerCollectionPreloadRestore, HandlerCollectionSearchWithPrimaryKey, CollectionHandlerAssignToAllDb, HandlerCollectionRestore},
 		},
 		DefaultModelHandlerChain: map[reflect.Type]map[string]CollectionHandlersChain{},
+		modelHandlerDeltas:       map[reflect.Type]map[string][]collectionHandlerDelta{},
 	}
 	switch driverName {
 	case "mysql":
@@ -105,14 +112,98 @@ func (t *ToyCollection) Model(v interface{}) *CollectionBrick {
 	return brick
 }
 
+// the Toy of db shard i, use to run the operation that not need the collection handlers, e.g migration
+func (t *ToyCollection) shardToy(i int) *Toy {
+	toy := newToy(t.dbs[i], t.Dialect)
+	toy.debug = t.debug
+	toy.Logger = t.Logger
+	return toy
+}
+
 func (t *ToyCollection) ModelHandlers(option string, model *Model) CollectionHandlersChain {
-	handlers := make(CollectionHandlersChain, 0, len(t.DefaultHandlerChain[option])+len(t.DefaultModelHandlerChain[model.ReflectType][option]))
+	t.handlerMu.RLock()
+	defer t.handlerMu.RUnlock()
+	chain := t.modelChain(model, option)
+	handlers := make(CollectionHandlersChain, 0, len(chain)+len(t.DefaultModelHandlerChain[model.ReflectType][option]))
 	handlers = append(handlers, t.DefaultModelHandlerChain[model.ReflectType][option]...)
-	handlers = append(handlers, t.DefaultHandlerChain[option]...)
+	handlers = append(handlers, chain...)
 	return handlers
 }
 
+// append handlers to the end of operation default handler chain
+func (t *ToyCollection) Use(op Operation, handlers ...CollectionHandlerFunc) {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	chain := t.DefaultHandlerChain[string(op)]
+	t.DefaultHandlerChain[string(op)] = append(chain[:len(chain):len(chain)], handlers...)
+}
+
+// insert handlers before the handler with name in operation default handler chain
+// e.g toy.InsertBefore(OpInsert, "CollectionHandlerInsert", myHandler)
+func (t *ToyCollection) InsertBefore(op Operation, name string, handlers ...CollectionHandlerFunc) error {
+	return t.insertHandlers(nil, op, name, false, handlers)
+}
+
+func (t *ToyCollection) InsertAfter(op Operation, name string, handlers ...CollectionHandlerFunc) error {
+	return t.insertHandlers(nil, op, name, true, handlers)
+}
+
+// append handlers to the end of operation handler chain of model v,
+// the model handler chain is the default chain with model changes, so the later change of default chain also reach it
+func (t *ToyCollection) UseModel(v interface{}, op Operation, handlers ...CollectionHandlerFunc) {
+	model := t.GetModel(LoopDivePtr(reflect.ValueOf(v)))
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	t.addModelDelta(model, op, collectionHandlerDelta{handlers: handlers})
+}
+
+func (t *ToyCollection) InsertModelBefore(v interface{}, op Operation, name string, handlers ...CollectionHandlerFunc) error {
+	return t.insertHandlers(t.GetModel(LoopDivePtr(reflect.ValueOf(v))), op, name, false, handlers)
+}
+
+func (t *ToyCollection) InsertModelAfter(v interface{}, op Operation, name string, handlers ...CollectionHandlerFunc) error {
+	return t.insertHandlers(t.GetModel(LoopDivePtr(reflect.ValueOf(v))), op, name, true, handlers)
+}
+
+// the model handler chain, need hold the handler lock
+func (t *ToyCollection) modelChain(model *Model, option string) CollectionHandlersChain {
+	return applyCollectionHandlerDeltas(t.DefaultHandlerChain[option], t.modelHandlerDeltas[model.ReflectType][option])
+}
+
+// need hold the handler lock
+func (t *ToyCollection) addModelDelta(model *Model, op Operation, delta collectionHandlerDelta) {
+	if t.modelHandlerDeltas[model.ReflectType] == nil {
+		t.modelHandlerDeltas[model.ReflectType] = map[string][]collectionHandlerDelta{}
+	}
+	t.modelHandlerDeltas[model.ReflectType][string(op)] = append(t.modelHandlerDeltas[model.ReflectType][string(op)], delta)
+}
+
+// nil model means default handler chain
+func (t *ToyCollection) insertHandlers(model *Model, op Operation, name string, after bool, handlers []CollectionHandlerFunc) error {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	var chain CollectionHandlersChain
+	if model == nil {
+		chain = t.DefaultHandlerChain[string(op)]
+	} else {
+		chain = t.modelChain(model, string(op))
+	}
+	newChain, ok := insertCollectionHandlers(chain, name, after, handlers)
+	if ok == false {
+		return ErrHandlerNotFound{op, name}
+	}
+	if model == nil {
+		t.DefaultHandlerChain[string(op)] = newChain
+	} else {
+		t.addModelDelta(model, op, collectionHandlerDelta{name, after, handlers})
+	}
+	return nil
+}
+
+// set the handlers run before the default handler chain of model
 func (t *ToyCollection) SetModelHandlers(option string, model *Model, handlers CollectionHandlersChain) {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
 	if t.DefaultModelHandlerChain[model.ReflectType] == nil {
 		t.DefaultModelHandlerChain[model.ReflectType] = map[string]CollectionHandlersChain{}
 	}
@@ -183,7 +274,7 @@ func (t *ToyCollection) OneToManyPreload(model *Model, field Field) *OneToManyPr
 }
 
 func (t *ToyCollection) ManyToManyPreload(model *Model, field Field, isRight bool) *ManyToManyPreload {
-	return t.manyToManyPreloadWithTag(model, field, isRight, `toyorm:"primary key"`)
+	return t.manyToManyPreloadWithTag(model, field, isRight, middleModelTag(field))
 }
 
 func (t *ToyCollection) manyToManyPreloadWithTag(model *Model, field Field, isRight bool, tag reflect.StructTag) *ManyToManyPreload {
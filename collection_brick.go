@@ -7,6 +7,7 @@
 package toyorm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -25,7 +26,12 @@ type CollectionBrick struct {
 
 	//Error             []error
 	debug bool
-	//tx    *sql.Tx
+	// transaction of every db, the db without transaction is not in it
+	tx        map[int]*sql.Tx
+	txOptions *sql.TxOptions
+	ctx       context.Context
+	// max records size of one insert statement
+	batchSize int
 
 	//orderBy []Column
 	Search SearchList
@@ -34,6 +40,9 @@ type CollectionBrick struct {
 	//groupBy []Column
 	template *BasicExec
 
+	// soft delete filter, it also affect preload brick
+	deletedScope deletedScope
+
 	selector DBPrimarySelector
 	dbIndex  int
 	BrickCommon
@@ -85,8 +94,12 @@ func (t *CollectionBrick) Scope(fn func(*CollectionBrick) *CollectionBrick) *Col
 
 func (t *CollectionBrick) CopyStatus(statusBrick *CollectionBrick) *CollectionBrick {
 	newt := *t
-	//newt.tx = statusBrick.tx
+	newt.tx = statusBrick.tx
+	newt.txOptions = statusBrick.txOptions
+	newt.ctx = statusBrick.ctx
+	newt.batchSize = statusBrick.batchSize
 	newt.debug = statusBrick.debug
+	newt.deletedScope = statusBrick.deletedScope
 	newt.ignoreModeSelector = t.ignoreModeSelector
 
 	return &newt
@@ -173,6 +186,50 @@ func (t *CollectionBrick) Preload(fv interface{}) *CollectionBrick {
 	})
 }
 
+// skip the soft delete filter of Find/Count/Update and Delete will be hard delete,
+// the preload brick also be affected
+func (t *CollectionBrick) Unscoped() *CollectionBrick {
+	return t.withDeletedScope(scopeUnscoped)
+}
+
+// only Find/Count/Update the soft deleted data, the preload brick also be affected
+func (t *CollectionBrick) OnlyDeleted() *CollectionBrick {
+	return t.withDeletedScope(scopeOnlyDeleted)
+}
+
+func (t *CollectionBrick) withDeletedScope(scope deletedScope) *CollectionBrick {
+	return t.Scope(func(t *CollectionBrick) *CollectionBrick {
+		newt := *t
+		newt.deletedScope = scope
+		newt.MapPreloadBrick = make(map[string]*CollectionBrick, len(t.MapPreloadBrick))
+		for name, preloadBrick := range t.MapPreloadBrick {
+			newt.MapPreloadBrick[name] = preloadBrick.withDeletedScope(scope)
+		}
+		return &newt
+	})
+}
+
+// add the soft delete condition of current scope
+func (t *CollectionBrick) softDeleteScope() *CollectionBrick {
+	deletedField := t.Model.GetSoftDeleteField()
+	if deletedField == nil || t.deletedScope == scopeUnscoped {
+		return t
+	}
+	var expr SearchExpr
+	var args []interface{}
+	if t.deletedScope == scopeOnlyDeleted {
+		expr, args = deletedCondition(deletedField)
+	} else {
+		expr, args = notDeletedCondition(deletedField)
+	}
+	return t.Where(expr, deletedField, args...).And().Conditions(t.Search)
+}
+
+// delete action use soft delete
+func (t *CollectionBrick) softDeleteMode() bool {
+	return t.Model.GetSoftDeleteField() != nil && t.deletedScope != scopeUnscoped
+}
+
 func (t *CollectionBrick) CopyMapPreloadBrick() map[string]*CollectionBrick {
 	preloadBrick := map[string]*CollectionBrick{}
 	for k, v := range t.MapPreloadBrick {
@@ -329,6 +386,44 @@ func (t *CollectionBrick) Debug() *CollectionBrick {
 	})
 }
 
+// set the max records size of one insert statement, size <= 1 means insert records one by one
+// the preload brick created after it will use same batch size
+func (t *CollectionBrick) BatchSize(size int) *CollectionBrick {
+	return t.Scope(func(t *CollectionBrick) *CollectionBrick {
+		newt := *t
+		newt.batchSize = size
+		return &newt
+	})
+}
+
+func (t *CollectionBrick) getBatchSize() int {
+	if t.batchSize == 0 {
+		return DefaultBatchSize
+	}
+	return t.batchSize
+}
+
+// all sql statement of every db and preload statement will use this context
+func (t *CollectionBrick) WithContext(ctx context.Context) *CollectionBrick {
+	return t.Scope(func(t *CollectionBrick) *CollectionBrick {
+		newt := *t
+		newt.ctx = ctx
+		newt.MapPreloadBrick = make(map[string]*CollectionBrick, len(t.MapPreloadBrick))
+		for name, preloadBrick := range t.MapPreloadBrick {
+			newt.MapPreloadBrick[name] = preloadBrick.WithContext(ctx)
+		}
+		return &newt
+	})
+}
+
+// return brick context, if not set return context.Background()
+func (t *CollectionBrick) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
 func (t *CollectionBrick) Selector(selector DBPrimarySelector) *CollectionBrick {
 	return t.Scope(func(t *CollectionBrick) *CollectionBrick {
 		newt := *t
@@ -389,7 +484,7 @@ func (t *CollectionBrick) usave(records ModelRecords) (*Result, error) {
 }
 
 func (t *CollectionBrick) deleteWithPrimaryKey(records ModelRecords) (*Result, error) {
-	if field := t.Model.GetFieldWithName("DeletedAt"); field != nil {
+	if t.softDeleteMode() {
 		return t.softDeleteWithPrimaryKey(records)
 	} else {
 		return t.hardDeleteWithPrimaryKey(records)
@@ -409,7 +504,7 @@ func (t *CollectionBrick) hardDeleteWithPrimaryKey(records ModelRecords) (*Resul
 }
 
 func (t *CollectionBrick) delete(records ModelRecords) (*Result, error) {
-	if field := t.Model.GetFieldWithName("DeletedAt"); field != nil {
+	if t.softDeleteMode() {
 		return t.softDelete(records)
 	} else {
 		return t.hardDelete(records)
@@ -422,6 +517,12 @@ func (t *CollectionBrick) softDelete(records ModelRecords) (*Result, error) {
 	return ctx.Result, ctx.Next()
 }
 
+func (t *CollectionBrick) restoreWithPrimaryKey(records ModelRecords) (*Result, error) {
+	handlers := t.Toy.ModelHandlers("RestoreWithPrimaryKey", t.Model)
+	ctx := NewCollectionContext(handlers, t, records)
+	return ctx.Result, ctx.Next()
+}
+
 func (t *CollectionBrick) hardDelete(records ModelRecords) (*Result, error) {
 	handlers := t.Toy.ModelHandlers("HardDelete", t.Model)
 	ctx := NewCollectionContext(handlers, t, records)
@@ -486,6 +587,9 @@ func (t *CollectionBrick) HasTable() ([]bool, error) {
 }
 
 func (t *CollectionBrick) Count() (count int, err error) {
+	if err := t.searchErr(); err != nil {
+		return 0, err
+	}
 	exec := t.CountExec()
 	countCount := 0
 	errs := ErrCollectionQueryRow{}
@@ -539,10 +643,18 @@ func (t *CollectionBrick) Find(v interface{}) (*Result, error) {
 
 func (t *CollectionBrick) Update(v interface{}) (*Result, error) {
 	vValue := LoopIndirect(reflect.ValueOf(v))
-	vValueList := reflect.MakeSlice(reflect.SliceOf(vValue.Type()), 0, 1)
-	vValueList = reflect.Append(vValueList, vValue)
+	var records ModelRecords
+	// use address to write back the version
+	if vValue.Kind() == reflect.Struct && vValue.CanAddr() {
+		records = MakeRecordsWithElem(t.Model, vValue.Addr().Type())
+		records.Add(vValue.Addr())
+	} else {
+		vValueList := reflect.MakeSlice(reflect.SliceOf(vValue.Type()), 0, 1)
+		vValueList = reflect.Append(vValueList, vValue)
+		records = NewRecords(t.Model, vValueList)
+	}
 	handlers := t.Toy.ModelHandlers("Update", t.Model)
-	ctx := NewCollectionContext(handlers, t, NewRecords(t.Model, vValueList))
+	ctx := NewCollectionContext(handlers, t, records)
 	return ctx.Result, ctx.Next()
 }
 
@@ -588,6 +700,20 @@ func (t *CollectionBrick) Delete(v interface{}) (*Result, error) {
 	}
 }
 
+// clear the soft delete field of v and the preload data with primary key
+func (t *CollectionBrick) Restore(v interface{}) (*Result, error) {
+	vValue := LoopIndirect(reflect.ValueOf(v))
+	var records ModelRecords
+	switch vValue.Kind() {
+	case reflect.Slice:
+		records = NewRecords(t.Model, vValue)
+	default:
+		records = MakeRecordsWithElem(t.Model, vValue.Addr().Type())
+		records.Add(vValue.Addr())
+	}
+	return t.restoreWithPrimaryKey(records)
+}
+
 func (t *CollectionBrick) DeleteWithConditions() (*Result, error) {
 	return t.delete(nil)
 }
@@ -595,19 +721,122 @@ func (t *CollectionBrick) DeleteWithConditions() (*Result, error) {
 func (t *CollectionBrick) debugPrint(i int) func(ExecValue, error) {
 	return func(exec ExecValue, err error) {
 		if t.debug {
+			var txInfo string
+			if tx := t.tx[i]; tx != nil {
+				txInfo = " use tx: " + txDebugInfo(tx, t.txOptions) + ","
+			}
 			if err != nil {
-				fmt.Fprintf(t.Toy.Logger, "db[%d] query:%s, args:%s faiure reason %s\n", i, exec.Query(), exec.JsonArgs(), err)
+				fmt.Fprintf(t.Toy.Logger, "db[%d]%s query:%s, args:%s faiure reason %s\n", i, txInfo, exec.Query(), exec.JsonArgs(), err)
 			} else {
-				fmt.Fprintf(t.Toy.Logger, "db[%d] query:%s, args:%s\n", i, exec.Query(), exec.JsonArgs())
+				fmt.Fprintf(t.Toy.Logger, "db[%d]%s query:%s, args:%s\n", i, txInfo, exec.Query(), exec.JsonArgs())
+			}
+		}
+	}
+
+}
+
+// get the executor of db[i] with brick context, use by Dialect.InsertExecutor/SaveExecutor
+func (t *CollectionBrick) executor(i int) Executor {
+	return contextExecutor{t.Context(), t.db(i)}
+}
+
+// start transaction in db which dbIndex selected, if dbIndex not set, start transaction in all of db
+func (t *CollectionBrick) Begin() (*CollectionBrick, error) {
+	return t.BeginTx(nil)
+}
+
+// start transaction with options in db which dbIndex selected, if dbIndex not set, start transaction in all of db
+func (t *CollectionBrick) BeginTx(opts *sql.TxOptions) (*CollectionBrick, error) {
+	newt := *t
+	newt.tx = make(map[int]*sql.Tx, len(t.tx))
+	for i, tx := range t.tx {
+		newt.tx[i] = tx
+	}
+	newt.txOptions = opts
+	var dbIndexList []int
+	if t.dbIndex == -1 {
+		for i := range t.Toy.dbs {
+			dbIndexList = append(dbIndexList, i)
+		}
+	} else {
+		dbIndexList = []int{t.dbIndex}
+	}
+	errs := ErrCollectionTx{}
+	for _, i := range dbIndexList {
+		if newt.tx[i] != nil {
+			continue
+		}
+		tx, err := t.Toy.dbs[i].BeginTx(newt.Context(), opts)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		newt.tx[i] = tx
+	}
+	if len(errs) != 0 {
+		// rollback the transaction that already started
+		for i, tx := range newt.tx {
+			if t.tx[i] == nil {
+				tx.Rollback()
 			}
 		}
+		return nil, errs
 	}
+	return newt.withTxStatus(&newt), nil
+}
 
+// set the transaction status of statusBrick to brick and all preload bricks,
+// so the preload query run in the same transaction
+func (t *CollectionBrick) withTxStatus(statusBrick *CollectionBrick) *CollectionBrick {
+	newt := *t
+	newt.tx = statusBrick.tx
+	newt.txOptions = statusBrick.txOptions
+	newt.MapPreloadBrick = make(map[string]*CollectionBrick, len(t.MapPreloadBrick))
+	for name, preloadBrick := range t.MapPreloadBrick {
+		newt.MapPreloadBrick[name] = preloadBrick.withTxStatus(statusBrick)
+	}
+	return &newt
+}
+
+// commit the transaction of all db
+func (t *CollectionBrick) Commit() error {
+	errs := ErrCollectionTx{}
+	for i, tx := range t.tx {
+		if err := tx.Commit(); err != nil {
+			errs[i] = err
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// rollback the transaction of all db
+func (t *CollectionBrick) Rollback() error {
+	errs := ErrCollectionTx{}
+	for i, tx := range t.tx {
+		if err := tx.Rollback(); err != nil {
+			errs[i] = err
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// return the transaction of db[i] if it in transaction, otherwise return db[i]
+func (t *CollectionBrick) db(i int) contextDBExecutor {
+	if tx := t.tx[i]; tx != nil {
+		return tx
+	}
+	return t.Toy.dbs[i]
 }
 
 func (t *CollectionBrick) Exec(exec ExecValue, i int) (sql.Result, error) {
 	query := exec.Query()
-	result, err := t.Toy.dbs[i].Exec(query, exec.Args()...)
+	result, err := t.db(i).ExecContext(t.Context(), query, exec.Args()...)
 	t.debugPrint(i)(exec, err)
 
 	return result, err
@@ -615,7 +844,7 @@ func (t *CollectionBrick) Exec(exec ExecValue, i int) (sql.Result, error) {
 
 func (t *CollectionBrick) Query(exec ExecValue, i int) (*sql.Rows, error) {
 	query := exec.Query()
-	rows, err := t.Toy.dbs[i].Query(query, exec.Args()...)
+	rows, err := t.db(i).QueryContext(t.Context(), query, exec.Args()...)
 	t.debugPrint(i)(exec, err)
 
 	return rows, err
@@ -623,20 +852,21 @@ func (t *CollectionBrick) Query(exec ExecValue, i int) (*sql.Rows, error) {
 
 func (t *CollectionBrick) QueryRow(exec ExecValue, i int) *sql.Row {
 	query := exec.Query()
-	row := t.Toy.dbs[i].QueryRow(query, exec.Args()...)
+	row := t.db(i).QueryRowContext(t.Context(), query, exec.Args()...)
 	t.debugPrint(i)(exec, nil)
 	return row
 }
 
 func (t *CollectionBrick) CountExec() (exec ExecValue) {
+	brick := t.softDeleteScope()
 	exec = t.Toy.Dialect.CountExec(t.Model, "")
-	cExec := t.ConditionExec()
+	cExec := brick.ConditionExec()
 	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
 	return
 }
 
 func (t *CollectionBrick) ConditionExec() ExecValue {
-	return t.Toy.Dialect.ConditionExec(t.Search, 0, 0, nil, nil)
+	return t.Toy.Dialect.ConditionExec(t.Search, 0, 0, nil, nil, nil)
 }
 
 func (t *CollectionBrick) FindExec(records ModelRecordFieldTypes) ExecValue {
@@ -669,6 +899,43 @@ func (t *CollectionBrick) InsertExec(record ModelRecord) ExecValue {
 	return exec
 }
 
+// insert multiple records in one statement, all of records must have same insert columns
+func (t *CollectionBrick) InsertValuesExec(records []ModelRecord) ExecValue {
+	valuesList := make([][]ColumnNameValue, len(records))
+	for i, record := range records {
+		valuesList[i] = t.getFieldValuePairWithRecord(ModeInsert, record).ToNameValueList()
+	}
+	exec := t.Toy.Dialect.InsertValuesExec(t.Model, valuesList)
+	cExec := t.ConditionExec()
+	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
+	return exec
+}
+
+// split records to insert batches, every batch is the records index list
+func (t *CollectionBrick) insertBatches(records []ModelRecord) [][]int {
+	valuesList := make([][]ColumnNameValue, len(records))
+	for i, record := range records {
+		valuesList[i] = t.getFieldValuePairWithRecord(ModeInsert, record).ToNameValueList()
+	}
+	return insertBatchGroup(t.Model, valuesList, t.getBatchSize())
+}
+
+// update the versioned record with primary key and previous version condition,
+// the Save of versioned model use it when record is not new
+func (t *CollectionBrick) VersionSaveExec(record ModelRecord) ExecValue {
+	versionField := t.Model.GetVersionField()
+	conditions := map[string]interface{}{}
+	for _, field := range t.Model.GetPrimary() {
+		conditions[field.Name()] = record.Field(field.Name()).Interface()
+	}
+	conditions[versionField.Name()] = versionValue(record.Field(versionField.Name())) - 1
+	brick := t.WhereGroup(ExprAnd, conditions).And().Conditions(t.Search)
+	exec := t.Toy.Dialect.UpdateExec(t.Model, brick.getFieldValuePairWithRecord(ModeSave, record).ToValueList())
+	cExec := brick.ConditionExec()
+	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
+	return exec
+}
+
 func (t *CollectionBrick) SaveExec(record ModelRecord) ExecValue {
 	recorders := t.getFieldValuePairWithRecord(ModeSave, record)
 	exec := t.Toy.Dialect.SaveExec(t.Model, recorders.ToNameValueList())
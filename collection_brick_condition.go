@@ -91,7 +91,19 @@ func (t *CollectionBrick) condition(expr SearchExpr, key FieldSelection, args ..
 	} else {
 		value = reflect.ValueOf(args)
 	}
-	mField := t.Model.fieldSelect(key)
+	var mField Field = t.Model.fieldSelect(key)
+	if _, ok := jsonPathExprs[expr]; ok {
+		expr, mField, args = jsonPathCondition(t.Toy.Dialect, expr, mField, args)
+		if len(args) == 1 {
+			value = reflect.ValueOf(args[0])
+		} else {
+			value = reflect.ValueOf(args)
+		}
+	}
+
+	if invalid, ok := invalidSubQueryValue(expr, mField, args); ok {
+		return SearchList{}.Condition(invalid, expr, ExprAnd)
+	}
 
 	search := SearchList{}.Condition(mField.ToFieldValue(value), expr, ExprAnd)
 
@@ -118,6 +130,26 @@ func (t *CollectionBrick) conditionGroup(expr SearchExpr, group interface{}) Sea
 	panic("invalid expr")
 }
 
+// the error of invalid sub query in conditions, include the conditions of sub query brick
+func (t *CollectionBrick) searchErr() error {
+	for _, cell := range t.Search {
+		if cell.Type.IsBranch() {
+			continue
+		}
+		if v, ok := cell.Val.(subQueryValue); ok && v.err != nil {
+			return v.err
+		}
+		if value := cell.Val.Value(); value.IsValid() && value.CanInterface() {
+			if sub, ok := value.Interface().(*ToyBrick); ok && sub != nil {
+				if err := sub.searchErr(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // where will clean old condition
 func (t *CollectionBrick) Where(expr SearchExpr, key FieldSelection, v ...interface{}) *CollectionBrick {
 	return t.Conditions(t.condition(expr, key, v...))
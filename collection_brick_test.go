@@ -7,9 +7,11 @@
 package toyorm
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"sort"
 	"strings"
 	"testing"
@@ -2273,3 +2275,414 @@ func TestCollectionSaveWithUniqueIndex(t *testing.T) {
 	//assert.NotNil(t, resultErr)
 	t.Log("error:\n", resultErr)
 }
+
+func TestCollectionBatchInsert(t *testing.T) {
+	var tab TestCountTable
+	brick := TestCollectionDB.Model(&tab)
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+
+	var data []TestCountTable
+	for i := 0; i < 25; i++ {
+		data = append(data, TestCountTable{Data: fmt.Sprintf("batch data %d", i)})
+	}
+	result, err := brick.BatchSize(5).Insert(&data)
+	assert.Nil(t, err)
+	if err := result.Err(); err != nil {
+		t.Error(err)
+	}
+	t.Logf("report:\n%s\n", result.Report())
+	// every record have an action and the action affect records size not more than batch size
+	for i := range data {
+		assert.Equal(t, len(result.RecordsActions[i]), 1)
+	}
+	for _, action := range result.ActionFlow {
+		assert.True(t, len(action.AffectData()) <= 5)
+	}
+
+	count, err := brick.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, count, 25)
+}
+
+func TestCollectionBeginTx(t *testing.T) {
+	var tab TestCountTable
+	brick := TestCollectionDB.Model(&tab)
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+
+	// transaction in all db
+	txBrick, err := brick.Begin()
+	assert.Nil(t, err)
+	assert.Equal(t, len(txBrick.tx), len(TestCollectionDB.dbs))
+	var data []TestCountTable
+	for i := 0; i < 4; i++ {
+		data = append(data, TestCountTable{Data: fmt.Sprintf("tx data %d", i)})
+	}
+	result, err := txBrick.Insert(&data)
+	assert.Nil(t, err)
+	if err := result.Err(); err != nil {
+		t.Error(err)
+	}
+	assert.Nil(t, txBrick.Rollback())
+	count, err := brick.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, count, 0)
+
+	// transaction in one db with options
+	shardBrick, err := brick.DBIndex(0).BeginTx(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	assert.Nil(t, err)
+	assert.Equal(t, len(shardBrick.tx), 1)
+	assert.NotNil(t, shardBrick.tx[0])
+	result, err = shardBrick.Insert(&TestCountTable{ID: 100, Data: "shard tx data"})
+	assert.Nil(t, err)
+	if err := result.Err(); err != nil {
+		t.Error(err)
+	}
+	assert.Nil(t, shardBrick.Commit())
+	count, err = brick.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, count, 1)
+}
+
+func TestCollectionPreloadBeginTx(t *testing.T) {
+	type TestCollectionPreloadTxChild struct {
+		ID                             uint32 `toyorm:"primary key"`
+		Data                           string
+		TestCollectionPreloadTxTableID uint32 `toyorm:"index"`
+	}
+	type TestCollectionPreloadTxTable struct {
+		ID       uint32 `toyorm:"primary key"`
+		Data     string
+		Children []TestCollectionPreloadTxChild
+	}
+	brick := TestCollectionDB.Model(&TestCollectionPreloadTxTable{}).Preload(Offsetof(TestCollectionPreloadTxTable{}.Children)).Enter()
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	for _, pBrick := range brick.MapPreloadBrick {
+		TestCollectionDB.SetModelHandlers("Insert", pBrick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	}
+
+	txBrick, err := brick.BeginTx(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	require.NoError(t, err)
+	// the preload brick created before BeginTx also in transaction
+	preloadBrick := txBrick.MapPreloadBrick["Children"]
+	assert.Equal(t, txBrick.tx, preloadBrick.tx)
+	assert.Equal(t, txBrick.txOptions, preloadBrick.txOptions)
+
+	result, err := txBrick.Insert(&TestCollectionPreloadTxTable{
+		Data:     "tx data",
+		Children: []TestCollectionPreloadTxChild{{Data: "tx child 1"}, {Data: "tx child 2"}},
+	})
+	resultProcessor(result, err)(t)
+	var data []TestCollectionPreloadTxTable
+	result, err = txBrick.Find(&data)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(data))
+	assert.Equal(t, 2, len(data[0].Children))
+	require.NoError(t, txBrick.Rollback())
+
+	count, err := TestCollectionDB.Model(&TestCollectionPreloadTxChild{}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestCollectionInvalidSubQuery(t *testing.T) {
+	type TestCollectionSubQueryTable struct {
+		ID   uint32 `toyorm:"primary key"`
+		Data string
+	}
+	brick := TestCollectionDB.Model(&TestCollectionSubQueryTable{})
+	createCollectionTableUnit(brick)(t)
+	sub := TestDB.Model(&TestCountTable{}).BindFields(ModeSelect, Offsetof(TestCountTable{}.ID))
+
+	var list []TestCollectionSubQueryTable
+	for _, expr := range []SearchExpr{ExprBetween, ExprNotBetween, ExprNull, ExprNotNull} {
+		_, err := brick.Where(expr, Offsetof(TestCollectionSubQueryTable{}.ID), sub).Find(&list)
+		assert.Equal(t, ErrInvalidSubQuery, err)
+	}
+	_, err := brick.Where(ExprNull, Offsetof(TestCollectionSubQueryTable{}.ID), sub).Count()
+	assert.Equal(t, ErrInvalidSubQuery, err)
+}
+
+func TestCollectionMigrator(t *testing.T) {
+	type TestCollectionMigratorTable struct {
+		ID   uint32 `toyorm:"primary key"`
+		Data string
+	}
+	for _, v := range []interface{}{&migrationRecord{}, &TestCollectionMigratorTable{}} {
+		result, err := TestCollectionDB.Model(v).DropTableIfExist()
+		assert.Nil(t, err)
+		if err := result.Err(); err != nil {
+			t.Error(err)
+		}
+	}
+	migrator := NewCollectionMigrator(TestCollectionDB, Migration{
+		Version: 1,
+		Name:    "create_table",
+		Up: func(brick *ToyBrick) error {
+			result, err := brick.SwitchModel(&TestCollectionMigratorTable{}).CreateTable()
+			if err != nil {
+				return err
+			}
+			return result.Err()
+		},
+	})
+	assert.Nil(t, migrator.Up())
+	hasTable, err := TestCollectionDB.Model(&TestCollectionMigratorTable{}).HasTable()
+	assert.Nil(t, err)
+	assert.Equal(t, len(TestCollectionDB.dbs), len(hasTable))
+	for _, b := range hasTable {
+		assert.True(t, b)
+	}
+
+	statusList, err := migrator.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, len(TestCollectionDB.dbs), len(statusList))
+	for i, status := range statusList {
+		assert.Equal(t, i, status.DbIndex)
+		assert.Equal(t, int64(1), status.Version)
+		assert.True(t, status.Applied)
+	}
+	// migration without down function
+	err = migrator.Down()
+	assert.IsType(t, ErrCollectionMigrate{}, err)
+	for _, e := range err.(ErrCollectionMigrate) {
+		assert.Equal(t, ErrMigrationNoDown{1, "create_table"}, e)
+	}
+}
+
+func TestCollectionConventionTag(t *testing.T) {
+	type TestCollectionConventionTable struct {
+		ID        uint32 `toyorm:"primary key"`
+		Name      string
+		Modified  time.Time `toyorm:"updated at"`
+		IsDeleted bool      `toyorm:"soft delete"`
+	}
+	brick := TestCollectionDB.Model(&TestCollectionConventionTable{})
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+
+	data := []TestCollectionConventionTable{{Name: "a"}, {Name: "b"}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	for _, d := range data {
+		assert.False(t, d.Modified.IsZero())
+	}
+	result, err = brick.Delete(&data[1])
+	resultProcessor(result, err)(t)
+
+	var list []TestCollectionConventionTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	if assert.Equal(t, 1, len(list)) {
+		assert.Equal(t, "a", list[0].Name)
+		assert.False(t, list[0].IsDeleted)
+	}
+}
+
+func TestCollectionUnscopedAndRestore(t *testing.T) {
+	type TestCollectionRestoreChild struct {
+		ModelDefault
+		Data                         string
+		TestCollectionRestoreTableID uint32 `toyorm:"index"`
+	}
+	type TestCollectionRestoreTable struct {
+		ModelDefault
+		Data     string
+		Children []TestCollectionRestoreChild
+	}
+	brick := TestCollectionDB.Model(&TestCollectionRestoreTable{}).Preload(Offsetof(TestCollectionRestoreTable{}.Children)).Enter()
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	for _, pBrick := range brick.MapPreloadBrick {
+		TestCollectionDB.SetModelHandlers("Insert", pBrick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	}
+
+	data := []TestCollectionRestoreTable{
+		{Data: "a", Children: []TestCollectionRestoreChild{{Data: "a1"}, {Data: "a2"}}},
+		{Data: "b", Children: []TestCollectionRestoreChild{{Data: "b1"}}},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	result, err = brick.Delete(&data[0])
+	resultProcessor(result, err)(t)
+
+	count, err := brick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = brick.Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// unscoped affect preload brick
+	var all []TestCollectionRestoreTable
+	result, err = brick.Unscoped().Find(&all)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(all))
+	for _, tab := range all {
+		if tab.Data == "a" {
+			assert.Equal(t, 2, len(tab.Children))
+			assert.NotNil(t, tab.DeletedAt)
+		}
+	}
+
+	var deleted []TestCollectionRestoreTable
+	result, err = brick.OnlyDeleted().Find(&deleted)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(deleted))
+	assert.Equal(t, "a", deleted[0].Data)
+	require.Equal(t, 2, len(deleted[0].Children))
+	assert.NotNil(t, deleted[0].Children[0].DeletedAt)
+
+	result, err = brick.Restore(&deleted[0])
+	resultProcessor(result, err)(t)
+	assert.Nil(t, deleted[0].DeletedAt)
+	assert.Nil(t, deleted[0].Children[0].DeletedAt)
+
+	var restored []TestCollectionRestoreTable
+	result, err = brick.Find(&restored)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(restored))
+	count, err = TestCollectionDB.Model(&TestCollectionRestoreChild{}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// unscoped delete is hard delete
+	result, err = brick.Unscoped().Delete(&data[1])
+	resultProcessor(result, err)(t)
+	count, err = brick.Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = TestCollectionDB.Model(&TestCollectionRestoreChild{}).Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCollectionOptimisticLock(t *testing.T) {
+	type TestCollectionOptimisticLockTable struct {
+		ID      uint32 `toyorm:"primary key"`
+		Name    string
+		Version int `toyorm:"version"`
+	}
+	brick := TestCollectionDB.Model(&TestCollectionOptimisticLockTable{})
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	lockConflict := func(result *Result) {
+		if assert.NotNil(t, result.Err()) {
+			_, ok := result.ActionFlow[len(result.ActionFlow)-1].Err().(ErrOptimisticLockConflict)
+			assert.True(t, ok)
+		}
+	}
+
+	data := TestCollectionOptimisticLockTable{Name: "a"}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	stale := data
+
+	data.Name = "b"
+	result, err = brick.Update(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, data.Version)
+
+	staleUpdate := stale
+	result, err = brick.Update(&staleUpdate)
+	assert.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleUpdate.Version)
+
+	staleUSave := stale
+	result, err = brick.USave(&staleUSave)
+	assert.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleUSave.Version)
+
+	staleSave := stale
+	result, err = brick.Save(&staleSave)
+	assert.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleSave.Version)
+	// retry save after conflict still use the stale version
+	result, err = brick.Save(&staleSave)
+	assert.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleSave.Version)
+
+	data.Name = "c"
+	result, err = brick.Save(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 3, data.Version)
+}
+
+func TestCollectionModelHooks(t *testing.T) {
+	brick := TestCollectionDB.Model(&TestHookTable{})
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+	hookCounter = map[string]int{}
+
+	data := []TestHookTable{{Data: "a"}, {Data: "b"}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, hookCounter["BeforeInsert"])
+	assert.Equal(t, 2, hookCounter["AfterInsert"])
+
+	result, err = brick.Insert(&TestHookTable{})
+	assert.Error(t, err)
+	assert.Error(t, result.Err())
+
+	var list []TestHookTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	if assert.Equal(t, 2, len(list)) {
+		assert.Equal(t, "display "+list[0].Data, list[0].Display)
+	}
+}
+
+func TestCollectionHandlerRegistration(t *testing.T) {
+	type TestCollectionHandlerRegisterTable struct {
+		ID   uint32 `toyorm:"primary key"`
+		Data string
+	}
+	brick := TestCollectionDB.Model(&TestCollectionHandlerRegisterTable{})
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+
+	var called []string
+	assert.NoError(t, TestCollectionDB.InsertModelBefore(&TestCollectionHandlerRegisterTable{}, OpInsert, "CollectionHandlerInsert", func(ctx *CollectionContext) error {
+		called = append(called, "before")
+		return nil
+	}))
+	assert.NoError(t, TestCollectionDB.InsertModelAfter(&TestCollectionHandlerRegisterTable{}, OpInsert, "CollectionHandlerInsert", func(ctx *CollectionContext) error {
+		called = append(called, "after")
+		return nil
+	}))
+	err := TestCollectionDB.InsertModelAfter(&TestCollectionHandlerRegisterTable{}, OpInsert, "HandlerInsert")
+	assert.Equal(t, ErrHandlerNotFound{OpInsert, "HandlerInsert"}, err)
+
+	result, err := brick.Insert(&TestCollectionHandlerRegisterTable{Data: "a"})
+	resultProcessor(result, err)(t)
+	assert.Equal(t, []string{"before", "after"}, called)
+}
+
+func TestCollectionJsonField(t *testing.T) {
+	type TestCollectionJsonTable struct {
+		ID    uint32            `toyorm:"primary key"`
+		Extra map[string]string `toyorm:"json"`
+		Tags  []int             `toyorm:"json"`
+	}
+	brick := TestCollectionDB.Model(&TestCollectionJsonTable{})
+	createCollectionTableUnit(brick)(t)
+	TestCollectionDB.SetModelHandlers("Insert", brick.Model, CollectionHandlersChain{CollectionIDGenerate})
+
+	data := TestCollectionJsonTable{Extra: map[string]string{"a": "b"}, Tags: []int{1, 2}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var list []TestCollectionJsonTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	if assert.Equal(t, 1, len(list)) {
+		assert.Equal(t, data, list[0])
+	}
+}
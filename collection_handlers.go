@@ -157,18 +157,18 @@ func CollectionHandlerPreloadInsertOrSave(option string) func(*CollectionContext
 
 func CollectionHandlerInsertTimeGenerate(ctx *CollectionContext) error {
 	records := ctx.Result.Records
-	createField := ctx.Brick.Model.GetFieldWithName("CreatedAt")
-	updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt")
+	createField := ctx.Brick.Model.GetCreatedAtField()
+	updateField := ctx.Brick.Model.GetUpdatedAtField()
 	if createField != nil || updateField != nil {
 		current := time.Now()
 		if createField != nil {
 			for _, record := range records.GetRecords() {
-				record.SetField(createField.Name(), reflect.ValueOf(current))
+				record.SetField(createField.Name(), timeFieldValue(createField, current))
 			}
 		}
 		if updateField != nil {
 			for _, record := range records.GetRecords() {
-				record.SetField(updateField.Name(), reflect.ValueOf(current))
+				record.SetField(updateField.Name(), timeFieldValue(updateField, current))
 			}
 		}
 	}
@@ -242,47 +242,94 @@ func CollectionHandlerInsert(ctx *CollectionContext) error {
 	if ctx.Brick.dbIndex == -1 {
 		return ErrDbIndexNotSet{}
 	}
-	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
-	for i, record := range ctx.Result.Records.GetRecords() {
-		action := CollectionExecAction{affectData: []int{i}, dbIndex: ctx.Brick.dbIndex}
+	records := ctx.Result.Records.GetRecords()
+	var batches [][]int
+	// template exec only can insert records one by one
+	if ctx.Brick.template == nil && ctx.Brick.getBatchSize() > 1 {
+		batches = ctx.Brick.insertBatches(records)
+	} else {
+		for i := range records {
+			batches = append(batches, []int{i})
+		}
+	}
+	for _, batch := range batches {
 		var err error
-		if ctx.Brick.template == nil {
-			action.Exec = ctx.Brick.InsertExec(record)
+		if len(batch) == 1 {
+			err = collectionHandlerInsertOne(ctx, batch[0], records[batch[0]])
 		} else {
-			tempMap := DefaultCollectionTemplateExec(ctx.Brick)
-			values := ctx.Brick.getFieldValuePairWithRecord(ModeInsert, record).ToValueList()
-			tempMap["Columns"] = getColumnExec(columnsValueToColumn(values))
-			tempMap["Values"] = getValuesExec(values)
-			action.Exec, err = ctx.Brick.Toy.Dialect.TemplateExec(*ctx.Brick.template, tempMap)
-			if err != nil {
-				return err
-			}
+			err = collectionHandlerInsertBatch(ctx, batch, records)
 		}
-		action.Result, action.Error = ctx.Brick.Toy.Dialect.InsertExecutor(
-			ctx.Brick.Toy.dbs[action.dbIndex],
-			action.Exec,
-			ctx.Brick.debugPrint(action.dbIndex),
-		)
-
-		if action.Error == nil {
-			// set primary field value if model has one primary key
-			if len(ctx.Brick.Model.GetPrimary()) == 1 {
-				primaryKey := ctx.Brick.Model.GetOnePrimary()
-				primaryKeyName := primaryKey.Name()
-				if IntKind(primaryKey.StructField().Type.Kind()) {
-					// just set not zero primary key
-					if fieldValue := record.Field(primaryKeyName); !fieldValue.IsValid() || IsZero(fieldValue) {
-						if lastId, err := action.Result.LastInsertId(); err == nil {
-							ctx.Result.Records.GetRecord(i).SetField(primaryKeyName, reflect.ValueOf(lastId))
-						} else {
-							return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
-						}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func collectionHandlerInsertOne(ctx *CollectionContext, i int, record ModelRecord) error {
+	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
+	action := CollectionExecAction{affectData: []int{i}, dbIndex: ctx.Brick.dbIndex}
+	var err error
+	if ctx.Brick.template == nil {
+		action.Exec = ctx.Brick.InsertExec(record)
+	} else {
+		tempMap := DefaultCollectionTemplateExec(ctx.Brick)
+		values := ctx.Brick.getFieldValuePairWithRecord(ModeInsert, record).ToValueList()
+		tempMap["Columns"] = getColumnExec(columnsValueToColumn(values))
+		tempMap["Values"] = getValuesExec(values)
+		action.Exec, err = ctx.Brick.Toy.Dialect.TemplateExec(*ctx.Brick.template, tempMap)
+		if err != nil {
+			return err
+		}
+	}
+	action.Result, action.Error = ctx.Brick.Toy.Dialect.InsertExecutor(
+		ctx.Brick.executor(action.dbIndex),
+		action.Exec,
+		ctx.Brick.debugPrint(action.dbIndex),
+	)
+
+	if action.Error == nil {
+		// set primary field value if model has one primary key
+		if len(ctx.Brick.Model.GetPrimary()) == 1 {
+			primaryKey := ctx.Brick.Model.GetOnePrimary()
+			primaryKeyName := primaryKey.Name()
+			if IntKind(primaryKey.StructField().Type.Kind()) {
+				// just set not zero primary key
+				if fieldValue := record.Field(primaryKeyName); !fieldValue.IsValid() || IsZero(fieldValue) {
+					if lastId, err := action.Result.LastInsertId(); err == nil {
+						ctx.Result.Records.GetRecord(i).SetField(primaryKeyName, reflect.ValueOf(lastId))
+					} else {
+						return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
 					}
 				}
 			}
 		}
-		ctx.Result.AddRecord(action)
 	}
+	ctx.Result.AddRecord(action)
+	return nil
+}
+
+// insert multiple records in one statement, the action affect all records in batch
+func collectionHandlerInsertBatch(ctx *CollectionContext, batch []int, records []ModelRecord) error {
+	action := CollectionExecAction{affectData: batch, dbIndex: ctx.Brick.dbIndex}
+	batchRecords := make([]ModelRecord, len(batch))
+	for j, i := range batch {
+		batchRecords[j] = records[i]
+	}
+	action.Exec = ctx.Brick.InsertValuesExec(batchRecords)
+	var ids []int64
+	action.Result, ids, action.Error = ctx.Brick.Toy.Dialect.InsertValuesExecutor(
+		ctx.Brick.executor(action.dbIndex),
+		action.Exec,
+		len(batch),
+		ctx.Brick.debugPrint(action.dbIndex),
+	)
+	if action.Error == nil {
+		if err := setBatchInsertId(ctx.Brick.Model, records, batch, ids); err != nil {
+			return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
+		}
+	}
+	ctx.Result.AddRecord(action)
 	return nil
 }
 
@@ -539,17 +586,17 @@ func CollectionHandlerPreloadContainerCheck(ctx *CollectionContext) error {
 }
 
 func CollectionHandlerSaveTimeGenerate(ctx *CollectionContext) error {
-	now := reflect.ValueOf(time.Now())
-	if createAtField := ctx.Brick.Model.GetFieldWithName("CreatedAt"); createAtField != nil {
+	now := time.Now()
+	if createAtField := ctx.Brick.Model.GetCreatedAtField(); createAtField != nil {
 		for _, record := range ctx.Result.Records.GetRecords() {
 			if fieldValue := record.Field(createAtField.Name()); fieldValue.IsValid() == false || IsZero(fieldValue) {
-				record.SetField(createAtField.Name(), now)
+				record.SetField(createAtField.Name(), timeFieldValue(createAtField, now))
 			}
 		}
 	}
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
 		for _, record := range ctx.Result.Records.GetRecords() {
-			record.SetField(updateField.Name(), now)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, now))
 		}
 	}
 	return nil
@@ -560,6 +607,7 @@ func CollectionHandlerSave(ctx *CollectionContext) error {
 		return ErrDbIndexNotSet{}
 	}
 	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
+	versionField := ctx.Brick.Model.GetVersionField()
 	for i, record := range ctx.Result.Records.GetRecords() {
 		var action CollectionExecAction
 		var err error
@@ -576,7 +624,7 @@ func CollectionHandlerSave(ctx *CollectionContext) error {
 			if useInsert {
 				action.Exec = ctx.Brick.InsertExec(record)
 				action.Result, action.Error = ctx.Brick.Toy.Dialect.InsertExecutor(
-					ctx.Brick.Toy.dbs[action.dbIndex],
+					ctx.Brick.executor(action.dbIndex),
 					action.Exec,
 					ctx.Brick.debugPrint(action.dbIndex),
 				)
@@ -599,13 +647,23 @@ func CollectionHandlerSave(ctx *CollectionContext) error {
 					}
 
 				}
+			} else if versionPushed(versionField, record) {
+				// optimistic lock
+				action.Exec = ctx.Brick.VersionSaveExec(record)
+				action.Result, action.Error = ctx.Brick.Exec(action.Exec, action.dbIndex)
+				if action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			} else {
 				action.Exec = ctx.Brick.SaveExec(record)
 				action.Result, action.Error = ctx.Brick.Toy.Dialect.SaveExecutor(
-					ctx.Brick.Toy.dbs[action.dbIndex],
+					ctx.Brick.executor(action.dbIndex),
 					action.Exec,
 					ctx.Brick.debugPrint(action.dbIndex),
 				)
+				if versionField != nil && action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			}
 		} else {
 			tempMap := DefaultCollectionTemplateExec(ctx.Brick)
@@ -619,6 +677,9 @@ func CollectionHandlerSave(ctx *CollectionContext) error {
 			}
 		}
 
+		if action.Error != nil {
+			versionRestore(versionField, record)
+		}
 		ctx.Result.AddRecord(action)
 	}
 	return nil
@@ -628,6 +689,7 @@ func CollectionHandlerUSave(ctx *CollectionContext) error {
 	if ctx.Brick.dbIndex == -1 {
 		return ErrDbIndexNotSet{}
 	}
+	versionField := ctx.Brick.Model.GetVersionField()
 	notIgnoreBrick := ctx.Brick.IgnoreMode(ModeDefault, IgnoreNo)
 	for i, record := range ctx.Result.Records.GetRecords() {
 		var action CollectionExecAction
@@ -648,13 +710,16 @@ func CollectionHandlerUSave(ctx *CollectionContext) error {
 			action.Error = ErrNilPrimaryKey{}
 		} else {
 			// cas process
-			if casField := ctx.Brick.Model.GetFieldWithName("Cas"); casField != nil {
-				ConditionKeyVal[casField.Name()] = record.Field(casField.Name()).Int() - 1
+			if versionField != nil {
+				ConditionKeyVal[versionField.Name()] = versionValue(record.Field(versionField.Name())) - 1
 			}
 			brick := notIgnoreBrick.WhereGroup(ExprAnd, ConditionKeyVal)
 			if ctx.Brick.template == nil {
 				action.Exec = brick.UpdateExec(record)
 				action.Result, action.Error = ctx.Brick.Exec(action.Exec, brick.dbIndex)
+				if versionField != nil && action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			} else {
 				tempMap := DefaultCollectionTemplateExec(brick)
 				values := ctx.Brick.getFieldValuePairWithRecord(ModeSave, record).ToValueList()
@@ -666,6 +731,9 @@ func CollectionHandlerUSave(ctx *CollectionContext) error {
 			}
 		}
 
+		if action.Error != nil {
+			versionRestore(versionField, record)
+		}
 		ctx.Result.AddRecord(action)
 	}
 	return nil
@@ -856,7 +924,7 @@ func CollectionHandlerFind(ctx *CollectionContext) error {
 		var scanners []interface{}
 		for _, field := range ctx.Brick.getScanFields(ctx.Result.Records) {
 			value := record.Field(field.Name())
-			scanners = append(scanners, value.Addr().Interface())
+			scanners = append(scanners, fieldScanner(field, value.Addr()))
 		}
 		err := rows.Scan(scanners...)
 		action.Error = append(action.Error, err)
@@ -928,7 +996,7 @@ func CollectionHandlerFindOne(ctx *CollectionContext) error {
 		var scanners []interface{}
 		for _, field := range ctx.Brick.getScanFields(ctx.Result.Records) {
 			value := record.Field(field.Name())
-			scanners = append(scanners, value.Addr().Interface())
+			scanners = append(scanners, fieldScanner(field, value.Addr()))
 		}
 		err := rows.Scan(scanners...)
 		action.Error = append(action.Error, err)
@@ -940,19 +1008,72 @@ func CollectionHandlerFindOne(ctx *CollectionContext) error {
 }
 
 func CollectionHandlerSoftDeleteCheck(ctx *CollectionContext) error {
-	deletedField := ctx.Brick.Model.GetFieldWithName("DeletedAt")
-	if deletedField != nil {
-		ctx.Brick = ctx.Brick.Where(ExprNull, deletedField).And().Conditions(ctx.Brick.Search)
-	}
+	ctx.Brick = ctx.Brick.softDeleteScope()
 	return nil
 }
 
 func CollectionHandlerUpdateTimeGenerate(ctx *CollectionContext) error {
 	records := ctx.Result.Records
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
-		current := reflect.ValueOf(time.Now())
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
+		current := time.Now()
 		for _, record := range records.GetRecords() {
-			record.SetField(updateField.Name(), current)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, current))
+		}
+	}
+	return nil
+}
+
+// push the record version and check optimistic lock conflict after update,
+// the record may only exist in one of db, so it's conflict when all of db affect nothing
+// the record keep previous version when update failure or conflict
+func CollectionHandlerUpdateVersion(ctx *CollectionContext) error {
+	versionField := ctx.Brick.Model.GetVersionField()
+	if versionField == nil {
+		return nil
+	}
+	records := ctx.Result.Records.GetRecords()
+	previous := map[int]int64{}
+	for i, record := range records {
+		if fieldValue := record.Field(versionField.Name()); fieldValue.IsValid() && IsZero(fieldValue) == false {
+			previous[i] = versionValue(fieldValue)
+			record.SetField(versionField.Name(), reflect.ValueOf(previous[i]+1))
+		}
+	}
+	rollback := func(i int) {
+		if version, ok := previous[i]; ok {
+			records[i].SetField(versionField.Name(), reflect.ValueOf(version))
+		}
+	}
+	if err := ctx.Next(); err != nil {
+		for i := range records {
+			rollback(i)
+		}
+		return err
+	}
+	affected := map[int]int64{}
+	lastAction := map[int]int{}
+	for j, sqlAction := range ctx.Result.ActionFlow {
+		action, ok := sqlAction.(CollectionExecAction)
+		if ok == false || action.Error != nil || action.Result == nil {
+			continue
+		}
+		n, err := action.Result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		for _, i := range action.affectData {
+			affected[i] += n
+			lastAction[i] = j
+		}
+	}
+	for i, record := range records {
+		if j, ok := lastAction[i]; ok && affected[i] == 0 && versionPushed(versionField, record) {
+			action := ctx.Result.ActionFlow[j].(CollectionExecAction)
+			action.Error = optimisticLockConflict(ctx.Brick.Model, record)
+			ctx.Result.ActionFlow[j] = action
+		}
+		if affected[i] == 0 {
+			rollback(i)
 		}
 	}
 	return nil
@@ -962,13 +1083,20 @@ func CollectionHandlerUpdate(ctx *CollectionContext) error {
 	if ctx.Brick.dbIndex == -1 {
 		return ErrDbIndexNotSet{}
 	}
+	versionField := ctx.Brick.Model.GetVersionField()
 	for i, record := range ctx.Result.Records.GetRecords() {
 		action := CollectionExecAction{affectData: []int{i}, dbIndex: ctx.Brick.dbIndex}
 		var err error
+		brick := ctx.Brick
+		// optimistic lock, the record with version will update with previous version condition
+		if versionPushed(versionField, record) {
+			version := versionValue(record.Field(versionField.Name())) - 1
+			brick = brick.Where(ExprEqual, versionField, version).And().Conditions(brick.Search)
+		}
 		if ctx.Brick.template == nil {
-			action.Exec = ctx.Brick.UpdateExec(record)
+			action.Exec = brick.UpdateExec(record)
 		} else {
-			tempMap := DefaultCollectionTemplateExec(ctx.Brick)
+			tempMap := DefaultCollectionTemplateExec(brick)
 			values := ctx.Brick.getFieldValuePairWithRecord(ModeUpdate, record).ToValueList()
 			tempMap["Columns"] = getColumnExec(columnsValueToColumn(values))
 			tempMap["Values"] = getUpdateValuesExec(values)
@@ -987,8 +1115,8 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 	for fieldName, preload := range ctx.Brick.OneToOnePreload {
 		preloadBrick := ctx.Brick.MapPreloadBrick[fieldName]
 		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		// set sub model relation field
 		for _, record := range ctx.Result.Records.GetRecords() {
 			// it means relation field, result[j].LastInsertId() is id value
@@ -996,7 +1124,7 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 		}
 		// if main model is hard delete need set relationship field set zero if sub model is soft delete
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 		result, err := preloadBrick.deleteWithPrimaryKey(subRecords)
@@ -1009,8 +1137,8 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 	// one to many
 	for fieldName, preload := range ctx.Brick.OneToManyPreload {
 		preloadBrick := ctx.Brick.MapPreloadBrick[fieldName]
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
 		subRecords := MakeRecordsWithElem(preload.SubModel, elemAddressType)
 		for _, record := range ctx.Result.Records.GetRecords() {
@@ -1021,7 +1149,7 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 		}
 		// model relationship field set zero
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 		result, err := preloadBrick.deleteWithPrimaryKey(subRecords)
@@ -1035,8 +1163,8 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 		subBrick := ctx.Brick.MapPreloadBrick[fieldName]
 		middleBrick := NewCollectionBrick(ctx.Brick.Toy, preload.MiddleModel).CopyStatus(ctx.Brick)
 		mainField, subField := preload.Model.GetOnePrimary(), preload.SubModel.GetOnePrimary()
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := subBrick.softDeleteMode()
 
 		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
 		subRecords := MakeRecordsWithElem(preload.SubModel, elemAddressType)
@@ -1121,10 +1249,10 @@ func HandlerCollectionPreloadDelete(ctx *CollectionContext) error {
 			subRecords.Add(record.FieldAddress(fieldName))
 		}
 
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 
@@ -1165,10 +1293,7 @@ func HandlerCollectionHardDelete(ctx *CollectionContext) error {
 
 //
 func HandlerCollectionSoftDeleteCheck(ctx *CollectionContext) error {
-	deletedField := ctx.Brick.Model.GetFieldWithName("DeletedAt")
-	if deletedField != nil {
-		ctx.Brick = ctx.Brick.Where(ExprNull, deletedField).And().Conditions(ctx.Brick.Search)
-	}
+	ctx.Brick = ctx.Brick.softDeleteScope()
 	return nil
 }
 
@@ -1177,13 +1302,13 @@ func HandlerCollectionSoftDelete(ctx *CollectionContext) error {
 		return ErrDbIndexNotSet{}
 	}
 
-	now := time.Now()
+	deletedField := ctx.Brick.Model.GetSoftDeleteField()
 	value := reflect.New(ctx.Brick.Model.ReflectType).Elem()
 	record := NewStructRecord(ctx.Brick.Model, value)
-	record.SetField("DeletedAt", reflect.ValueOf(now))
-	bindFields := []interface{}{"DeletedAt"}
+	record.SetField(deletedField.Name(), softDeletedValue(deletedField, time.Now()))
+	bindFields := []interface{}{deletedField.Name()}
 	for _, preload := range ctx.Brick.BelongToPreload {
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		subSoftDelete := preload.SubModel.GetSoftDeleteField() != nil
 		if subSoftDelete == false {
 			rField := preload.RelationField
 			bindFields = append(bindFields, rField.Name())
@@ -1198,13 +1323,156 @@ func HandlerCollectionSoftDelete(ctx *CollectionContext) error {
 	return nil
 }
 
+// restore the preload data which HandlerCollectionPreloadDelete deleted
+func HandlerCollectionPreloadRestore(ctx *CollectionContext) error {
+	for fieldName, preload := range ctx.Brick.OneToOnePreload {
+		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
+		for _, record := range ctx.Result.Records.GetRecords() {
+			subRecords.Add(record.FieldAddress(fieldName))
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	for fieldName, preload := range ctx.Brick.BelongToPreload {
+		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
+		for _, record := range ctx.Result.Records.GetRecords() {
+			subRecords.Add(record.FieldAddress(fieldName))
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	// one to many and many to many restore sub data, the middle data is not soft delete
+	sliceFields := map[string]*Model{}
+	for fieldName, preload := range ctx.Brick.OneToManyPreload {
+		sliceFields[fieldName] = preload.SubModel
+	}
+	for fieldName, preload := range ctx.Brick.ManyToManyPreload {
+		sliceFields[fieldName] = preload.SubModel
+	}
+	for fieldName, subModel := range sliceFields {
+		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
+		subRecords := MakeRecordsWithElem(subModel, elemAddressType)
+		for _, record := range ctx.Result.Records.GetRecords() {
+			rField := LoopIndirect(record.Field(fieldName))
+			for subi := 0; subi < rField.Len(); subi++ {
+				subRecords.Add(rField.Index(subi).Addr())
+			}
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clear the soft delete field, the model without soft delete field do nothing
+func HandlerCollectionRestore(ctx *CollectionContext) error {
+	deletedField := ctx.Brick.Model.GetSoftDeleteField()
+	if deletedField == nil {
+		return nil
+	}
+	if ctx.Brick.dbIndex == -1 {
+		return ErrDbIndexNotSet{}
+	}
+	zero := reflect.Zero(deletedField.StructField().Type)
+	for _, record := range ctx.Result.Records.GetRecords() {
+		record.SetField(deletedField.Name(), zero)
+	}
+	action := CollectionExecAction{dbIndex: ctx.Brick.dbIndex}
+	record := NewStructRecord(ctx.Brick.Model, reflect.New(ctx.Brick.Model.ReflectType).Elem())
+	ctx.Brick = ctx.Brick.BindFields(ModeUpdate, deletedField.Name())
+	action.Exec = ctx.Brick.UpdateExec(record)
+	action.Result, action.Error = ctx.Brick.Exec(action.Exec, action.dbIndex)
+	ctx.Result.AddRecord(action)
+	return nil
+}
+
 func HandlerCollectionCasVersionPushOne(ctx *CollectionContext) error {
 	records := ctx.Result.Records
-	casField := ctx.Brick.Model.GetFieldWithName("Cas")
+	casField := ctx.Brick.Model.GetVersionField()
 	if casField != nil {
 		for _, record := range records.GetRecords() {
-			record.SetField(casField.Name(), reflect.ValueOf(record.Field("Cas").Int()+1))
+			record.SetField(casField.Name(), reflect.ValueOf(versionValue(record.Field(casField.Name()))+1))
 		}
 	}
 	return nil
 }
+
+func CollectionHandlerBeforeInsertHook(ctx *CollectionContext) error {
+	return runRecordsHook(ctx.Result, "BeforeInsert", callBeforeInsert)
+}
+
+// call AfterInsert of records after the rest handlers success
+func CollectionHandlerAfterInsertHook(ctx *CollectionContext) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterInsert", callAfterInsert)
+}
+
+func CollectionHandlerBeforeSaveHook(ctx *CollectionContext) error {
+	return runRecordsHook(ctx.Result, "BeforeSave", callBeforeSave)
+}
+
+// call AfterSave of records after the rest handlers success
+func CollectionHandlerAfterSaveHook(ctx *CollectionContext) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterSave", callAfterSave)
+}
+
+func CollectionHandlerBeforeUpdateHook(ctx *CollectionContext) error {
+	return runRecordsHook(ctx.Result, "BeforeUpdate", callBeforeUpdate)
+}
+
+// call AfterUpdate of records after the rest handlers success
+func CollectionHandlerAfterUpdateHook(ctx *CollectionContext) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterUpdate", callAfterUpdate)
+}
+
+func CollectionHandlerBeforeDeleteHook(ctx *CollectionContext) error {
+	return runRecordsHook(ctx.Result, "BeforeDelete", callBeforeDelete)
+}
+
+// call AfterDelete of records after the rest handlers success
+func CollectionHandlerAfterDeleteHook(ctx *CollectionContext) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterDelete", callAfterDelete)
+}
+
+// call AfterFind of records after the rest handlers success
+func CollectionHandlerAfterFindHook(ctx *CollectionContext) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterFind", callAfterFind)
+}
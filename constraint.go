@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// the referential action of foreign key tag, e.g
+// `toyorm:"foreign key:on_delete=cascade,on_update=restrict"`
+var referentialActions = map[string]string{
+	"cascade":     "CASCADE",
+	"set_null":    "SET NULL",
+	"set_default": "SET DEFAULT",
+	"restrict":    "RESTRICT",
+	"no_action":   "NO ACTION",
+}
+
+// parse foreign key tag value, return the ON DELETE and ON UPDATE action
+func parseForeignKeyOption(val string) (onDelete, onUpdate string) {
+	if val == "" {
+		return
+	}
+	for _, option := range strings.Split(val, ",") {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			panic(ErrInvalidTag)
+		}
+		action, ok := referentialActions[strings.ToLower(strings.TrimSpace(kv[1]))]
+		if ok == false {
+			panic(ErrInvalidTag)
+		}
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "on_delete":
+			onDelete = action
+		case "on_update":
+			onUpdate = action
+		default:
+			panic(ErrInvalidTag)
+		}
+	}
+	return
+}
+
+// the check constraint of check tag in column definition, e.g CONSTRAINT chk_price CHECK (price > 0)
+// the constraint tag name the foreign key first, so check constraint is unnamed when field is foreign key
+func columnCheck(field Field) string {
+	check := field.Check()
+	if check == "" {
+		return ""
+	}
+	s := ""
+	if name := field.Constraint(); name != "" && field.IsForeign() == false {
+		s += " CONSTRAINT " + name
+	}
+	return s + " CHECK (" + check + ")"
+}
+
+// the foreign key definition of field, refTable is the quoted table name of referenced model, e.g
+// CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES `user`(id) ON DELETE CASCADE
+func foreignKeyDefinition(field Field, refTable string, refField Field) string {
+	s := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", field.Column(), refTable, refField.Column())
+	if name := field.Constraint(); name != "" {
+		s = "CONSTRAINT " + name + " " + s
+	}
+	if onDelete := field.OnDelete(); onDelete != "" {
+		s += " ON DELETE " + onDelete
+	}
+	if onUpdate := field.OnUpdate(); onUpdate != "" {
+		s += " ON UPDATE " + onUpdate
+	}
+	return s
+}
+
+// the field tag of many-to-many middle model, when container field have foreign key tag
+// the middle table will create foreign keys with the same referential action
+func middleModelTag(containerField Field) reflect.StructTag {
+	if containerField.IsForeign() == false {
+		return `toyorm:"primary key"`
+	}
+	var options []string
+	for _, option := range [][2]string{{"on_delete", containerField.OnDelete()}, {"on_update", containerField.OnUpdate()}} {
+		for k, v := range referentialActions {
+			if v == option[1] {
+				options = append(options, option[0]+"="+k)
+			}
+		}
+	}
+	if len(options) == 0 {
+		return `toyorm:"primary key;foreign key"`
+	}
+	return reflect.StructTag(fmt.Sprintf(`toyorm:"primary key;foreign key:%s"`, strings.Join(options, ",")))
+}
@@ -24,16 +24,17 @@ type Context struct {
 	//err      error
 }
 
+// Deadline, Done, Err and Value forward to the brick context
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	return
+	return c.Brick.Context().Deadline()
 }
 
 func (c *Context) Done() <-chan struct{} {
-	return nil
+	return c.Brick.Context().Done()
 }
 
 func (c *Context) Err() error {
-	return nil
+	return c.Brick.Context().Err()
 }
 
 func NewContext(handlers HandlersChain, brick *ToyBrick, columns ModelRecords) *Context {
@@ -53,10 +54,20 @@ func NewContext(handlers HandlersChain, brick *ToyBrick, columns ModelRecords) *
 }
 
 func (c *Context) Value(v interface{}) interface{} {
-	return c.value[v]
+	if val, ok := c.value[v]; ok {
+		return val
+	}
+	return c.Brick.Context().Value(v)
 }
 
 func (c *Context) Next() error {
+	// the brick with invalid sub query condition can't run
+	if c.index == -1 {
+		if err := c.Brick.searchErr(); err != nil {
+			c.Abort()
+			return err
+		}
+	}
 	c.index++
 	//handlerNames := make([]string, len(c.handlers))
 	//for i, h := range c.handlers {
@@ -107,11 +118,33 @@ func NewCollectionContext(handlers CollectionHandlersChain, brick *CollectionBri
 	}
 }
 
+func (c *CollectionContext) Deadline() (deadline time.Time, ok bool) {
+	return c.Brick.Context().Deadline()
+}
+
+func (c *CollectionContext) Done() <-chan struct{} {
+	return c.Brick.Context().Done()
+}
+
+func (c *CollectionContext) Err() error {
+	return c.Brick.Context().Err()
+}
+
 func (c *CollectionContext) Value(v interface{}) interface{} {
-	return c.value[v]
+	if val, ok := c.value[v]; ok {
+		return val
+	}
+	return c.Brick.Context().Value(v)
 }
 
 func (c *CollectionContext) Next() error {
+	// the brick with invalid sub query condition can't run
+	if c.index == -1 {
+		if err := c.Brick.searchErr(); err != nil {
+			c.Abort()
+			return err
+		}
+	}
 	c.index++
 	//handlerNames := make([]string, len(c.handlers))
 	//for i, h := range c.handlers {
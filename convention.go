@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+)
+
+// the field convention set by tag, e.g `toyorm:"soft delete"`,
+// when model have not the tag, use the field with default name
+type Convention int
+
+const (
+	ConventionSoftDelete Convention = iota
+	ConventionCreatedAt
+	ConventionUpdatedAt
+	ConventionVersion
+	ConventionEnd
+)
+
+var conventionTags = map[string]Convention{
+	"soft delete": ConventionSoftDelete,
+	"created at":  ConventionCreatedAt,
+	"updated at":  ConventionUpdatedAt,
+	"version":     ConventionVersion,
+}
+
+var conventionDefaultNames = [ConventionEnd]string{
+	ConventionSoftDelete: "DeletedAt",
+	ConventionCreatedAt:  "CreatedAt",
+	ConventionUpdatedAt:  "UpdatedAt",
+	ConventionVersion:    "Cas",
+}
+
+func (c Convention) String() string {
+	for tag, convention := range conventionTags {
+		if convention == c {
+			return tag
+		}
+	}
+	return "unknown"
+}
+
+// the condition of not deleted record, soft delete field can be
+// time (NULL mean not deleted), bool (false mean not deleted) or unix time int (0 mean not deleted)
+func notDeletedCondition(field Field) (SearchExpr, []interface{}) {
+	switch LoopTypeIndirect(field.StructField().Type).Kind() {
+	case reflect.Bool:
+		return ExprEqual, []interface{}{false}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ExprEqual, []interface{}{0}
+	default:
+		return ExprNull, nil
+	}
+}
+
+// the condition of soft deleted record
+func deletedCondition(field Field) (SearchExpr, []interface{}) {
+	switch LoopTypeIndirect(field.StructField().Type).Kind() {
+	case reflect.Bool:
+		return ExprEqual, []interface{}{true}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ExprNotEqual, []interface{}{0}
+	default:
+		return ExprNotNull, nil
+	}
+}
+
+// the soft delete filter of Find/Count/Update
+type deletedScope int
+
+const (
+	scopeNotDeleted deletedScope = iota
+	scopeUnscoped
+	scopeOnlyDeleted
+)
+
+// the soft delete field value of deleted record
+func softDeletedValue(field Field, now time.Time) reflect.Value {
+	if LoopTypeIndirect(field.StructField().Type).Kind() == reflect.Bool {
+		return reflect.ValueOf(true)
+	}
+	return timeFieldValue(field, now)
+}
+
+// the int field use unix time
+func timeFieldValue(field Field, now time.Time) reflect.Value {
+	switch LoopTypeIndirect(field.StructField().Type).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(now.Unix())
+	default:
+		return reflect.ValueOf(now)
+	}
+}
+
+// the version field value as int64, it can be any int or uint type
+func versionValue(v reflect.Value) int64 {
+	v = LoopIndirect(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	default:
+		return v.Int()
+	}
+}
+
+// return ErrOptimisticLockConflict when the write with version condition affect nothing
+func versionConflictCheck(model *Model, record ModelRecord, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return optimisticLockConflict(model, record)
+	}
+	return nil
+}
+
+func optimisticLockConflict(model *Model, record ModelRecord) error {
+	var primaryKey []interface{}
+	for _, field := range model.GetPrimary() {
+		if fieldValue := record.Field(field.Name()); fieldValue.IsValid() {
+			primaryKey = append(primaryKey, fieldValue.Interface())
+		}
+	}
+	return ErrOptimisticLockConflict{model.Name, primaryKey}
+}
+
+// the record version have been push one, previous version is 0 mean it's a new record or have no version
+func versionPushed(versionField Field, record ModelRecord) bool {
+	return versionField != nil && versionValue(record.Field(versionField.Name())) > 1
+}
+
+// the record keep previous version when the versioned write failure, the version was pushed by HandlerCasVersionPushOne
+func versionRestore(versionField Field, record ModelRecord) {
+	if versionField != nil {
+		record.SetField(versionField.Name(), reflect.ValueOf(versionValue(record.Field(versionField.Name()))-1))
+	}
+}
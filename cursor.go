@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Cursor scan the Find result one record at a time instead of load all records in memory,
+// it read BatchSize records as a chunk and preload them together
+type Cursor struct {
+	brick       *ToyBrick
+	rows        *sql.Rows
+	elemType    reflect.Type
+	scannersGen func(ModelRecord) []interface{}
+	chunk       ModelRecords
+	index       int
+	done        bool
+	err         error
+}
+
+// create a cursor of Find, v is the record type e.g &User{}, the Scan data must be same type
+func (t *ToyBrick) Cursor(v interface{}) (*Cursor, error) {
+	elemType := LoopTypeIndirect(reflect.TypeOf(v))
+	records := MakeRecordsWithElem(t.Model, elemType)
+	ctx := NewContext(HandlersChain{HandlerPreloadContainerCheck, HandlerSoftDeleteCheck}, t, records)
+	if err := ctx.Next(); err != nil {
+		return nil, err
+	}
+	brick := ctx.Brick
+	if brick.tx != nil && cursorHasPreload(brick) {
+		// sqlite driver can run other query when rows is open in the same connection
+		if _, ok := brick.Toy.Dialect.(Sqlite3Dialect); ok == false {
+			return nil, ErrCursorPreloadInTx
+		}
+	}
+	columns, scannersGen := FindColumnFactory(records, brick)
+	exec, err := brick.findOrTemplateExec(columns)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := brick.Query(exec)
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{
+		brick:       brick,
+		rows:        rows,
+		elemType:    elemType,
+		scannersGen: scannersGen,
+		chunk:       records,
+	}, nil
+}
+
+// the preload of cursor need query when rows is open
+func cursorHasPreload(brick *ToyBrick) bool {
+	if len(brick.MapPreloadBrick) != 0 {
+		return true
+	}
+	for name := range brick.JoinMap {
+		if len(brick.SwapMap[name].MapPreloadBrick) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare the next record, it will read next chunk and preload them when current chunk is exhausted
+func (c *Cursor) Next() bool {
+	c.index++
+	if c.index < c.chunk.Len() {
+		return true
+	}
+	if c.done || c.err != nil {
+		return false
+	}
+	c.chunk = MakeRecordsWithElem(c.brick.Model, c.elemType)
+	c.index = 0
+	chunkSize := c.brick.getBatchSize()
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	for c.chunk.Len() < chunkSize {
+		if c.rows.Next() == false {
+			c.done = true
+			c.err = c.rows.Err()
+			break
+		}
+		record := c.chunk.Add(reflect.New(c.elemType).Elem())
+		if err := c.rows.Scan(c.scannersGen(record)...); err != nil {
+			c.err = err
+			return false
+		}
+	}
+	if c.chunk.Len() == 0 {
+		return false
+	}
+	ctx := NewContext(HandlersChain{HandlerAfterFindHook, HandlerPreloadOnJoinFind, HandlerPreloadFind}, c.brick, c.chunk)
+	if err := ctx.Next(); err != nil {
+		c.err = err
+		return false
+	}
+	if err := ctx.Result.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	return true
+}
+
+// copy current record to v, v must be the pointer of record type
+func (c *Cursor) Scan(v interface{}) error {
+	if c.index >= c.chunk.Len() {
+		return sql.ErrNoRows
+	}
+	vValue := LoopIndirectAndNew(reflect.ValueOf(v))
+	if vValue.Type() != c.elemType {
+		panic(fmt.Sprintf("scan data type(%s) must be type(%s)", vValue.Type(), c.elemType))
+	}
+	vValue.Set(c.chunk.GetRecord(c.index).Source())
+	return nil
+}
+
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
+
+// call fn with each record of Find, fn must be func(*T) error, iterate stop when fn return error
+func (t *ToyBrick) Iterate(fn interface{}) error {
+	fnValue := reflect.ValueOf(fn)
+	fnType := fnValue.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 1 || fnType.In(0).Kind() != reflect.Ptr ||
+		fnType.NumOut() != 1 || fnType.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		panic("iterate function must be func(*T) error")
+	}
+	elemType := fnType.In(0).Elem()
+	cursor, err := t.Cursor(reflect.New(elemType).Interface())
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		v := reflect.New(elemType)
+		if err := cursor.Scan(v.Interface()); err != nil {
+			return err
+		}
+		if ret := fnValue.Call([]reflect.Value{v})[0]; ret.IsNil() == false {
+			return ret.Interface().(error)
+		}
+	}
+	return cursor.Err()
+}
@@ -7,9 +7,11 @@
 package toyorm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -24,27 +26,87 @@ type Executor interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 }
 
+// sql.DB and sql.Tx executor with context
+type contextDBExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// wrap the context executor to Executor, Dialect executor use it to bind context
+type contextExecutor struct {
+	ctx context.Context
+	db  contextDBExecutor
+}
+
+func (e contextExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return e.db.ExecContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return e.db.QueryRowContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return e.db.QueryContext(e.ctx, query, args...)
+}
+
 type Dialect interface {
 	// some database like postgres not support LastInsertId, need QueryRow to get the return id
 	InsertExecutor(Executor, ExecValue, func(ExecValue, error)) (sql.Result, error)
+	// insert multiple records in one statement, return the insert id list of records if it can get
+	InsertValuesExecutor(Executor, ExecValue, int, func(ExecValue, error)) (sql.Result, []int64, error)
+	// some database can't get the primary key of the update row when upsert
+	UpsertExecutor(Executor, ExecValue, func(ExecValue, error)) (sql.Result, error)
 	// sqlite3/postgresql use RowsAffected to check success or failure, but mysql can't,
 	// because it's RowsAffected is zero when update value not change
 	SaveExecutor(Executor, ExecValue, func(ExecValue, error)) (sql.Result, error)
 	HasTable(*Model) ExecValue
 	CreateTable(*Model, map[string]ForeignKey) []ExecValue
 	DropTable(*Model) ExecValue
-	ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column) ExecValue
+	ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column, having SearchList) ExecValue
 	FindExec(model *Model, columns []Column, alias string) ExecValue
 	UpdateExec(*Model, []ColumnValue) ExecValue
 	DeleteExec(*Model) ExecValue
 	InsertExec(*Model, []ColumnNameValue) ExecValue
+	InsertValuesExec(*Model, [][]ColumnNameValue) ExecValue
 	SaveExec(*Model, []ColumnNameValue) ExecValue
+	// insert record, update the conflict record with strategies, the column without strategy use UpsertOverwrite
+	UpsertExec(model *Model, columnValues []ColumnNameValue, conflict []Column, strategies map[string]UpsertStrategy) ExecValue
 	AddForeignKey(model, relationModel *Model, ForeignKeyField Field) ExecValue
 	DropForeignKey(model *Model, ForeignKeyField Field) ExecValue
 	CountExec(model *Model, alias string) ExecValue
 	SearchExec(search SearchList) ExecValue
 	TemplateExec(BasicExec, map[string]BasicExec) (ExecValue, error)
+	// the sql type of field in this database
+	SqlType(Field) string
+	// query the labels of exist enum type used by fields, the query result columns must be
+	// type name, label (order by label position), return nil if database not use enum type
+	EnumLabelsExec([]Field) ExecValue
+	// create the enum type used by fields, labels is the exist enum type labels,
+	// the exist enum type will add the new values, nil labels mean unknown and create all of them
+	EnumTypeExecs(fields []Field, labels map[string][]string) []ExecValue
+	// the column which extract the json path value, path is bind argument, numeric means compare with number
+	JsonExtract(column, path string, numeric bool) ExecValue
 	JoinExec(*JoinSwap) ExecValue
+	// nested transaction use save point
+	SavePointExec(name string) ExecValue
+	ReleaseSavePointExec(name string) ExecValue
+	RollbackToSavePointExec(name string) ExecValue
+	// table introspection use to migrate, the query result columns must be
+	// columns: name, type, nullable, default
+	// indexes: index name, unique, column name (order by index name and column position, primary key index not included)
+	// foreign keys: constraint name, column name, referenced table, referenced column
+	TableColumnsExec(*Model) ExecValue
+	TableIndexesExec(*Model) ExecValue
+	TableForeignKeysExec(*Model) ExecValue
+	// alter table, return nil if database not support it
+	AddColumnExec(*Model, Field) ExecValue
+	AlterColumnExec(*Model, Field) ExecValue
+	CreateIndexExec(model *Model, name string, columns []Column, unique bool) ExecValue
+	DropIndexExec(model *Model, name string) ExecValue
+	// DDL can rollback in transaction, mysql will commit implicitly when exec DDL
+	TransactionalDDL() bool
 }
 
 type DefaultDialect struct{}
@@ -74,6 +136,27 @@ func (dia DefaultDialect) InsertExecutor(db Executor, exec ExecValue, debugPrint
 	return result, err
 }
 
+func (dia DefaultDialect) UpsertExecutor(db Executor, exec ExecValue, debugPrinter func(ExecValue, error)) (sql.Result, error) {
+	return dia.InsertExecutor(db, exec, debugPrinter)
+}
+
+// mysql LastInsertId is the first id of multiple insert, and auto increment id is consecutive
+func (dia DefaultDialect) InsertValuesExecutor(db Executor, exec ExecValue, n int, debugPrinter func(ExecValue, error)) (sql.Result, []int64, error) {
+	query := exec.Query()
+	result, err := db.Exec(query, exec.Args()...)
+	debugPrinter(exec, err)
+	if err != nil {
+		return result, nil, err
+	}
+	var ids []int64
+	if firstId, e := result.LastInsertId(); e == nil {
+		for i := 0; i < n; i++ {
+			ids = append(ids, firstId+int64(i))
+		}
+	}
+	return result, ids, nil
+}
+
 // use to test
 func (dia DefaultDialect) HasTable(model *Model) ExecValue {
 	return DefaultExec{
@@ -90,7 +173,7 @@ func (dia DefaultDialect) CreateTable(model *Model, foreign map[string]ForeignKe
 
 	for _, sqlField := range model.GetSqlFields() {
 
-		s := fmt.Sprintf("%s %s", sqlField.Column(), sqlField.SqlType())
+		s := fmt.Sprintf("%s %s", sqlField.Column(), dia.SqlType(sqlField))
 		if sqlField.AutoIncrement() {
 			s += " AUTO_INCREMENT"
 		}
@@ -101,6 +184,7 @@ func (dia DefaultDialect) CreateTable(model *Model, foreign map[string]ForeignKe
 				s += " " + fmt.Sprintf("%s=%s", k, v)
 			}
 		}
+		s += enumCheck(sqlField) + columnCheck(sqlField)
 		strList = append(strList, s)
 	}
 	var primaryStrList []string
@@ -112,7 +196,7 @@ func (dia DefaultDialect) CreateTable(model *Model, foreign map[string]ForeignKe
 	for name, key := range foreign {
 		f := model.GetFieldWithName(name)
 		strList = append(strList,
-			fmt.Sprintf("FOREIGN KEY (%s) REFERENCES `%s`(%s)", f.Column(), key.Model.Name, key.Field.Column()),
+			foreignKeyDefinition(f, "`"+key.Model.Name+"`", key.Field),
 		)
 	}
 
@@ -151,7 +235,7 @@ func (dia DefaultDialect) DropTable(m *Model) ExecValue {
 	return DefaultExec{fmt.Sprintf("DROP TABLE `%s`", m.Name), nil}
 }
 
-func (dia DefaultDialect) ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column) ExecValue {
+func (dia DefaultDialect) ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column, having SearchList) ExecValue {
 	var exec ExecValue = DefaultExec{}
 	if len(search) > 0 {
 		searchExec := dia.SearchExec(search)
@@ -164,6 +248,10 @@ func (dia DefaultDialect) ConditionExec(search SearchList, limit, offset int, or
 		}
 		exec = exec.Append(" GROUP BY " + strings.Join(list, ","))
 	}
+	if len(having) > 0 {
+		havingExec := dia.SearchExec(having)
+		exec = exec.Append(" HAVING "+havingExec.Source(), havingExec.Args()...)
+	}
 	if len(orderBy) > 0 {
 		var __list []string
 		for _, column := range orderBy {
@@ -185,6 +273,13 @@ func (dia DefaultDialect) SearchExec(s SearchList) ExecValue {
 	for i := 0; i < len(s); i++ {
 
 		var exec ExecValue = DefaultExec{}
+		if s[i].Type.IsBranch() == false {
+			exec = exec.Append("", columnArgs(s[i].Val)...)
+			if vExec, ok := searchReferenceExec(s[i]); ok {
+				stack = append(stack, exec.Append(vExec.Source(), vExec.Args()...))
+				continue
+			}
+		}
 		switch s[i].Type {
 		case ExprAnd:
 			if len(stack) < 2 {
@@ -194,7 +289,7 @@ func (dia DefaultDialect) SearchExec(s SearchList) ExecValue {
 			stack = stack[:len(stack)-2]
 
 			exec = exec.Append(
-				fmt.Sprintf(last2.Source()),
+				last2.Source(),
 				last2.Args()...,
 			)
 			exec = exec.Append(" AND "+last1.Source(), last1.Args()...)
@@ -317,7 +412,8 @@ func (dia DefaultDialect) SearchExec(s SearchList) ExecValue {
 			exec = exec.Append(
 				fmt.Sprintf("%s IS NOT NULL", s[i].Val.Column()),
 			)
-
+		case ExprContains, ExprOverlap, ExprAny:
+			panic(ErrNotSupportExpr(s[i].Type))
 		}
 		stack = append(stack, exec)
 
@@ -370,6 +466,49 @@ func (dia DefaultDialect) InsertExec(model *Model, columnValues []ColumnNameValu
 	return exec
 }
 
+func (dia DefaultDialect) InsertValuesExec(model *Model, valuesList [][]ColumnNameValue) ExecValue {
+	fieldStr, qStr, args := insertMultipleValuesFormat(model, valuesList)
+
+	var exec ExecValue = DefaultExec{}
+	exec = exec.Append(
+		fmt.Sprintf("INSERT INTO `%s`(%s) VALUES%s", model.Name, fieldStr, qStr),
+		args...,
+	)
+	return exec
+}
+
+// mysql ON DUPLICATE KEY UPDATE will check all of unique index, conflict columns is unused
+// use id = LAST_INSERT_ID(id) to get the primary key of update row
+func (dia DefaultDialect) UpsertExec(model *Model, columnValues []ColumnNameValue, conflict []Column, strategies map[string]UpsertStrategy) ExecValue {
+	fieldStr, qStr, args := insertValuesFormat(model, columnValues)
+
+	var exec ExecValue = DefaultExec{}
+	exec = exec.Append(
+		fmt.Sprintf("INSERT INTO `%s`(%s) VALUES(%s)", model.Name, fieldStr, qStr),
+		args...,
+	)
+	var recordList []string
+	var recordArgs []interface{}
+	for _, r := range upsertUpdateFields(model, columnValues, conflict) {
+		strategy := strategies[r.Name()]
+		switch strategy.Action {
+		case UpsertOverwrite:
+			recordList = append(recordList, fmt.Sprintf("%[1]s = VALUES(%[1]s)", r.Column()))
+		case UpsertExpr:
+			recordList = append(recordList, fmt.Sprintf("%s = %s", r.Column(), excludedToValues(strategy.Expr)))
+			recordArgs = append(recordArgs, strategy.Args...)
+		}
+	}
+	if len(model.GetPrimary()) == 1 && model.GetOnePrimary().AutoIncrement() {
+		recordList = append(recordList, fmt.Sprintf("%[1]s = LAST_INSERT_ID(%[1]s)", model.GetOnePrimary().Column()))
+	} else if len(recordList) == 0 {
+		// all of column keep, use a useless update
+		recordList = append(recordList, fmt.Sprintf("%[1]s = %[1]s", conflict[0].Column()))
+	}
+	exec = exec.Append(" ON DUPLICATE KEY UPDATE "+strings.Join(recordList, ","), recordArgs...)
+	return exec
+}
+
 func (dia DefaultDialect) SaveExec(model *Model, columnValues []ColumnNameValue) ExecValue {
 	// optimization column format
 	fieldStr, qStr, args := insertValuesFormat(model, columnValues)
@@ -385,13 +524,15 @@ func (dia DefaultDialect) SaveExec(model *Model, columnValues []ColumnNameValue)
 func (dia DefaultDialect) AddForeignKey(model, relationModel *Model, ForeignKeyField Field) ExecValue {
 
 	return DefaultExec{fmt.Sprintf(
-		"ALTER TABLE `%s` ADD FOREIGN KEY (%s) REFERENCES `%s`(%s)",
-		model.Name, ForeignKeyField.Column(),
-		relationModel.Name, relationModel.GetOnePrimary().Column(),
+		"ALTER TABLE `%s` ADD %s",
+		model.Name, foreignKeyDefinition(ForeignKeyField, "`"+relationModel.Name+"`", relationModel.GetOnePrimary()),
 	), nil}
 }
 
 func (dia DefaultDialect) DropForeignKey(model *Model, ForeignKeyField Field) ExecValue {
+	if name := ForeignKeyField.Constraint(); name != "" {
+		return DefaultExec{fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY %s", model.Name, name), nil}
+	}
 	return DefaultExec{fmt.Sprintf(
 		"ALTER TABLE `%s` DROP FOREIGN KEY (%s)", model.Name, ForeignKeyField.Column(),
 	), nil}
@@ -415,21 +556,183 @@ func (dia DefaultDialect) TemplateExec(tExec BasicExec, execs map[string]BasicEx
 }
 
 func (dia DefaultDialect) JoinExec(mainSwap *JoinSwap) ExecValue {
-	var strList []string
+	names := make([]string, 0, len(mainSwap.JoinMap))
 	for name := range mainSwap.JoinMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var exec ExecValue = DefaultExec{"", nil}
+	for i, name := range names {
 		join := mainSwap.JoinMap[name]
 		swap := mainSwap.SwapMap[name]
-		strList = append(strList, fmt.Sprintf("JOIN `%s` AS `%s` ON %s.%s = %s.%s",
-			join.SubModel.Name,
+		if i != 0 {
+			exec = exec.Append(" ")
+		}
+		exec = exec.Append(fmt.Sprintf("%s `%s` AS `%s` ON %s.%s = %s.%s",
+			join.Type, join.SubModel.Name,
 			swap.Alias,
 			mainSwap.Alias, join.OnMain.Column(),
 			swap.Alias, join.OnSub.Column(),
 		))
+		if len(join.OnSearch) > 0 {
+			onExec := dia.SearchExec(join.OnSearch)
+			exec = exec.Append(" AND "+onExec.Source(), onExec.Args()...)
+		}
 	}
-	var exec ExecValue = DefaultExec{strings.Join(strList, " "), nil}
-	for _, subSwap := range mainSwap.SwapMap {
-		subExec := dia.JoinExec(subSwap)
+	for _, name := range names {
+		subExec := dia.JoinExec(mainSwap.SwapMap[name])
 		exec = exec.Append(" "+subExec.Source(), subExec.Args()...)
 	}
 	return exec
 }
+
+func (dia DefaultDialect) SavePointExec(name string) ExecValue {
+	return DefaultExec{"SAVEPOINT " + name, nil}
+}
+
+func (dia DefaultDialect) ReleaseSavePointExec(name string) ExecValue {
+	return DefaultExec{"RELEASE SAVEPOINT " + name, nil}
+}
+
+func (dia DefaultDialect) RollbackToSavePointExec(name string) ExecValue {
+	return DefaultExec{"ROLLBACK TO SAVEPOINT " + name, nil}
+}
+
+func (dia DefaultDialect) TableColumnsExec(model *Model) ExecValue {
+	return DefaultExec{
+		"SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', COLUMN_DEFAULT FROM INFORMATION_SCHEMA.COLUMNS " +
+			"WHERE TABLE_SCHEMA = (SELECT DATABASE()) AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		[]interface{}{model.Name},
+	}
+}
+
+func (dia DefaultDialect) TableIndexesExec(model *Model) ExecValue {
+	return DefaultExec{
+		"SELECT INDEX_NAME, NON_UNIQUE = 0, COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS " +
+			"WHERE TABLE_SCHEMA = (SELECT DATABASE()) AND TABLE_NAME = ? AND INDEX_NAME <> 'PRIMARY' ORDER BY INDEX_NAME, SEQ_IN_INDEX",
+		[]interface{}{model.Name},
+	}
+}
+
+func (dia DefaultDialect) TableForeignKeysExec(model *Model) ExecValue {
+	return DefaultExec{
+		"SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE " +
+			"WHERE TABLE_SCHEMA = (SELECT DATABASE()) AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL",
+		[]interface{}{model.Name},
+	}
+}
+
+func (dia DefaultDialect) EnumLabelsExec(fields []Field) ExecValue {
+	return nil
+}
+
+func (dia DefaultDialect) EnumTypeExecs(fields []Field, labels map[string][]string) []ExecValue {
+	return nil
+}
+
+func (dia DefaultDialect) AddColumnExec(model *Model, field Field) ExecValue {
+	return addColumnExec(dia, model, field)
+}
+
+func (dia DefaultDialect) AlterColumnExec(model *Model, field Field) ExecValue {
+	return modifyColumnExec(dia, model, field)
+}
+
+// mysql style add column, the column type use dia.SqlType
+func addColumnExec(dia Dialect, model *Model, field Field) ExecValue {
+	s := columnDefinition(dia, field)
+	if field.AutoIncrement() {
+		s += " AUTO_INCREMENT"
+	}
+	s += enumCheck(field) + columnCheck(field)
+	return DefaultExec{fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN %s", model.Name, s), nil}
+}
+
+func modifyColumnExec(dia Dialect, model *Model, field Field) ExecValue {
+	s := columnDefinition(dia, field)
+	if field.AutoIncrement() {
+		s += " AUTO_INCREMENT"
+	}
+	return DefaultExec{fmt.Sprintf("ALTER TABLE `%s` MODIFY COLUMN %s", model.Name, s), nil}
+}
+
+func (dia DefaultDialect) SqlType(field Field) string {
+	return field.SqlType()
+}
+
+func (dia DefaultDialect) JsonExtract(column, path string, numeric bool) ExecValue {
+	if numeric {
+		return DefaultExec{fmt.Sprintf("JSON_EXTRACT(%s, ?)", column), []interface{}{jsonDollarPath(path)}}
+	}
+	return DefaultExec{fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, ?))", column), []interface{}{jsonDollarPath(path)}}
+}
+
+func (dia DefaultDialect) CreateIndexExec(model *Model, name string, columns []Column, unique bool) ExecValue {
+	var columnStrList []string
+	for _, c := range columns {
+		columnStrList = append(columnStrList, c.Column())
+	}
+	var indexType string
+	if unique {
+		indexType = "UNIQUE "
+	}
+	return DefaultExec{fmt.Sprintf("CREATE %sINDEX %s ON `%s`(%s)", indexType, name, model.Name, strings.Join(columnStrList, ",")), nil}
+}
+
+func (dia DefaultDialect) DropIndexExec(model *Model, name string) ExecValue {
+	return DefaultExec{fmt.Sprintf("DROP INDEX %s ON `%s`", name, model.Name), nil}
+}
+
+// the condition value is sub query brick or other column, e.g
+// id IN (SELECT user_id FROM blog), EXISTS (SELECT ...), user.id = blog.user_id
+func searchReferenceExec(cell SearchCell) (ExecValue, bool) {
+	// the invalid sub query is reported by brick before it run, don't build it
+	if v, ok := cell.Val.(subQueryValue); ok && v.err != nil {
+		return DefaultExec{}, true
+	}
+	value := cell.Val.Value()
+	if value.IsValid() == false || value.CanInterface() == false {
+		return nil, false
+	}
+	switch v := value.Interface().(type) {
+	case *ToyBrick:
+		sub := v.subQueryExec()
+		switch cell.Type {
+		case ExprExists, ExprNotExists:
+			return DefaultExec{fmt.Sprintf("%s (%s)", cell.Type, sub.Source()), sub.Args()}, true
+		}
+		return DefaultExec{fmt.Sprintf("%s %s (%s)", cell.Val.Column(), cell.Type, sub.Source()), sub.Args()}, true
+	case Field:
+		switch cell.Type {
+		case ExprEqual, ExprNotEqual, ExprGreater, ExprGreaterEqual, ExprLess, ExprLessEqual:
+			return DefaultExec{fmt.Sprintf("%s %s %s", cell.Val.Column(), cell.Type, v.Column()), nil}, true
+		}
+	}
+	return nil, false
+}
+
+// column definition with type, default value and extension attribute
+func columnDefinition(dia Dialect, field Field) string {
+	s := fmt.Sprintf("%s %s", field.Column(), dia.SqlType(field))
+	if _default := field.Default(); _default != "" {
+		s += " DEFAULT " + _default
+	}
+	attrs := field.Attrs()
+	var keys []string
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v := attrs[k]; v == "" {
+			s += " " + k
+		} else {
+			s += " " + fmt.Sprintf("%s=%s", k, v)
+		}
+	}
+	return s
+}
+
+func (dia DefaultDialect) TransactionalDDL() bool {
+	return false
+}
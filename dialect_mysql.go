@@ -37,7 +37,7 @@ func (dia MySqlDialect) CreateTable(model *Model, foreign map[string]ForeignKey)
 
 	for _, sqlField := range model.GetSqlFields() {
 
-		s := fmt.Sprintf("%s %s", sqlField.Column(), sqlField.SqlType())
+		s := fmt.Sprintf("%s %s", sqlField.Column(), dia.SqlType(sqlField))
 		if sqlField.AutoIncrement() {
 			s += " AUTO_INCREMENT"
 		}
@@ -51,6 +51,7 @@ func (dia MySqlDialect) CreateTable(model *Model, foreign map[string]ForeignKey)
 				s += " " + fmt.Sprintf("%s=%s", k, v)
 			}
 		}
+		s += enumCheck(sqlField) + columnCheck(sqlField)
 		strList = append(strList, s)
 	}
 	var primaryStrList []string
@@ -62,7 +63,7 @@ func (dia MySqlDialect) CreateTable(model *Model, foreign map[string]ForeignKey)
 	for name, key := range foreign {
 		f := model.GetFieldWithName(name)
 		strList = append(strList,
-			fmt.Sprintf("FOREIGN KEY (%s) REFERENCES `%s`(%s)", f.Column(), key.Model.Name, key.Field.Column()),
+			foreignKeyDefinition(f, "`"+key.Model.Name+"`", key.Field),
 		)
 	}
 
@@ -107,9 +108,10 @@ func (dia MySqlDialect) SaveExec(model *Model, columnValues []ColumnNameValue) E
 	)
 
 	var recordList []string
+	versionField := model.GetVersionField()
 	for _, r := range columnValues {
-		switch r.Name() {
-		case "Cas":
+		switch {
+		case versionField != nil && r.Name() == versionField.Name():
 			recordList = append(recordList, fmt.Sprintf("%[1]s = IF(%[1]s = VALUES(%[1]s) - 1, VALUES(%[1]s) , \"update failure\")", r.Column()))
 		default:
 			recordList = append(recordList, fmt.Sprintf("%[1]s = VALUES(%[1]s)", r.Column()))
@@ -122,3 +124,40 @@ func (dia MySqlDialect) SaveExec(model *Model, columnValues []ColumnNameValue) E
 
 	return exec
 }
+
+func (dia MySqlDialect) AddColumnExec(model *Model, field Field) ExecValue {
+	return addColumnExec(dia, model, field)
+}
+
+func (dia MySqlDialect) AlterColumnExec(model *Model, field Field) ExecValue {
+	return modifyColumnExec(dia, model, field)
+}
+
+func (dia MySqlDialect) SqlType(field Field) string {
+	return dialectSqlType("mysql", field, func(kind sqlKind, field Field) string {
+		switch kind {
+		case sqlKindUBigInt:
+			return "BIGINT UNSIGNED"
+		case sqlKindFloat:
+			return "FLOAT"
+		case sqlKindDouble:
+			return "DOUBLE"
+		case sqlKindBytes:
+			// BLOB max size is 64KB, MEDIUMBLOB is 16MB
+			switch size := field.Size(); {
+			case size > 1<<24-1:
+				return "LONGBLOB"
+			case size > 1<<16-1:
+				return "MEDIUMBLOB"
+			}
+			return "BLOB"
+		case sqlKindString:
+			return varcharType(field)
+		case sqlKindTime:
+			return timeSqlType("TIMESTAMP", field)
+		case sqlKindJson:
+			return "JSON"
+		}
+		return ""
+	})
+}
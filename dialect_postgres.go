@@ -10,6 +10,7 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -52,6 +53,35 @@ func (dia PostgreSqlDialect) InsertExecutor(db Executor, exec ExecValue, debugPr
 	return result, err
 }
 
+// use RETURNING to get the insert id list of multiple insert
+func (dia PostgreSqlDialect) InsertValuesExecutor(db Executor, exec ExecValue, n int, debugPrinter func(ExecValue, error)) (sql.Result, []int64, error) {
+	var result RawResult
+	var ids []int64
+	query := exec.Query()
+	rows, err := db.Query(query, exec.Args()...)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err = rows.Scan(&id); err != nil {
+				break
+			}
+			ids = append(ids, id)
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	if len(ids) != 0 {
+		result.ID = ids[len(ids)-1]
+	} else {
+		result.Err = sql.ErrNoRows
+	}
+
+	debugPrinter(exec, err)
+	return result, ids, err
+}
+
 func (dia PostgreSqlDialect) CreateTable(model *Model, foreign map[string]ForeignKey) (execlist []ExecValue) {
 	// lazy init model
 	strList := []string{}
@@ -63,7 +93,7 @@ func (dia PostgreSqlDialect) CreateTable(model *Model, foreign map[string]Foreig
 		if sqlField.AutoIncrement() {
 			s = fmt.Sprintf("%s SERIAL", sqlField.Column())
 		} else {
-			s = fmt.Sprintf("%s %s", sqlField.Column(), sqlField.SqlType())
+			s = fmt.Sprintf("%s %s", sqlField.Column(), dia.SqlType(sqlField))
 		}
 		if _default := sqlField.Default(); _default != "" {
 			s += " DEFAULT " + _default
@@ -75,6 +105,7 @@ func (dia PostgreSqlDialect) CreateTable(model *Model, foreign map[string]Foreig
 				s += " " + fmt.Sprintf("%s=%s", k, v)
 			}
 		}
+		s += columnCheck(sqlField)
 		strList = append(strList, s)
 	}
 	var primaryStrList []string
@@ -86,7 +117,7 @@ func (dia PostgreSqlDialect) CreateTable(model *Model, foreign map[string]Foreig
 	for name, key := range foreign {
 		f := model.GetFieldWithName(name)
 		strList = append(strList,
-			fmt.Sprintf(`FOREIGN KEY (%s) REFERENCES "%s"(%s)`, f.Column(), key.Model.Name, key.Field.Column()),
+			foreignKeyDefinition(f, `"`+key.Model.Name+`"`, key.Field),
 		)
 	}
 
@@ -94,6 +125,7 @@ func (dia PostgreSqlDialect) CreateTable(model *Model, foreign map[string]Foreig
 		model.Name,
 		strings.Join(strList, ","),
 	)
+	execlist = append(execlist, dia.EnumTypeExecs(model.GetSqlFields(), nil)...)
 	execlist = append(execlist, QToSExec{DefaultExec{sqlStr, nil}})
 
 	indexStrList := []string{}
@@ -125,7 +157,7 @@ func (dia PostgreSqlDialect) DropTable(m *Model) ExecValue {
 	return QToSExec{DefaultExec{fmt.Sprintf(`DROP TABLE "%s"`, m.Name), nil}}
 }
 
-func (dia PostgreSqlDialect) ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column) ExecValue {
+func (dia PostgreSqlDialect) ConditionExec(search SearchList, limit, offset int, orderBy []Column, groupBy []Column, having SearchList) ExecValue {
 	var exec ExecValue = QToSExec{}
 	if len(search) > 0 {
 		searchExec := dia.SearchExec(search)
@@ -140,6 +172,10 @@ func (dia PostgreSqlDialect) ConditionExec(search SearchList, limit, offset int,
 		}
 		exec = exec.Append(" GROUP BY " + strings.Join(__list, ","))
 	}
+	if len(having) > 0 {
+		havingExec := dia.SearchExec(having)
+		exec = exec.Append(" HAVING "+havingExec.Source(), havingExec.Args()...)
+	}
 	if len(orderBy) > 0 {
 		var __list []string
 		for _, column := range orderBy {
@@ -161,6 +197,13 @@ func (dia PostgreSqlDialect) SearchExec(s SearchList) ExecValue {
 	for i := 0; i < len(s); i++ {
 
 		var exec ExecValue = QToSExec{}
+		if s[i].Type.IsBranch() == false {
+			exec = exec.Append("", columnArgs(s[i].Val)...)
+			if vExec, ok := searchReferenceExec(s[i]); ok {
+				stack = append(stack, exec.Append(vExec.Source(), vExec.Args()...))
+				continue
+			}
+		}
 		switch s[i].Type {
 		case ExprAnd:
 			if len(stack) < 2 {
@@ -286,7 +329,16 @@ func (dia PostgreSqlDialect) SearchExec(s SearchList) ExecValue {
 			exec = exec.Append(
 				fmt.Sprintf("%s IS NOT NULL", s[i].Val.Column()),
 			)
-
+		case ExprContains, ExprOverlap:
+			exec = exec.Append(
+				fmt.Sprintf("%s %s ?", s[i].Val.Column(), s[i].Type),
+				s[i].Val.Value().Interface(),
+			)
+		case ExprAny:
+			exec = exec.Append(
+				fmt.Sprintf("? = ANY(%s)", s[i].Val.Column()),
+				s[i].Val.Value().Interface(),
+			)
 		}
 		stack = append(stack, exec)
 
@@ -348,6 +400,34 @@ func (dia PostgreSqlDialect) InsertExec(model *Model, columnValues []ColumnNameV
 	return exec
 }
 
+func (dia PostgreSqlDialect) InsertValuesExec(model *Model, valuesList [][]ColumnNameValue) ExecValue {
+	fieldStr, qStr, args := insertMultipleValuesFormat(model, valuesList)
+
+	var exec ExecValue = QToSExec{}
+	exec = exec.Append(
+		fmt.Sprintf(`INSERT INTO "%s"(%s) VALUES%s`, model.Name, fieldStr, qStr),
+		args...,
+	)
+	if len(model.GetPrimary()) == 1 && IntKind(model.GetOnePrimary().StructField().Type.Kind()) {
+		exec = exec.Append(" RETURNING " + model.GetOnePrimary().Column())
+	}
+	return exec
+}
+
+func (dia PostgreSqlDialect) UpsertExecutor(db Executor, exec ExecValue, debugPrinter func(ExecValue, error)) (sql.Result, error) {
+	return dia.InsertExecutor(db, exec, debugPrinter)
+}
+
+func (dia PostgreSqlDialect) UpsertExec(model *Model, columnValues []ColumnNameValue, conflict []Column, strategies map[string]UpsertStrategy) ExecValue {
+	exec := dia.insertExec(model, columnValues)
+	conflictStr, conflictArgs := onConflictFormat(model, columnValues, conflict, strategies)
+	exec = exec.Append(conflictStr, conflictArgs...)
+	if len(model.GetPrimary()) == 1 && IntKind(model.GetOnePrimary().StructField().Type.Kind()) {
+		exec = exec.Append(" RETURNING " + model.GetOnePrimary().Column())
+	}
+	return exec
+}
+
 // postgres have not replace use ON CONFLICT(%s) replace
 func (dia PostgreSqlDialect) SaveExec(model *Model, columnNameValues []ColumnNameValue) ExecValue {
 	fieldStr, qStr, args := insertValuesFormat(model, columnNameValues)
@@ -364,8 +444,9 @@ func (dia PostgreSqlDialect) SaveExec(model *Model, columnNameValues []ColumnNam
 	}
 	var recordList []string
 	var casField ColumnNameValue
+	versionField := model.GetVersionField()
 	for _, r := range columnNameValues {
-		if r.Name() == "Cas" {
+		if versionField != nil && r.Name() == versionField.Name() {
 			casField = r
 		}
 		recordList = append(recordList, r.Column()+" = Excluded."+r.Column())
@@ -376,7 +457,7 @@ func (dia PostgreSqlDialect) SaveExec(model *Model, columnNameValues []ColumnNam
 	))
 
 	if casField != nil {
-		exec = exec.Append(fmt.Sprintf(" WHERE %s.%s = ?", model.Name, casField.Column()), casField.Value().Int()-1)
+		exec = exec.Append(fmt.Sprintf(" WHERE %s.%s = ?", model.Name, casField.Column()), versionValue(casField.Value())-1)
 	}
 	// save only process has id data
 	//if len(model.GetPrimary()) == 1 && model.GetOnePrimary().AutoIncrement() {
@@ -388,13 +469,15 @@ func (dia PostgreSqlDialect) SaveExec(model *Model, columnNameValues []ColumnNam
 func (dia PostgreSqlDialect) AddForeignKey(model, relationModel *Model, ForeignKeyField Field) ExecValue {
 
 	return DefaultExec{fmt.Sprintf(
-		`ALTER TABLE "%s" ADD FOREIGN KEY (%s) REFERENCES "%s"(%s)`,
-		model.Name, ForeignKeyField.Column(),
-		relationModel.Name, relationModel.GetOnePrimary().Column(),
+		`ALTER TABLE "%s" ADD %s`,
+		model.Name, foreignKeyDefinition(ForeignKeyField, `"`+relationModel.Name+`"`, relationModel.GetOnePrimary()),
 	), nil}
 }
 
 func (dia PostgreSqlDialect) DropForeignKey(model *Model, ForeignKeyField Field) ExecValue {
+	if name := ForeignKeyField.Constraint(); name != "" {
+		return QToSExec{DefaultExec{fmt.Sprintf(`ALTER TABLE "%s" DROP CONSTRAINT %s`, model.Name, name), nil}}
+	}
 	return QToSExec{DefaultExec{fmt.Sprintf(
 		`ALTER TABLE "%s" DROP FOREIGN KEY (%s)`, model.Name, ForeignKeyField.Column(),
 	), nil}}
@@ -418,21 +501,216 @@ func (dia PostgreSqlDialect) TemplateExec(tExec BasicExec, execs map[string]Basi
 }
 
 func (dia PostgreSqlDialect) JoinExec(mainSwap *JoinSwap) ExecValue {
-	var strList []string
+	names := make([]string, 0, len(mainSwap.JoinMap))
 	for name := range mainSwap.JoinMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var exec ExecValue = QToSExec{DefaultExec{"", nil}}
+	for i, name := range names {
 		join := mainSwap.JoinMap[name]
 		swap := mainSwap.SwapMap[name]
-		strList = append(strList, fmt.Sprintf(`JOIN "%s" as "%s" on %s.%s = %s.%s`,
-			join.SubModel.Name,
+		if i != 0 {
+			exec = exec.Append(" ")
+		}
+		exec = exec.Append(fmt.Sprintf(`%s "%s" as "%s" on %s.%s = %s.%s`,
+			join.Type, join.SubModel.Name,
 			swap.Alias,
 			mainSwap.Alias, join.OnMain.Column(),
 			swap.Alias, join.OnSub.Column(),
 		))
+		if len(join.OnSearch) > 0 {
+			onExec := dia.SearchExec(join.OnSearch)
+			exec = exec.Append(" AND "+onExec.Source(), onExec.Args()...)
+		}
 	}
-	var exec ExecValue = QToSExec{DefaultExec{strings.Join(strList, " "), nil}}
-	for _, subSwap := range mainSwap.SwapMap {
-		subExec := dia.JoinExec(subSwap)
+	for _, name := range names {
+		subExec := dia.JoinExec(mainSwap.SwapMap[name])
 		exec = exec.Append(" "+subExec.Source(), subExec.Args()...)
 	}
 	return exec
 }
+
+// oid of the model table in current schema
+const postgresTableOid = "(SELECT c.oid FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace " +
+	"WHERE c.relname = ? AND n.nspname = current_schema())"
+
+func (dia PostgreSqlDialect) TableColumnsExec(model *Model) ExecValue {
+	return QToSExec{DefaultExec{
+		"SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, pg_get_expr(d.adbin, d.adrelid) " +
+			"FROM pg_catalog.pg_attribute a LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum " +
+			"WHERE a.attrelid = " + postgresTableOid + " AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum",
+		[]interface{}{model.Name},
+	}}
+}
+
+// the index of primary key and constraint not included
+func (dia PostgreSqlDialect) TableIndexesExec(model *Model) ExecValue {
+	return QToSExec{DefaultExec{
+		"SELECT i.relname, ix.indisunique, a.attname FROM pg_catalog.pg_index ix " +
+			"JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid " +
+			"JOIN pg_catalog.pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ANY(ix.indkey) " +
+			"WHERE ix.indrelid = " + postgresTableOid + " AND NOT ix.indisprimary " +
+			"AND NOT EXISTS (SELECT 1 FROM pg_catalog.pg_constraint con WHERE con.conindid = ix.indexrelid) " +
+			"ORDER BY i.relname, array_position(ix.indkey::int2[], a.attnum)",
+		[]interface{}{model.Name},
+	}}
+}
+
+func (dia PostgreSqlDialect) TableForeignKeysExec(model *Model) ExecValue {
+	return QToSExec{DefaultExec{
+		"SELECT con.conname, a.attname, fc.relname, fa.attname FROM pg_catalog.pg_constraint con " +
+			"JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1] " +
+			"JOIN pg_catalog.pg_class fc ON fc.oid = con.confrelid " +
+			"JOIN pg_catalog.pg_attribute fa ON fa.attrelid = con.confrelid AND fa.attnum = con.confkey[1] " +
+			"WHERE con.contype = 'f' AND con.conrelid = " + postgresTableOid,
+		[]interface{}{model.Name},
+	}}
+}
+
+func (dia PostgreSqlDialect) AddColumnExec(model *Model, field Field) ExecValue {
+	var s string
+	if field.AutoIncrement() {
+		s = fmt.Sprintf("ADD COLUMN %s SERIAL", field.Column())
+	} else {
+		s = "ADD COLUMN " + columnDefinition(dia, field)
+	}
+	s += columnCheck(field)
+	return QToSExec{DefaultExec{fmt.Sprintf(`ALTER TABLE "%s" %s`, model.Name, s), nil}}
+}
+
+func (dia PostgreSqlDialect) AlterColumnExec(model *Model, field Field) ExecValue {
+	column := field.Column()
+	sqlType := dia.SqlType(field)
+	// the type like enum can't cast implicitly
+	strList := []string{fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", column, sqlType, column, sqlType)}
+	if _, ok := field.Attrs()["not null"]; ok || field.IsPrimary() {
+		strList = append(strList, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", column))
+	} else {
+		strList = append(strList, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", column))
+	}
+	// serial default value is nextval of sequence, don't touch it
+	if field.AutoIncrement() == false {
+		if _default := field.Default(); _default != "" {
+			strList = append(strList, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", column, _default))
+		} else {
+			strList = append(strList, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", column))
+		}
+	}
+	return QToSExec{DefaultExec{fmt.Sprintf(`ALTER TABLE "%s" %s`, model.Name, strings.Join(strList, ", ")), nil}}
+}
+
+func (dia PostgreSqlDialect) CreateIndexExec(model *Model, name string, columns []Column, unique bool) ExecValue {
+	var columnStrList []string
+	for _, c := range columns {
+		columnStrList = append(columnStrList, c.Column())
+	}
+	var indexType string
+	if unique {
+		indexType = "UNIQUE "
+	}
+	return QToSExec{DefaultExec{fmt.Sprintf(`CREATE %sINDEX %s ON "%s"(%s)`, indexType, name, model.Name, strings.Join(columnStrList, ",")), nil}}
+}
+
+func (dia PostgreSqlDialect) DropIndexExec(model *Model, name string) ExecValue {
+	return QToSExec{DefaultExec{"DROP INDEX " + name, nil}}
+}
+
+func (dia PostgreSqlDialect) TransactionalDDL() bool {
+	return true
+}
+
+func (dia PostgreSqlDialect) SqlType(field Field) string {
+	return dialectSqlType("postgres", field, func(kind sqlKind, field Field) string {
+		switch kind {
+		case sqlKindFloat:
+			return "REAL"
+		case sqlKindDouble:
+			return "DOUBLE PRECISION"
+		case sqlKindBytes:
+			return "BYTEA"
+		case sqlKindString:
+			return varcharType(field)
+		case sqlKindTime:
+			return timeSqlType("TIMESTAMP", field)
+		case sqlKindJson:
+			return "JSONB"
+		case sqlKindArray:
+			return postgresArrayType(LoopTypeIndirect(field.StructField().Type).Elem())
+		case sqlKindEnum:
+			return enumTypeName(field)
+		}
+		return ""
+	})
+}
+
+func (dia PostgreSqlDialect) JsonExtract(column, path string, numeric bool) ExecValue {
+	if numeric {
+		return QToSExec{DefaultExec{fmt.Sprintf("(%s #>> ?::text[])::numeric", column), []interface{}{jsonArrayPath(path)}}}
+	}
+	return QToSExec{DefaultExec{fmt.Sprintf("(%s #>> ?::text[])", column), []interface{}{jsonArrayPath(path)}}}
+}
+
+// the enum type name of fields, the field use custom sql type is ignored
+func postgresEnumTypes(dia PostgreSqlDialect, fields []Field) (names []string, values map[string][]string) {
+	values = map[string][]string{}
+	for _, field := range fields {
+		if len(field.Enum()) == 0 {
+			continue
+		}
+		name := enumTypeName(field)
+		if _, ok := values[name]; ok || dia.SqlType(field) != name {
+			continue
+		}
+		names = append(names, name)
+		values[name] = field.Enum()
+	}
+	return
+}
+
+func (dia PostgreSqlDialect) EnumLabelsExec(fields []Field) ExecValue {
+	names, _ := postgresEnumTypes(dia, fields)
+	if len(names) == 0 {
+		return nil
+	}
+	var args []interface{}
+	for _, name := range names {
+		args = append(args, name)
+	}
+	return QToSExec{DefaultExec{
+		"SELECT t.typname, e.enumlabel FROM pg_catalog.pg_type t " +
+			"JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace AND n.nspname = current_schema() " +
+			"JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid " +
+			"WHERE t.typname IN (?" + strings.Repeat(",?", len(names)-1) + ") ORDER BY t.typname, e.enumsortorder",
+		args,
+	}}
+}
+
+// postgres not support CREATE TYPE IF NOT EXISTS, so ignore the duplicate error,
+// the new value of exist type use ALTER TYPE ADD VALUE, it can run in transaction since postgres 12
+func (dia PostgreSqlDialect) EnumTypeExecs(fields []Field, labels map[string][]string) []ExecValue {
+	var execlist []ExecValue
+	names, values := postgresEnumTypes(dia, fields)
+	for _, name := range names {
+		exist, ok := labels[name]
+		if ok == false {
+			execlist = append(execlist, QToSExec{DefaultExec{fmt.Sprintf(
+				"DO $$ BEGIN CREATE TYPE %s AS ENUM (%s); EXCEPTION WHEN duplicate_object THEN NULL; END $$",
+				name, quoteEnumValues(values[name]),
+			), nil}})
+			continue
+		}
+		existMap := map[string]bool{}
+		for _, label := range exist {
+			existMap[label] = true
+		}
+		for _, v := range values[name] {
+			if existMap[v] == false {
+				execlist = append(execlist, QToSExec{DefaultExec{fmt.Sprintf(
+					"ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", name, quoteEnumValues([]string{v}),
+				), nil}})
+			}
+		}
+	}
+	return execlist
+}
@@ -7,6 +7,7 @@
 package toyorm
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 )
@@ -22,6 +23,59 @@ func (dia Sqlite3Dialect) HasTable(model *Model) ExecValue {
 	}
 }
 
+// sqlite3 LastInsertId is the last id of multiple insert
+func (dia Sqlite3Dialect) InsertValuesExecutor(db Executor, exec ExecValue, n int, debugPrinter func(ExecValue, error)) (sql.Result, []int64, error) {
+	query := exec.Query()
+	result, err := db.Exec(query, exec.Args()...)
+	debugPrinter(exec, err)
+	if err != nil {
+		return result, nil, err
+	}
+	var ids []int64
+	if lastId, e := result.LastInsertId(); e == nil {
+		for i := n - 1; i >= 0; i-- {
+			ids = append(ids, lastId-int64(i))
+		}
+	}
+	return result, ids, nil
+}
+
+// sqlite3 last insert id not change when upsert update the conflict row, so it can't use to get primary key
+type upsertResult struct {
+	sql.Result
+}
+
+func (r upsertResult) LastInsertId() (int64, error) {
+	return 0, ErrLastInsertId{}
+}
+
+// the last insert id is valid only when the row is inserted
+func (r upsertResult) insertId() (int64, error) {
+	return r.Result.LastInsertId()
+}
+
+func (dia Sqlite3Dialect) UpsertExecutor(db Executor, exec ExecValue, debugPrinter func(ExecValue, error)) (sql.Result, error) {
+	query := exec.Query()
+	result, err := db.Exec(query, exec.Args()...)
+	debugPrinter(exec, err)
+	if err != nil {
+		return result, err
+	}
+	return upsertResult{result}, nil
+}
+
+func (dia Sqlite3Dialect) UpsertExec(model *Model, columnValues []ColumnNameValue, conflict []Column, strategies map[string]UpsertStrategy) ExecValue {
+	fieldStr, qStr, args := insertValuesFormat(model, columnValues)
+	var exec ExecValue = DefaultExec{}
+	exec = exec.Append(
+		fmt.Sprintf("INSERT INTO `%s`(%s) VALUES(%s)", model.Name, fieldStr, qStr),
+		args...,
+	)
+	conflictStr, conflictArgs := onConflictFormat(model, columnValues, conflict, strategies)
+	exec = exec.Append(conflictStr, conflictArgs...)
+	return exec
+}
+
 func (dia Sqlite3Dialect) SaveExec(model *Model, columnValues []ColumnNameValue) ExecValue {
 	// optimization column format
 	fieldStr, qStr, args := insertValuesFormat(model, columnValues)
@@ -43,7 +97,7 @@ func (dia Sqlite3Dialect) CreateTable(model *Model, foreign map[string]ForeignKe
 	// use to create foreign definition
 	for _, sqlField := range model.GetSqlFields() {
 
-		s := fmt.Sprintf("%s %s", sqlField.Column(), sqlField.SqlType())
+		s := fmt.Sprintf("%s %s", sqlField.Column(), dia.SqlType(sqlField))
 		if isSinglePrimary && sqlField.Name() == model.GetOnePrimary().Name() {
 			s += " PRIMARY KEY"
 		}
@@ -61,6 +115,7 @@ func (dia Sqlite3Dialect) CreateTable(model *Model, foreign map[string]ForeignKe
 			}
 		}
 
+		s += enumCheck(sqlField) + columnCheck(sqlField)
 		strList = append(strList, s)
 	}
 
@@ -76,7 +131,7 @@ func (dia Sqlite3Dialect) CreateTable(model *Model, foreign map[string]ForeignKe
 	for name, key := range foreign {
 		f := model.GetFieldWithName(name)
 		strList = append(strList,
-			fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", f.Column(), key.Model.Name, key.Field.Column()),
+			foreignKeyDefinition(f, key.Model.Name, key.Field),
 		)
 	}
 
@@ -111,3 +166,65 @@ func (dia Sqlite3Dialect) CreateTable(model *Model, foreign map[string]ForeignKe
 	}
 	return
 }
+
+func (dia Sqlite3Dialect) TableColumnsExec(model *Model) ExecValue {
+	return DefaultExec{
+		`SELECT name, type, "notnull" = 0, dflt_value FROM pragma_table_info(?) ORDER BY cid`,
+		[]interface{}{model.Name},
+	}
+}
+
+// only the index created by CREATE INDEX, the auto index of primary key and unique constraint can't drop
+func (dia Sqlite3Dialect) TableIndexesExec(model *Model) ExecValue {
+	return DefaultExec{
+		`SELECT il.name, il."unique", ii.name FROM pragma_index_list(?) AS il, pragma_index_info(il.name) AS ii ` +
+			`WHERE il.origin = 'c' ORDER BY il.name, ii.seqno`,
+		[]interface{}{model.Name},
+	}
+}
+
+func (dia Sqlite3Dialect) TableForeignKeysExec(model *Model) ExecValue {
+	return DefaultExec{
+		`SELECT id, "from", "table", "to" FROM pragma_foreign_key_list(?)`,
+		[]interface{}{model.Name},
+	}
+}
+
+func (dia Sqlite3Dialect) AddColumnExec(model *Model, field Field) ExecValue {
+	return DefaultExec{fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN %s", model.Name, columnDefinition(dia, field)+enumCheck(field)+columnCheck(field)), nil}
+}
+
+// sqlite3 not support modify column, and column type only used to determine affinity
+func (dia Sqlite3Dialect) AlterColumnExec(model *Model, field Field) ExecValue {
+	return nil
+}
+
+func (dia Sqlite3Dialect) DropIndexExec(model *Model, name string) ExecValue {
+	return DefaultExec{"DROP INDEX " + name, nil}
+}
+
+// sqlite3 can't add foreign key to an existing table
+func (dia Sqlite3Dialect) AddForeignKey(model, relationModel *Model, ForeignKeyField Field) ExecValue {
+	return nil
+}
+
+func (dia Sqlite3Dialect) TransactionalDDL() bool {
+	return true
+}
+
+func (dia Sqlite3Dialect) SqlType(field Field) string {
+	return dialectSqlType("sqlite3", field, func(kind sqlKind, field Field) string {
+		switch kind {
+		case sqlKindBytes:
+			return "BLOB"
+		case sqlKindString:
+			return varcharType(field)
+		}
+		return ""
+	})
+}
+
+// need json1 extension, the json_extract return sql type of json value
+func (dia Sqlite3Dialect) JsonExtract(column, path string, numeric bool) ExecValue {
+	return DefaultExec{fmt.Sprintf("json_extract(%s, ?)", column), []interface{}{jsonDollarPath(path)}}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// string type implement it will be an enum column, postgres create enum type with it,
+// other database use a check constraint, e.g
+//
+//	type Status string
+//
+//	func (Status) EnumValues() []string { return []string{"active", "disabled"} }
+type Enumer interface {
+	EnumValues() []string
+}
+
+var enumerType = reflect.TypeOf((*Enumer)(nil)).Elem()
+
+// the enum values of string type, nil if it's not enum
+func enumValues(_type reflect.Type) []string {
+	_type = LoopTypeIndirect(_type)
+	if _type.Kind() != reflect.String {
+		return nil
+	}
+	if _type.Implements(enumerType) {
+		return reflect.Zero(_type).Interface().(Enumer).EnumValues()
+	}
+	if reflect.PtrTo(_type).Implements(enumerType) {
+		return reflect.New(_type).Interface().(Enumer).EnumValues()
+	}
+	return nil
+}
+
+var enumPackageNameRe = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// the postgres enum type name with package name, the same name type in different package will not share one enum type,
+// e.g models.OrderStatus => models_order_status
+func enumTypeName(field Field) string {
+	_type := LoopTypeIndirect(field.StructField().Type)
+	pkgPath := _type.PkgPath()
+	pkg := enumPackageNameRe.ReplaceAllString(pkgPath[strings.LastIndex(pkgPath, "/")+1:], "_")
+	return SqlNameConvert(pkg) + "_" + SqlNameConvert(_type.Name())
+}
+
+func quoteEnumValues(values []string) string {
+	list := make([]string, len(values))
+	for i, v := range values {
+		list[i] = "'" + strings.Replace(v, "'", "''", -1) + "'"
+	}
+	return strings.Join(list, ",")
+}
+
+// the check constraint of enum field in column definition, e.g CHECK (status IN ('active','disabled'))
+func enumCheck(field Field) string {
+	if values := field.Enum(); len(values) != 0 {
+		return fmt.Sprintf(" CHECK (%s IN (%s))", field.Column(), quoteEnumValues(values))
+	}
+	return ""
+}
@@ -15,6 +15,12 @@ var (
 	ErrInvalidTag        = errors.New("invalid tag")
 	ErrInvalidSearchTree = errors.New("invalid search tree")
 	ErrNotMatchDialect   = errors.New("not match dialect")
+	ErrInvalidSubQuery   = errors.New("invalid sub query expr")
+	ErrInvalidJsonPath   = errors.New("invalid json path condition, the first arg must be path string")
+	// the mysql and postgres transaction has only one connection, it can't run the preload query when cursor rows is open
+	ErrCursorPreloadInTx = errors.New("cursor with preload not support in transaction")
+	// the conflict update of upsert can't check and push the version, use Save/Update for optimistic lock
+	ErrUpsertVersion = errors.New("upsert not support the model with version field")
 )
 
 type ErrInvalidModelType string
@@ -102,6 +108,26 @@ func (e ErrCollectionDBSelectorNotFound) Error() string {
 	return "db selector not found"
 }
 
+type ErrCollectionTx map[int]error
+
+func (e ErrCollectionTx) Error() string {
+	var s string
+	for k, v := range e {
+		s += fmt.Sprintf("[%d] %s;", k, v)
+	}
+	return s
+}
+
+// the rollback of Transaction failure, the transaction or save point may be still open
+type ErrTransactionRollback struct {
+	Err         error // the error return by fn or the panic value
+	RollbackErr error
+}
+
+func (e ErrTransactionRollback) Error() string {
+	return fmt.Sprintf("%s, rollback failure: %s", e.Err, e.RollbackErr)
+}
+
 type ErrCollectionClose map[int]error
 
 func (e ErrCollectionClose) Error() string {
@@ -162,6 +188,25 @@ func (e ErrModelDuplicateAssociation) Error() string {
 	return fmt.Sprintf("model %s have duplicate %s in field %s tag", e.Model, e.Type, e.Name)
 }
 
+type ErrModelDuplicateConvention struct {
+	Model string
+	Type  Convention
+	Name  string
+}
+
+func (e ErrModelDuplicateConvention) Error() string {
+	return fmt.Sprintf("model %s have duplicate %s in field %s tag", e.Model, e.Type, e.Name)
+}
+
+type ErrInvalidConflictFields struct {
+	Model  string
+	Fields []string
+}
+
+func (e ErrInvalidConflictFields) Error() string {
+	return fmt.Sprintf("model %s conflict fields %v is not primary key or unique index", e.Model, e.Fields)
+}
+
 type ErrSaveFailure struct{}
 
 func (e ErrSaveFailure) Error() string {
@@ -173,3 +218,84 @@ type ErrNilPrimaryKey struct{}
 func (e ErrNilPrimaryKey) Error() string {
 	return fmt.Sprintf("this record has zero primary key")
 }
+
+type ErrRepeatMigrationVersion struct {
+	Version int64
+}
+
+func (e ErrRepeatMigrationVersion) Error() string {
+	return fmt.Sprintf("repeat migration version %d", e.Version)
+}
+
+type ErrMigrationVersionNotFound struct {
+	Version int64
+}
+
+func (e ErrMigrationVersionNotFound) Error() string {
+	return fmt.Sprintf("migration version %d not found", e.Version)
+}
+
+type ErrMigrationNoDown struct {
+	Version int64
+	Name    string
+}
+
+func (e ErrMigrationNoDown) Error() string {
+	return fmt.Sprintf("migration %d_%s can't down", e.Version, e.Name)
+}
+
+type ErrCollectionMigrate map[int]error
+
+func (e ErrCollectionMigrate) Error() string {
+	var s string
+	for k, v := range e {
+		s += fmt.Sprintf("[%d] %s;", k, v)
+	}
+	return s
+}
+
+type ErrNullableScan struct {
+	SrcType  string
+	DestType string
+}
+
+func (e ErrNullableScan) Error() string {
+	return fmt.Sprintf("cannot scan %s into outer join field type %s", e.SrcType, e.DestType)
+}
+
+// the write with version condition affect nothing, the record have been modified or deleted by others
+type ErrOptimisticLockConflict struct {
+	Model      string
+	PrimaryKey []interface{}
+}
+
+func (e ErrOptimisticLockConflict) Error() string {
+	return fmt.Sprintf("optimistic lock conflict, model %s primary key %v have been modified", e.Model, e.PrimaryKey)
+}
+
+// the Seek values size must be same as order by fields
+type ErrSeekValues struct {
+	OrderBy int
+	Values  int
+}
+
+func (e ErrSeekValues) Error() string {
+	return fmt.Sprintf("seek need %d values of order by fields but got %d", e.OrderBy, e.Values)
+}
+
+// the search expr not support in this database
+type ErrNotSupportExpr SearchExpr
+
+func (e ErrNotSupportExpr) Error() string {
+	return fmt.Sprintf("search expr %s is not supported in this database", string(e))
+}
+
+// the handler name not found in operation handler chain
+type ErrHandlerNotFound struct {
+	Operation Operation
+	Name      string
+}
+
+func (e ErrHandlerNotFound) Error() string {
+	return fmt.Sprintf("handler %s not found in %s handler chain", e.Name, e.Operation)
+}
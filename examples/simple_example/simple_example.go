@@ -282,7 +282,10 @@ func main() {
 	// delete with element
 	{
 		// make a transaction, because I do not really delete a data
-		brick := brick.Begin()
+		brick, err := brick.Begin()
+		if err != nil {
+			panic(err)
+		}
 		var product Product
 		result, err := brick.Where(toyorm.ExprEqual, Offsetof(Product{}.Name), "food four").Find(&product)
 		if err != nil {
@@ -311,7 +314,10 @@ func main() {
 	// delete with condition
 	{
 		// make a transaction, because I am not really delete a data
-		brick := brick.Begin()
+		brick, err := brick.Begin()
+		if err != nil {
+			panic(err)
+		}
 		result, err := brick.Where(toyorm.ExprEqual, Offsetof(Product{}.Name), "food four").DeleteWithConditions()
 		if err != nil {
 			panic(err)
@@ -7,6 +7,7 @@
 package toyorm
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
@@ -157,83 +158,122 @@ func HandlerPreloadInsertOrSave(option string) func(*Context) error {
 }
 
 func HandlerInsertTimeGenerate(ctx *Context) error {
-	now := reflect.ValueOf(time.Now())
+	now := time.Now()
 	records := ctx.Result.Records.GetRecords()
-	if createAtField := ctx.Brick.Model.GetFieldWithName("CreatedAt"); createAtField != nil {
+	if createAtField := ctx.Brick.Model.GetCreatedAtField(); createAtField != nil {
 		for _, record := range records {
 			if fieldValue := record.Field(createAtField.Name()); fieldValue.IsValid() == false || IsZero(fieldValue) {
-				record.SetField(createAtField.Name(), now)
+				record.SetField(createAtField.Name(), timeFieldValue(createAtField, now))
 			}
 		}
 	}
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
 		for _, record := range records {
-			record.SetField(updateField.Name(), now)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, now))
 		}
 	}
 	return nil
 }
 
 func HandlerInsert(ctx *Context) error {
-
-	// current insert
-	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
-	for i, record := range ctx.Result.Records.GetRecords() {
-		action := ExecAction{affectData: []int{i}}
+	records := ctx.Result.Records.GetRecords()
+	var batches [][]int
+	// template exec only can insert records one by one
+	if ctx.Brick.template == nil && ctx.Brick.getBatchSize() > 1 {
+		batches = ctx.Brick.insertBatches(records)
+	} else {
+		for i := range records {
+			batches = append(batches, []int{i})
+		}
+	}
+	for _, batch := range batches {
 		var err error
-		if ctx.Brick.template == nil {
-			action.Exec = ctx.Brick.InsertExec(record)
+		if len(batch) == 1 {
+			err = handlerInsertOne(ctx, batch[0], records[batch[0]])
 		} else {
-			tempMap := DefaultTemplateExec(ctx.Brick)
-			values := ctx.Brick.getFieldValuePairWithRecord(ModeInsert, record)
-			columnsExec, valExec := getInsertColumnExecAndValue(values)
-			tempMap["Columns"] = columnsExec
-			tempMap["Values"] = valExec
-			action.Exec, err = ctx.Brick.Toy.Dialect.TemplateExec(*ctx.Brick.template, tempMap)
-			if err != nil {
-				return err
-			}
+			err = handlerInsertBatch(ctx, batch, records)
 		}
-		var executor Executor
-		if ctx.Brick.tx != nil {
-			executor = ctx.Brick.tx
-		} else {
-			executor = ctx.Brick.Toy.db
+		if err != nil {
+			return err
 		}
-		action.Result, action.Error = ctx.Brick.Toy.Dialect.InsertExecutor(
-			executor,
-			action.Exec,
-			ctx.Brick.debugPrint,
-		)
-		if action.Error == nil {
-			// set primary field value if model has one primary key
-			if len(ctx.Brick.Model.GetPrimary()) == 1 {
-				primaryKey := ctx.Brick.Model.GetOnePrimary()
-				primaryKeyName := primaryKey.Name()
-				if IntKind(primaryKey.StructField().Type.Kind()) {
-					// just set not zero primary key
-					if fieldValue := record.Field(primaryKeyName); !fieldValue.IsValid() || IsZero(fieldValue) {
-						if lastId, err := action.Result.LastInsertId(); err == nil {
-							ctx.Result.Records.GetRecord(i).SetField(primaryKeyName, reflect.ValueOf(lastId))
-						} else {
-							return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
-						}
+	}
+	return nil
+}
+
+func handlerInsertOne(ctx *Context, i int, record ModelRecord) error {
+	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
+	action := ExecAction{affectData: []int{i}}
+	var err error
+	if ctx.Brick.template == nil {
+		action.Exec = ctx.Brick.InsertExec(record)
+	} else {
+		tempMap := DefaultTemplateExec(ctx.Brick)
+		values := ctx.Brick.getFieldValuePairWithRecord(ModeInsert, record)
+		columnsExec, valExec := getInsertColumnExecAndValue(values)
+		tempMap["Columns"] = columnsExec
+		tempMap["Values"] = valExec
+		action.Exec, err = ctx.Brick.Toy.Dialect.TemplateExec(*ctx.Brick.template, tempMap)
+		if err != nil {
+			return err
+		}
+	}
+	action.Result, action.Error = ctx.Brick.Toy.Dialect.InsertExecutor(
+		ctx.Brick.executor(),
+		action.Exec,
+		ctx.Brick.debugPrint,
+	)
+	if action.Error == nil {
+		// set primary field value if model has one primary key
+		if len(ctx.Brick.Model.GetPrimary()) == 1 {
+			primaryKey := ctx.Brick.Model.GetOnePrimary()
+			primaryKeyName := primaryKey.Name()
+			if IntKind(primaryKey.StructField().Type.Kind()) {
+				// just set not zero primary key
+				if fieldValue := record.Field(primaryKeyName); !fieldValue.IsValid() || IsZero(fieldValue) {
+					if lastId, err := action.Result.LastInsertId(); err == nil {
+						ctx.Result.Records.GetRecord(i).SetField(primaryKeyName, reflect.ValueOf(lastId))
+					} else {
+						return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
 					}
 				}
 			}
+		}
 
+	}
+	ctx.Result.AddRecord(action)
+	return nil
+}
+
+// insert multiple records in one statement, the action affect all records in batch
+func handlerInsertBatch(ctx *Context, batch []int, records []ModelRecord) error {
+	action := ExecAction{affectData: batch}
+	batchRecords := make([]ModelRecord, len(batch))
+	for j, i := range batch {
+		batchRecords[j] = records[i]
+	}
+	action.Exec = ctx.Brick.InsertValuesExec(batchRecords)
+	var ids []int64
+	action.Result, ids, action.Error = ctx.Brick.Toy.Dialect.InsertValuesExecutor(
+		ctx.Brick.executor(),
+		action.Exec,
+		len(batch),
+		ctx.Brick.debugPrint,
+	)
+	if action.Error == nil {
+		if err := setBatchInsertId(ctx.Brick.Model, records, batch, ids); err != nil {
+			return errors.New(fmt.Sprintf("get (%s) auto increment  failure reason(%s)", ctx.Brick.Model.Name, err))
 		}
-		ctx.Result.AddRecord(action)
 	}
+	ctx.Result.AddRecord(action)
 	return nil
 }
 
 func HandlerCasVersionPushOne(ctx *Context) error {
 	records := ctx.Result.Records
-	casField := ctx.Brick.Model.GetFieldWithName("Cas")
+	casField := ctx.Brick.Model.GetVersionField()
 	if casField != nil {
 		for _, record := range records.GetRecords() {
-			record.SetField(casField.Name(), reflect.ValueOf(record.Field("Cas").Int()+1))
+			record.SetField(casField.Name(), reflect.ValueOf(versionValue(record.Field(casField.Name()))+1))
 		}
 	}
 	return nil
@@ -244,16 +284,9 @@ func HandlerFind(ctx *Context) error {
 	var err error
 	columns, scannersGen := FindColumnFactory(ctx.Result.Records, ctx.Brick)
 
-	// use template or use default exec
-	if ctx.Brick.template == nil {
-		action.Exec = ctx.Brick.FindExec(columns)
-	} else {
-		tempMap := DefaultTemplateExec(ctx.Brick)
-		tempMap["Columns"] = getColumnExec(columns)
-		action.Exec, err = ctx.Brick.Toy.Dialect.TemplateExec(*ctx.Brick.template, tempMap)
-		if err != nil {
-			return err
-		}
+	action.Exec, err = ctx.Brick.findOrTemplateExec(columns)
+	if err != nil {
+		return err
 	}
 	rows, err := ctx.Brick.Query(action.Exec)
 	if err != nil {
@@ -543,23 +576,36 @@ func HandlerPreloadFind(ctx *Context) error {
 
 func HandlerUpdateTimeGenerate(ctx *Context) error {
 	records := ctx.Result.Records
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
-		current := reflect.ValueOf(time.Now())
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
+		current := time.Now()
 		for _, record := range records.GetRecords() {
-			record.SetField(updateField.Name(), current)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, current))
 		}
 	}
 	return nil
 }
 
 func HandlerUpdate(ctx *Context) error {
+	versionField := ctx.Brick.Model.GetVersionField()
 	for i, record := range ctx.Result.Records.GetRecords() {
 		action := ExecAction{affectData: []int{i}}
 		var err error
+		brick := ctx.Brick
+		// optimistic lock, the record with version will update with previous version condition
+		versioned := false
+		var version int64
+		if versionField != nil {
+			if fieldValue := record.Field(versionField.Name()); fieldValue.IsValid() && IsZero(fieldValue) == false {
+				version = versionValue(fieldValue)
+				record.SetField(versionField.Name(), reflect.ValueOf(version+1))
+				brick = brick.Where(ExprEqual, versionField, version).And().Conditions(brick.Search)
+				versioned = true
+			}
+		}
 		if ctx.Brick.template == nil {
-			action.Exec = ctx.Brick.UpdateExec(record)
+			action.Exec = brick.UpdateExec(record)
 		} else {
-			tempMap := DefaultTemplateExec(ctx.Brick)
+			tempMap := DefaultTemplateExec(brick)
 			values := ctx.Brick.getFieldValuePairWithRecord(ModeUpdate, record).ToValueList()
 			tempMap["Columns"] = getColumnExec(columnsValueToColumn(values))
 			tempMap["Values"] = getUpdateValuesExec(values)
@@ -570,6 +616,13 @@ func HandlerUpdate(ctx *Context) error {
 		}
 
 		action.Result, action.Error = ctx.Brick.Exec(action.Exec)
+		if versioned && action.Error == nil {
+			action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+		}
+		// the record keep previous version when update failure
+		if versioned && action.Error != nil {
+			record.SetField(versionField.Name(), reflect.ValueOf(version))
+		}
 		ctx.Result.AddRecord(action)
 	}
 	return nil
@@ -579,12 +632,8 @@ func HandlerUpdate(ctx *Context) error {
 // else try to replace
 func HandlerSave(ctx *Context) error {
 	//setInsertId := len(ctx.Brick.Model.GetPrimary()) == 1 && ctx.Brick.Model.GetOnePrimary().AutoIncrement() == true
-	var executor Executor
-	if ctx.Brick.tx != nil {
-		executor = ctx.Brick.tx
-	} else {
-		executor = ctx.Brick.Toy.db
-	}
+	executor := ctx.Brick.executor()
+	versionField := ctx.Brick.Model.GetVersionField()
 	for i, record := range ctx.Result.Records.GetRecords() {
 		var action ExecAction
 		var err error
@@ -622,6 +671,13 @@ func HandlerSave(ctx *Context) error {
 						}
 					}
 				}
+			} else if versionPushed(versionField, record) {
+				// optimistic lock
+				action.Exec = ctx.Brick.VersionSaveExec(record)
+				action.Result, action.Error = ctx.Brick.Exec(action.Exec)
+				if action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			} else {
 				action.Exec = ctx.Brick.SaveExec(record)
 				action.Result, action.Error = ctx.Brick.Toy.Dialect.SaveExecutor(
@@ -629,6 +685,9 @@ func HandlerSave(ctx *Context) error {
 					action.Exec,
 					ctx.Brick.debugPrint,
 				)
+				if versionField != nil && action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			}
 		} else {
 			tempMap := DefaultTemplateExec(ctx.Brick)
@@ -643,12 +702,58 @@ func HandlerSave(ctx *Context) error {
 			}
 		}
 
+		if action.Error != nil {
+			versionRestore(versionField, record)
+		}
+		ctx.Result.AddRecord(action)
+	}
+	return nil
+}
+
+// insert records, update the conflict row with brick upsert strategies
+func HandlerUpsert(ctx *Context) error {
+	executor := ctx.Brick.executor()
+	for i, record := range ctx.Result.Records.GetRecords() {
+		action := ExecAction{affectData: []int{i}}
+		action.Exec = ctx.Brick.UpsertExec(record)
+		action.Result, action.Error = ctx.Brick.Toy.Dialect.UpsertExecutor(
+			executor,
+			action.Exec,
+			ctx.Brick.debugPrint,
+		)
+		if action.Error == nil {
+			// set primary field value if model has one primary key and the dialect can get it
+			if len(ctx.Brick.Model.GetPrimary()) == 1 {
+				primaryKey := ctx.Brick.Model.GetOnePrimary()
+				primaryKeyName := primaryKey.Name()
+				if IntKind(primaryKey.StructField().Type.Kind()) {
+					if fieldValue := record.Field(primaryKeyName); !fieldValue.IsValid() || IsZero(fieldValue) {
+						lastId, err := action.Result.LastInsertId()
+						if r, ok := action.Result.(upsertResult); ok {
+							if len(ctx.Brick.conflict) == 0 {
+								// conflict with zero primary key is impossible, the row must be inserted
+								lastId, err = r.insertId()
+							} else {
+								lastId, err = ctx.Brick.conflictPrimaryKey(record)
+								if err != nil && err != sql.ErrNoRows {
+									action.Error = err
+								}
+							}
+						}
+						if err == nil {
+							record.SetField(primaryKeyName, reflect.ValueOf(lastId))
+						}
+					}
+				}
+			}
+		}
 		ctx.Result.AddRecord(action)
 	}
 	return nil
 }
 
 func HandlerUSave(ctx *Context) error {
+	versionField := ctx.Brick.Model.GetVersionField()
 	notIgnoreBrick := ctx.Brick.IgnoreMode(ModeDefault, IgnoreNo)
 	for i, record := range ctx.Result.Records.GetRecords() {
 		var action ExecAction
@@ -669,13 +774,16 @@ func HandlerUSave(ctx *Context) error {
 			action.Error = ErrNilPrimaryKey{}
 		} else {
 			// cas process
-			if casField := ctx.Brick.Model.GetFieldWithName("Cas"); casField != nil {
-				ConditionKeyVal[casField.Name()] = record.Field(casField.Name()).Int() - 1
+			if versionField != nil {
+				ConditionKeyVal[versionField.Name()] = versionValue(record.Field(versionField.Name())) - 1
 			}
 			brick := notIgnoreBrick.WhereGroup(ExprAnd, ConditionKeyVal)
 			if ctx.Brick.template == nil {
 				action.Exec = brick.UpdateExec(record)
 				action.Result, action.Error = ctx.Brick.Exec(action.Exec)
+				if versionField != nil && action.Error == nil {
+					action.Error = versionConflictCheck(ctx.Brick.Model, record, action.Result)
+				}
 			} else {
 				tempMap := DefaultTemplateExec(brick)
 				values := ctx.Brick.getFieldValuePairWithRecord(ModeSave, record).ToValueList()
@@ -687,34 +795,37 @@ func HandlerUSave(ctx *Context) error {
 			}
 		}
 
+		if action.Error != nil {
+			versionRestore(versionField, record)
+		}
 		ctx.Result.AddRecord(action)
 	}
 	return nil
 }
 
 func HandlerSaveTimeGenerate(ctx *Context) error {
-	now := reflect.ValueOf(time.Now())
+	now := time.Now()
 	records := ctx.Result.Records.GetRecords()
-	if createAtField := ctx.Brick.Model.GetFieldWithName("CreatedAt"); createAtField != nil {
+	if createAtField := ctx.Brick.Model.GetCreatedAtField(); createAtField != nil {
 		for _, record := range records {
 			if fieldValue := record.Field(createAtField.Name()); fieldValue.IsValid() == false || IsZero(fieldValue) {
-				record.SetField(createAtField.Name(), now)
+				record.SetField(createAtField.Name(), timeFieldValue(createAtField, now))
 			}
 		}
 	}
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
 		for _, record := range records {
-			record.SetField(updateField.Name(), now)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, now))
 		}
 	}
 	return nil
 }
 
 func HandlerUSaveTimeGenerate(ctx *Context) error {
-	now := reflect.ValueOf(time.Now())
-	if updateField := ctx.Brick.Model.GetFieldWithName("UpdatedAt"); updateField != nil {
+	now := time.Now()
+	if updateField := ctx.Brick.Model.GetUpdatedAtField(); updateField != nil {
 		for _, record := range ctx.Result.Records.GetRecords() {
-			record.SetField(updateField.Name(), now)
+			record.SetField(updateField.Name(), timeFieldValue(updateField, now))
 		}
 	}
 	return nil
@@ -723,7 +834,7 @@ func HandlerUSaveTimeGenerate(ctx *Context) error {
 // preload schedule belongTo -> Next() -> oneToOne -> oneToMany -> manyToMany(sub -> middle)
 func HandlerCreateTablePreload(option string) func(ctx *Context) error {
 	return func(ctx *Context) (err error) {
-		for fieldName := range ctx.Brick.BelongToPreload {
+		for _, fieldName := range sortedMapKeys(ctx.Brick.BelongToPreload) {
 			brick := ctx.Brick.MapPreloadBrick[fieldName]
 			subCtx := brick.GetContext(option, MakeRecordsWithElem(brick.Model, brick.Model.ReflectType))
 			ctx.Result.Preload[fieldName] = subCtx.Result
@@ -735,7 +846,7 @@ func HandlerCreateTablePreload(option string) func(ctx *Context) error {
 		if err != nil {
 			return err
 		}
-		for fieldName := range ctx.Brick.OneToOnePreload {
+		for _, fieldName := range sortedMapKeys(ctx.Brick.OneToOnePreload) {
 			brick := ctx.Brick.MapPreloadBrick[fieldName]
 			subCtx := brick.GetContext(option, MakeRecordsWithElem(brick.Model, brick.Model.ReflectType))
 			ctx.Result.Preload[fieldName] = subCtx.Result
@@ -744,7 +855,7 @@ func HandlerCreateTablePreload(option string) func(ctx *Context) error {
 			}
 		}
 
-		for fieldName := range ctx.Brick.OneToManyPreload {
+		for _, fieldName := range sortedMapKeys(ctx.Brick.OneToManyPreload) {
 			brick := ctx.Brick.MapPreloadBrick[fieldName]
 			subCtx := brick.GetContext(option, MakeRecordsWithElem(brick.Model, brick.Model.ReflectType))
 			ctx.Result.Preload[fieldName] = subCtx.Result
@@ -752,7 +863,8 @@ func HandlerCreateTablePreload(option string) func(ctx *Context) error {
 				return err
 			}
 		}
-		for fieldName, preload := range ctx.Brick.ManyToManyPreload {
+		for _, fieldName := range sortedMapKeys(ctx.Brick.ManyToManyPreload) {
+			preload := ctx.Brick.ManyToManyPreload[fieldName]
 			{
 				brick := ctx.Brick.MapPreloadBrick[fieldName]
 				subCtx := brick.GetContext(option, MakeRecordsWithElem(brick.Model, brick.Model.ReflectType))
@@ -844,13 +956,14 @@ func HandlerDropTablePreload(option string) func(ctx *Context) error {
 	}
 }
 
-func HandlerCreateTable(ctx *Context) error {
+// get the foreign key of the model from the relationship with parent or belong to preload
+func getForeignKeys(brick *ToyBrick) map[string]ForeignKey {
 	foreign := map[string]ForeignKey{}
-	for _, field := range ctx.Brick.Model.GetSqlFields() {
+	for _, field := range brick.Model.GetSqlFields() {
 		// this is foreign key, mean it must relationship field with parent or child
 		if field.IsForeign() {
-			if ctx.Brick.preBrick.Parent != nil {
-				parent, containerField := ctx.Brick.preBrick.Parent, ctx.Brick.preBrick.Field
+			if brick.preBrick.Parent != nil {
+				parent, containerField := brick.preBrick.Parent, brick.preBrick.Field
 				if preload := parent.OneToOnePreload[containerField.Name()]; preload != nil {
 					if preload.RelationField.Name() == field.Name() {
 						foreign[field.Name()] = ForeignKey{preload.Model, preload.Model.GetOnePrimary()}
@@ -868,14 +981,18 @@ func HandlerCreateTable(ctx *Context) error {
 				}
 			}
 			// search belong to
-			for _, preload := range ctx.Brick.BelongToPreload {
+			for _, preload := range brick.BelongToPreload {
 				if preload.RelationField.Name() == field.Name() {
 					foreign[field.Name()] = ForeignKey{preload.SubModel, preload.SubModel.GetOnePrimary()}
 				}
 			}
 		}
 	}
+	return foreign
+}
 
+func HandlerCreateTable(ctx *Context) error {
+	foreign := getForeignKeys(ctx.Brick)
 	execs := ctx.Brick.Toy.Dialect.CreateTable(ctx.Brick.Model, foreign)
 	for _, exec := range execs {
 		action := ExecAction{Exec: exec}
@@ -885,6 +1002,32 @@ func HandlerCreateTable(ctx *Context) error {
 	return nil
 }
 
+// diff model with the live table and exec the alter statements
+func HandlerMigrate(ctx *Context) error {
+	execs, err := migrateExecs(ctx.Brick)
+	if err != nil {
+		return err
+	}
+	for _, exec := range execs {
+		action := ExecAction{Exec: exec}
+		action.Result, action.Error = ctx.Brick.Exec(exec)
+		ctx.Result.AddRecord(action)
+	}
+	return nil
+}
+
+// only record the migrate execs, don't exec it
+func HandlerMigratePlan(ctx *Context) error {
+	execs, err := migrateExecs(ctx.Brick)
+	if err != nil {
+		return err
+	}
+	for _, exec := range execs {
+		ctx.Result.AddRecord(ExecAction{Exec: exec})
+	}
+	return nil
+}
+
 func HandlerExistTableAbort(ctx *Context) error {
 	action := QueryAction{}
 	action.Exec = ctx.Brick.Toy.Dialect.HasTable(ctx.Brick.Model)
@@ -928,8 +1071,8 @@ func HandlerPreloadDelete(ctx *Context) error {
 	for fieldName, preload := range ctx.Brick.OneToOnePreload {
 		preloadBrick := ctx.Brick.MapPreloadBrick[fieldName]
 		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		// set sub model relation field
 		for _, record := range ctx.Result.Records.GetRecords() {
 			// it means relation field, result[j].LastInsertId() is id value
@@ -937,7 +1080,7 @@ func HandlerPreloadDelete(ctx *Context) error {
 		}
 		// if main model is hard delete need set relationship field set zero if sub model is soft delete
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 		result, err := preloadBrick.deleteWithPrimaryKey(subRecords)
@@ -950,8 +1093,8 @@ func HandlerPreloadDelete(ctx *Context) error {
 	// one to many
 	for fieldName, preload := range ctx.Brick.OneToManyPreload {
 		preloadBrick := ctx.Brick.MapPreloadBrick[fieldName]
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
 		subRecords := MakeRecordsWithElem(preload.SubModel, elemAddressType)
 		for _, record := range ctx.Result.Records.GetRecords() {
@@ -962,7 +1105,7 @@ func HandlerPreloadDelete(ctx *Context) error {
 		}
 		// model relationship field set zero
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 		result, err := preloadBrick.deleteWithPrimaryKey(subRecords)
@@ -976,8 +1119,8 @@ func HandlerPreloadDelete(ctx *Context) error {
 		subBrick := ctx.Brick.MapPreloadBrick[fieldName]
 		middleBrick := NewToyBrick(ctx.Brick.Toy, preload.MiddleModel).CopyStatus(ctx.Brick)
 		mainField, subField := preload.Model.GetOnePrimary(), preload.SubModel.GetOnePrimary()
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := subBrick.softDeleteMode()
 
 		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
 		subRecords := MakeRecordsWithElem(preload.SubModel, elemAddressType)
@@ -1062,10 +1205,10 @@ func HandlerPreloadDelete(ctx *Context) error {
 			subRecords.Add(record.FieldAddress(fieldName))
 		}
 
-		mainSoftDelete := preload.Model.GetFieldWithName("DeletedAt") != nil
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		mainSoftDelete := ctx.Brick.softDeleteMode()
+		subSoftDelete := preloadBrick.softDeleteMode()
 		if mainSoftDelete == false && subSoftDelete == true {
-			deletedAtField := preloadBrick.Model.GetFieldWithName("DeletedAt")
+			deletedAtField := preloadBrick.Model.GetSoftDeleteField()
 			preloadBrick = preloadBrick.bindDefaultFields(preload.RelationField, deletedAtField)
 		}
 
@@ -1103,22 +1246,19 @@ func HandlerHardDelete(ctx *Context) error {
 
 //
 func HandlerSoftDeleteCheck(ctx *Context) error {
-	deletedField := ctx.Brick.Model.GetFieldWithName("DeletedAt")
-	if deletedField != nil {
-		ctx.Brick = ctx.Brick.Where(ExprNull, deletedField).And().Conditions(ctx.Brick.Search)
-	}
+	ctx.Brick = ctx.Brick.softDeleteScope()
 	return nil
 }
 
 func HandlerSoftDelete(ctx *Context) error {
 	action := ExecAction{}
-	now := time.Now()
+	deletedField := ctx.Brick.Model.GetSoftDeleteField()
 	value := reflect.New(ctx.Brick.Model.ReflectType).Elem()
 	record := NewStructRecord(ctx.Brick.Model, value)
-	record.SetField("DeletedAt", reflect.ValueOf(now))
-	bindFields := []interface{}{"DeletedAt"}
+	record.SetField(deletedField.Name(), softDeletedValue(deletedField, time.Now()))
+	bindFields := []interface{}{deletedField.Name()}
 	for _, preload := range ctx.Brick.BelongToPreload {
-		subSoftDelete := preload.SubModel.GetFieldWithName("DeletedAt") != nil
+		subSoftDelete := preload.SubModel.GetSoftDeleteField() != nil
 		if subSoftDelete == false {
 			rField := preload.RelationField
 			bindFields = append(bindFields, rField.Name())
@@ -1131,3 +1271,189 @@ func HandlerSoftDelete(ctx *Context) error {
 	ctx.Result.AddRecord(action)
 	return nil
 }
+
+// restore the preload data which HandlerPreloadDelete deleted
+func HandlerPreloadRestore(ctx *Context) error {
+	for fieldName, preload := range ctx.Brick.OneToOnePreload {
+		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
+		for _, record := range ctx.Result.Records.GetRecords() {
+			subRecords.Add(record.FieldAddress(fieldName))
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	for fieldName, preload := range ctx.Brick.BelongToPreload {
+		subRecords := MakeRecordsWithElem(preload.SubModel, ctx.Result.Records.GetFieldAddressType(fieldName))
+		for _, record := range ctx.Result.Records.GetRecords() {
+			subRecords.Add(record.FieldAddress(fieldName))
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	// one to many and many to many restore sub data, the middle data is not soft delete
+	sliceFields := map[string]*Model{}
+	for fieldName, preload := range ctx.Brick.OneToManyPreload {
+		sliceFields[fieldName] = preload.SubModel
+	}
+	for fieldName, preload := range ctx.Brick.ManyToManyPreload {
+		sliceFields[fieldName] = preload.SubModel
+	}
+	for fieldName, subModel := range sliceFields {
+		elemAddressType := reflect.PtrTo(LoopTypeIndirect(ctx.Result.Records.GetFieldType(fieldName)).Elem())
+		subRecords := MakeRecordsWithElem(subModel, elemAddressType)
+		for _, record := range ctx.Result.Records.GetRecords() {
+			rField := LoopIndirect(record.Field(fieldName))
+			for subi := 0; subi < rField.Len(); subi++ {
+				subRecords.Add(rField.Index(subi).Addr())
+			}
+		}
+		result, err := ctx.Brick.MapPreloadBrick[fieldName].restoreWithPrimaryKey(subRecords)
+		ctx.Result.Preload[fieldName] = result
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clear the soft delete field, the model without soft delete field do nothing
+func HandlerRestore(ctx *Context) error {
+	deletedField := ctx.Brick.Model.GetSoftDeleteField()
+	if deletedField == nil {
+		return nil
+	}
+	zero := reflect.Zero(deletedField.StructField().Type)
+	for _, record := range ctx.Result.Records.GetRecords() {
+		record.SetField(deletedField.Name(), zero)
+	}
+	action := ExecAction{}
+	record := NewStructRecord(ctx.Brick.Model, reflect.New(ctx.Brick.Model.ReflectType).Elem())
+	ctx.Brick = ctx.Brick.BindFields(ModeUpdate, deletedField.Name())
+	action.Exec = ctx.Brick.UpdateExec(record)
+	action.Result, action.Error = ctx.Brick.Exec(action.Exec)
+	ctx.Result.AddRecord(action)
+	return nil
+}
+
+// use the cached records when brick use cache, otherwise cache the Find result after it
+func HandlerCacheFind(ctx *Context) error {
+	if ctx.Brick.useCache() == false {
+		return nil
+	}
+	columns, _ := FindColumnFactory(ctx.Result.Records, ctx.Brick)
+	exec, err := ctx.Brick.findOrTemplateExec(columns)
+	if err != nil {
+		return err
+	}
+	key := ctx.Brick.cacheKey("Find", ctx.Result.Records.ElemType(), exec)
+	if value, ok := ctx.Brick.Toy.cache.get(key); ok {
+		cached := value.(reflect.Value)
+		for i := 0; i < cached.Len(); i++ {
+			ctx.Result.Records.Add(cacheCopy(cached.Index(i)))
+		}
+		ctx.Abort()
+		return nil
+	}
+	min := ctx.Result.Records.Len()
+	versions := ctx.Brick.Toy.cache.snapshot(ctx.Brick.cacheModels(true))
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() == nil {
+		source := ctx.Result.Records.Source()
+		cached := cacheCopy(source.Slice(min, source.Len()))
+		ctx.Brick.Toy.cache.set(versions, key, cached, ctx.Brick.cacheTTL)
+	}
+	return nil
+}
+
+// invalidate the cached query of model after write, the write in transaction will invalidate after commit
+func HandlerCacheInvalidate(ctx *Context) error {
+	if ctx.Brick.Toy.cache == nil {
+		return nil
+	}
+	err := ctx.Next()
+	if ctx.Brick.tx != nil {
+		ctx.Brick.txWrites.add(ctx.Brick.Model)
+	} else {
+		ctx.Brick.Toy.cache.invalidate(ctx.Brick.Model)
+	}
+	return err
+}
+
+func HandlerBeforeInsertHook(ctx *Context) error {
+	return runRecordsHook(ctx.Result, "BeforeInsert", callBeforeInsert)
+}
+
+// call AfterInsert of records after the rest handlers success
+func HandlerAfterInsertHook(ctx *Context) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterInsert", callAfterInsert)
+}
+
+func HandlerBeforeSaveHook(ctx *Context) error {
+	return runRecordsHook(ctx.Result, "BeforeSave", callBeforeSave)
+}
+
+// call AfterSave of records after the rest handlers success
+func HandlerAfterSaveHook(ctx *Context) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterSave", callAfterSave)
+}
+
+func HandlerBeforeUpdateHook(ctx *Context) error {
+	return runRecordsHook(ctx.Result, "BeforeUpdate", callBeforeUpdate)
+}
+
+// call AfterUpdate of records after the rest handlers success
+func HandlerAfterUpdateHook(ctx *Context) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterUpdate", callAfterUpdate)
+}
+
+func HandlerBeforeDeleteHook(ctx *Context) error {
+	return runRecordsHook(ctx.Result, "BeforeDelete", callBeforeDelete)
+}
+
+// call AfterDelete of records after the rest handlers success
+func HandlerAfterDeleteHook(ctx *Context) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterDelete", callAfterDelete)
+}
+
+// call AfterFind of records after the rest handlers success
+func HandlerAfterFindHook(ctx *Context) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+	if ctx.Result.Err() != nil {
+		return nil
+	}
+	return runRecordsHook(ctx.Result, "AfterFind", callAfterFind)
+}
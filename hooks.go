@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"reflect"
+)
+
+// the model can implement the following hook interfaces, the hook handlers in default handler chains will call them
+// with each record, the hook return error will abort the handler chain and add to Result
+
+type BeforeInserter interface {
+	BeforeInsert() error
+}
+
+type AfterInserter interface {
+	AfterInsert() error
+}
+
+type BeforeSaver interface {
+	BeforeSave() error
+}
+
+type AfterSaver interface {
+	AfterSave() error
+}
+
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+type AfterUpdater interface {
+	AfterUpdate() error
+}
+
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+type AfterDeleter interface {
+	AfterDelete() error
+}
+
+type AfterFinder interface {
+	AfterFind() error
+}
+
+func callBeforeInsert(v interface{}) error {
+	if hook, ok := v.(BeforeInserter); ok {
+		return hook.BeforeInsert()
+	}
+	return nil
+}
+
+func callAfterInsert(v interface{}) error {
+	if hook, ok := v.(AfterInserter); ok {
+		return hook.AfterInsert()
+	}
+	return nil
+}
+
+func callBeforeSave(v interface{}) error {
+	if hook, ok := v.(BeforeSaver); ok {
+		return hook.BeforeSave()
+	}
+	return nil
+}
+
+func callAfterSave(v interface{}) error {
+	if hook, ok := v.(AfterSaver); ok {
+		return hook.AfterSave()
+	}
+	return nil
+}
+
+func callBeforeUpdate(v interface{}) error {
+	if hook, ok := v.(BeforeUpdater); ok {
+		return hook.BeforeUpdate()
+	}
+	return nil
+}
+
+func callAfterUpdate(v interface{}) error {
+	if hook, ok := v.(AfterUpdater); ok {
+		return hook.AfterUpdate()
+	}
+	return nil
+}
+
+func callBeforeDelete(v interface{}) error {
+	if hook, ok := v.(BeforeDeleter); ok {
+		return hook.BeforeDelete()
+	}
+	return nil
+}
+
+func callAfterDelete(v interface{}) error {
+	if hook, ok := v.(AfterDeleter); ok {
+		return hook.AfterDelete()
+	}
+	return nil
+}
+
+func callAfterFind(v interface{}) error {
+	if hook, ok := v.(AfterFinder); ok {
+		return hook.AfterFind()
+	}
+	return nil
+}
+
+// call hook with each record, the hook error will add to result as HookAction
+func runRecordsHook(result *Result, hook string, call func(interface{}) error) error {
+	if result.Records == nil {
+		return nil
+	}
+	for i, record := range result.Records.GetRecords() {
+		source := record.Source()
+		var v interface{}
+		// use pointer to support the pointer receiver method
+		if source.Kind() == reflect.Struct && source.CanAddr() {
+			v = source.Addr().Interface()
+		} else {
+			v = source.Interface()
+		}
+		if err := call(v); err != nil {
+			result.AddRecord(HookAction{Hook: hook, affectData: []int{i}, Error: err})
+			return err
+		}
+	}
+	return nil
+}
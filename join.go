@@ -6,12 +6,48 @@
 
 package toyorm
 
+import (
+	"database/sql"
+	"reflect"
+)
+
+type JoinType string
+
+const (
+	JoinInner JoinType = "JOIN"
+	JoinLeft  JoinType = "LEFT JOIN"
+	JoinRight JoinType = "RIGHT JOIN"
+	// mysql not support full outer join
+	JoinFull JoinType = "FULL OUTER JOIN"
+)
+
+// join type of tag modifier, e.g `toyorm:"join:Detail,left"`
+var tagJoinTypes = map[string]JoinType{
+	"inner": JoinInner,
+	"left":  JoinLeft,
+	"right": JoinRight,
+	"full":  JoinFull,
+}
+
 type Join struct {
 	Model     *Model
 	SubModel  *Model
 	Container Field
 	OnMain    Field
 	OnSub     Field
+	Type      JoinType
+	// extra ON conditions, combine with OnMain = OnSub by AND
+	OnSearch SearchList
+}
+
+// the sub model columns may be NULL when the join is left or full outer join
+func (j *Join) SubNullable() bool {
+	return j.Type == JoinLeft || j.Type == JoinFull
+}
+
+// the main model columns may be NULL when the join is right or full outer join
+func (j *Join) MainNullable() bool {
+	return j.Type == JoinRight || j.Type == JoinFull
 }
 
 // join will replace attribute
@@ -47,3 +83,73 @@ func (m *JoinSwap) Copy() *JoinSwap {
 	newMap := *m
 	return &newMap
 }
+
+// scan NULL to zero value, it use for the columns of outer join which record side maybe missing,
+// missing will call when scan NULL value
+type nullableScanner struct {
+	dest    reflect.Value // the pointer of field
+	missing func()
+}
+
+func (s nullableScanner) Scan(src interface{}) error {
+	elem := s.dest.Elem()
+	if src == nil {
+		elem.Set(reflect.Zero(elem.Type()))
+		if s.missing != nil {
+			s.missing()
+		}
+		return nil
+	}
+	if scanner, ok := s.dest.Interface().(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if elem.Kind() == reflect.Ptr {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		return nullableScanner{elem, nil}.Scan(src)
+	}
+	switch elem.Kind() {
+	case reflect.String:
+		var v sql.NullString
+		if err := v.Scan(src); err != nil {
+			return err
+		}
+		elem.SetString(v.String)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v sql.NullInt64
+		if err := v.Scan(src); err != nil {
+			return err
+		}
+		elem.SetInt(v.Int64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var v sql.NullInt64
+		if err := v.Scan(src); err != nil {
+			return err
+		}
+		elem.SetUint(uint64(v.Int64))
+	case reflect.Float32, reflect.Float64:
+		var v sql.NullFloat64
+		if err := v.Scan(src); err != nil {
+			return err
+		}
+		elem.SetFloat(v.Float64)
+	case reflect.Bool:
+		var v sql.NullBool
+		if err := v.Scan(src); err != nil {
+			return err
+		}
+		elem.SetBool(v.Bool)
+	default:
+		var v sql.NullString
+		srcValue := reflect.ValueOf(src)
+		if srcValue.Type().ConvertibleTo(elem.Type()) {
+			elem.Set(srcValue.Convert(elem.Type()))
+		} else if err := v.Scan(src); err != nil {
+			return err
+		} else if stringValue := reflect.ValueOf(v.String); stringValue.Type().ConvertibleTo(elem.Type()) {
+			elem.Set(stringValue.Convert(elem.Type()))
+		} else {
+			return ErrNullableScan{srcValue.Type().String(), elem.Type().String()}
+		}
+	}
+	return nil
+}
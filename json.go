@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// json path condition, use with Where, the first arg is path and second is value, e.g
+// brick.Where(ExprJsonEqual, Offsetof(User{}.Extra), "address.city", "Paris")
+// brick.Where(ExprJsonGreater, Offsetof(User{}.Extra), "tags.0.score", 10)
+// brick.Where(ExprJsonNull, Offsetof(User{}.Extra), "address")
+const (
+	ExprJsonEqual        SearchExpr = "JSON ="
+	ExprJsonNotEqual     SearchExpr = "JSON <>"
+	ExprJsonGreater      SearchExpr = "JSON >"
+	ExprJsonGreaterEqual SearchExpr = "JSON >="
+	ExprJsonLess         SearchExpr = "JSON <"
+	ExprJsonLessEqual    SearchExpr = "JSON <="
+	ExprJsonLike         SearchExpr = "JSON LIKE"
+	ExprJsonNull         SearchExpr = "JSON NULL"
+	ExprJsonNotNull      SearchExpr = "JSON NOT NULL"
+)
+
+var jsonPathExprs = map[SearchExpr]SearchExpr{
+	ExprJsonEqual:        ExprEqual,
+	ExprJsonNotEqual:     ExprNotEqual,
+	ExprJsonGreater:      ExprGreater,
+	ExprJsonGreaterEqual: ExprGreaterEqual,
+	ExprJsonLess:         ExprLess,
+	ExprJsonLessEqual:    ExprLessEqual,
+	ExprJsonLike:         ExprLike,
+	ExprJsonNull:         ExprNull,
+	ExprJsonNotNull:      ExprNotNull,
+}
+
+// convert json path condition to normal condition with json extract column
+func jsonPathCondition(dia Dialect, expr SearchExpr, field Field, args []interface{}) (SearchExpr, Field, []interface{}) {
+	if len(args) == 0 {
+		panic(ErrInvalidJsonPath)
+	}
+	path, ok := args[0].(string)
+	if ok == false || path == "" {
+		panic(ErrInvalidJsonPath)
+	}
+	args = args[1:]
+	numeric := false
+	if len(args) == 1 {
+		switch reflect.ValueOf(args[0]).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			numeric = true
+		}
+	}
+	return jsonPathExprs[expr], &jsonPathField{field, dia, path, numeric}, args
+}
+
+// the json extract column of json path condition, the path is bind argument of column
+type jsonPathField struct {
+	Field
+	dia     Dialect
+	path    string
+	numeric bool
+}
+
+func (f *jsonPathField) Source() Field { return f.Field }
+
+func (f *jsonPathField) Column() string {
+	return f.dia.JsonExtract(f.Field.Column(), f.path, f.numeric).Source()
+}
+
+func (f *jsonPathField) ColumnArgs() []interface{} {
+	return f.dia.JsonExtract(f.Field.Column(), f.path, f.numeric).Args()
+}
+
+func (f *jsonPathField) ToColumnAlias(alias string) Field {
+	return &jsonPathField{f.Field.ToColumnAlias(alias), f.dia, f.path, f.numeric}
+}
+
+func (f *jsonPathField) ToFieldValue(value reflect.Value) FieldValue {
+	return &jsonPathFieldValue{f, value}
+}
+
+type jsonPathFieldValue struct {
+	*jsonPathField
+	value reflect.Value
+}
+
+func (v *jsonPathFieldValue) Value() reflect.Value {
+	return v.value
+}
+
+// the bind arguments of condition column, it's before the condition value arguments
+func columnArgs(val FieldValue) []interface{} {
+	if v, ok := val.(interface {
+		ColumnArgs() []interface{}
+	}); ok {
+		return v.ColumnArgs()
+	}
+	return nil
+}
+
+var jsonPathKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// convert a.b.0 to $.a.b[0], used by mysql and sqlite
+func jsonDollarPath(path string) string {
+	s := "$"
+	for _, key := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(key); err == nil {
+			s += "[" + key + "]"
+		} else if jsonPathKeyRe.MatchString(key) {
+			s += "." + key
+		} else {
+			s += `."` + jsonPathKeyReplacer.Replace(key) + `"`
+		}
+	}
+	return s
+}
+
+var jsonPathKeyReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// convert a.b.0 to {"a","b","0"}, used by postgres
+func jsonArrayPath(path string) string {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		keys[i] = `"` + jsonPathKeyReplacer.Replace(key) + `"`
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
+
+// json field value, it will marshal to json string when write to database
+type jsonValue struct {
+	value reflect.Value
+}
+
+func (v jsonValue) Value() (driver.Value, error) {
+	switch v.value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if v.value.IsNil() {
+			return nil, nil
+		}
+	}
+	data, err := json.Marshal(v.value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value.Interface())
+}
+
+var jsonValueType = reflect.TypeOf(jsonValue{})
+
+// the field value of json/array field need to encode
+func newFieldValue(field Field, value reflect.Value) FieldValue {
+	if value.IsValid() {
+		if field.Json() && value.Type() != jsonValueType {
+			value = reflect.ValueOf(jsonValue{value})
+		} else if field.Array() && value.Kind() == reflect.Slice {
+			value = reflect.ValueOf(arrayValue{value})
+		}
+	}
+	return &fieldValue{field, value}
+}
+
+// unmarshal the json column to field, NULL will set zero value
+type jsonScanner struct {
+	dest    reflect.Value // the pointer of field
+	missing func()
+}
+
+func (s jsonScanner) Scan(src interface{}) error {
+	elem := s.dest.Elem()
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		elem.Set(reflect.Zero(elem.Type()))
+		if s.missing != nil {
+			s.missing()
+		}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T to json field %s", src, elem.Type())
+	}
+	elem.Set(reflect.Zero(elem.Type()))
+	return json.Unmarshal(data, s.dest.Interface())
+}
+
+// the scanner of json/array field, return nil if field not need to decode
+func encodedFieldScanner(field Field, dest reflect.Value, missing func()) sql.Scanner {
+	if field.Json() {
+		return jsonScanner{dest: dest, missing: missing}
+	} else if field.Array() {
+		return arrayScanner{dest: dest, missing: missing}
+	}
+	return nil
+}
+
+// the scanner of field, dest is the pointer of field
+func fieldScanner(field Field, dest reflect.Value) interface{} {
+	if scanner := encodedFieldScanner(field, dest, nil); scanner != nil {
+		return scanner
+	}
+	return dest.Interface()
+}
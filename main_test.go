@@ -247,6 +247,60 @@ func (t *TestGroupByTableGroup) TableName() string {
 	return ModelName(reflect.ValueOf(TestGroupByTable{}))
 }
 
+type TestHookTable struct {
+	ID       uint32 `toyorm:"primary key;auto_increment"`
+	Data     string
+	Display  string `toyorm:"-"`
+	Children []TestHookChild
+}
+
+var hookCounter = map[string]int{}
+
+func (t *TestHookTable) BeforeInsert() error {
+	if t.Data == "" {
+		return fmt.Errorf("data is empty")
+	}
+	hookCounter["BeforeInsert"]++
+	return nil
+}
+
+func (t *TestHookTable) AfterInsert() error {
+	hookCounter["AfterInsert"]++
+	return nil
+}
+
+func (t *TestHookTable) BeforeSave() error {
+	hookCounter["BeforeSave"]++
+	return nil
+}
+
+func (t *TestHookTable) BeforeUpdate() error {
+	t.Data += " updated"
+	return nil
+}
+
+func (t *TestHookTable) BeforeDelete() error {
+	hookCounter["BeforeDelete"]++
+	return nil
+}
+
+func (t *TestHookTable) AfterFind() error {
+	t.Display = "display " + t.Data
+	return nil
+}
+
+type TestHookChild struct {
+	ID              uint32 `toyorm:"primary key;auto_increment"`
+	Data            string
+	Display         string `toyorm:"-"`
+	TestHookTableID uint32 `toyorm:"index"`
+}
+
+func (t *TestHookChild) AfterFind() error {
+	t.Display = "child " + t.Data
+	return nil
+}
+
 type TestForeignKeyTable struct {
 	ModelDefault
 	Data     string
@@ -771,6 +825,41 @@ func (t *TestCustomTableNameJoin) TableName() string {
 	return SqlNameConvert(reflect.TypeOf(*t).Name()) + "_" + fmt.Sprint(t.FragNum)
 }
 
+// the old and new version of same table, use to test migrate
+type TestMigrateOldTable struct {
+	ID   uint32 `toyorm:"primary key;auto_increment"`
+	Name string `toyorm:"index:idx_migrate_name"`
+	Age  int32
+}
+
+func (t *TestMigrateOldTable) TableName() string {
+	return "test_migrate_table"
+}
+
+type TestMigrateTable struct {
+	ID    uint32 `toyorm:"primary key;auto_increment"`
+	Name  string `toyorm:"unique index:udx_migrate_name"`
+	Age   int64
+	Email string `toyorm:"index:idx_migrate_email;default:''"`
+}
+
+func (t *TestMigrateTable) TableName() string {
+	return "test_migrate_table"
+}
+
+// enum column type, postgres create toyorm_test_enum_status type
+type TestEnumStatus string
+
+func (TestEnumStatus) EnumValues() []string {
+	return []string{"active", "disabled"}
+}
+
+type TestEnumTable struct {
+	ID     uint32 `toyorm:"primary key;auto_increment"`
+	Name   string
+	Status TestEnumStatus
+}
+
 // use to create many to many preload which have foreign key
 func foreignKeyManyToManyPreload(v interface{}) func(*ToyBrick) *ToyBrick {
 	return func(t *ToyBrick) *ToyBrick {
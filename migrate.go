@@ -0,0 +1,400 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// live table column information
+type tableColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  sql.NullString
+}
+
+type tableIndex struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+type tableForeignKey struct {
+	Name      string
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+type tableSchema struct {
+	Columns     map[string]tableColumn
+	Indexes     map[string]*tableIndex
+	ForeignKeys []tableForeignKey
+}
+
+// query the table columns, indexes and foreign keys of brick model
+func (t *ToyBrick) tableSchema() (*tableSchema, error) {
+	schema := &tableSchema{
+		Columns: map[string]tableColumn{},
+		Indexes: map[string]*tableIndex{},
+	}
+	dia := t.Toy.Dialect
+	rows, err := t.Query(dia.TableColumnsExec(t.Model))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var column tableColumn
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &column.Default); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Columns[column.Name] = column
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = t.Query(dia.TableIndexesExec(t.Model))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name, column string
+		var unique bool
+		if err := rows.Scan(&name, &unique, &column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index := schema.Indexes[name]
+		if index == nil {
+			index = &tableIndex{Name: name, Unique: unique}
+			schema.Indexes[name] = index
+		}
+		index.Columns = append(index.Columns, column)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = t.Query(dia.TableForeignKeysExec(t.Model))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key tableForeignKey
+		if err := rows.Scan(&key.Name, &key.Column, &key.RefTable, &key.RefColumn); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.ForeignKeys = append(schema.ForeignKeys, key)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func closeRows(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	return rows.Close()
+}
+
+var (
+	sqlTypeIntegerWidthRe = regexp.MustCompile(`^(TINYINT|SMALLINT|MEDIUMINT|INT|INTEGER|BIGINT)\(\d+\)`)
+	sqlTypeSpaceRe        = regexp.MustCompile(`\s+`)
+	sqlTypeAlias          = map[string]string{
+		"INT":                         "INTEGER",
+		"INT4":                        "INTEGER",
+		"SERIAL":                      "INTEGER",
+		"INT8":                        "BIGINT",
+		"BIGSERIAL":                   "BIGINT",
+		"INT2":                        "SMALLINT",
+		"BOOL":                        "BOOLEAN",
+		"TINYINT(1)":                  "BOOLEAN",
+		"DOUBLE":                      "FLOAT",
+		"DOUBLE PRECISION":            "FLOAT",
+		"FLOAT8":                      "FLOAT",
+		"FLOAT4":                      "FLOAT",
+		"REAL":                        "FLOAT",
+		"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+		"TIMESTAMP WITH TIME ZONE":    "TIMESTAMPTZ",
+	}
+)
+
+// convert the sql type to comparable format, e.g
+// int(11) => INTEGER, character varying(255) => VARCHAR(255), double precision => FLOAT
+func normalizeSqlType(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(sqlTypeSpaceRe.ReplaceAllString(s, " ")))
+	s = strings.Replace(s, " (", "(", -1)
+	s = strings.Replace(s, "CHARACTER VARYING", "VARCHAR", 1)
+	if strings.HasPrefix(s, "CHARACTER") {
+		s = "CHAR" + strings.TrimPrefix(s, "CHARACTER")
+	}
+	if alias, ok := sqlTypeAlias[s]; ok {
+		return alias
+	}
+	s = sqlTypeIntegerWidthRe.ReplaceAllString(s, "$1")
+	// keep the suffix like UNSIGNED
+	name, suffix := s, ""
+	if i := strings.Index(s, " "); i != -1 {
+		name, suffix = s[:i], s[i:]
+	}
+	if alias, ok := sqlTypeAlias[name]; ok {
+		name = alias
+	}
+	return name + suffix
+}
+
+// remove the quote and type cast of default value, e.g
+// 'abc'::character varying => abc, ('abc') => abc
+func normalizeDefault(s string) string {
+	s = strings.TrimSpace(s)
+	for len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	if i := strings.LastIndex(s, "::"); i != -1 && !strings.HasSuffix(s, "'") {
+		s = s[:i]
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	return s
+}
+
+func fieldNotNull(field Field) bool {
+	_, ok := field.Attrs()["not null"]
+	return ok || field.IsPrimary()
+}
+
+// the column need to alter if type, nullability or default value are different with live table
+// the default value only compare when model have defined it
+func columnChanged(dia Dialect, field Field, column tableColumn) bool {
+	if normalizeSqlType(dia.SqlType(field)) != normalizeSqlType(column.Type) {
+		// postgres serial column type is integer
+		if !(field.AutoIncrement() && strings.ToUpper(field.SqlType()) == "SERIAL") {
+			return true
+		}
+	}
+	if field.IsPrimary() == false && fieldNotNull(field) == column.Nullable {
+		return true
+	}
+	if _default := field.Default(); _default != "" && field.AutoIncrement() == false {
+		if column.Default.Valid == false || normalizeDefault(_default) != normalizeDefault(column.Default.String) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexColumnsEqual(fields []Field, columns []string) bool {
+	if len(fields) != len(columns) {
+		return false
+	}
+	for i := range fields {
+		if fields[i].Column() != columns[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// query the exist labels of enum type used by brick model, key is the type name
+func (t *ToyBrick) enumLabels() (map[string][]string, error) {
+	labels := map[string][]string{}
+	exec := t.Toy.Dialect.EnumLabelsExec(t.Model.GetSqlFields())
+	if exec == nil {
+		return labels, nil
+	}
+	rows, err := t.Query(exec)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name, label string
+		if err := rows.Scan(&name, &label); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		labels[name] = append(labels[name], label)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// diff brick model with the live table, the exec order is
+// drop index -> create enum type -> add column -> alter column -> create index -> add foreign key
+// the column and foreign key not in the model will keep in the table
+func migrateExecs(brick *ToyBrick) ([]ExecValue, error) {
+	dia := brick.Toy.Dialect
+	model := brick.Model
+	foreign := getForeignKeys(brick)
+	hasTable, err := brick.HasTable()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := brick.enumLabels()
+	if err != nil {
+		return nil, err
+	}
+	if hasTable == false {
+		// the enum type not exist will be created in CreateTable, add the new values to the exist one
+		var existEnumFields []Field
+		for _, field := range model.GetSqlFields() {
+			if _, ok := labels[dia.SqlType(field)]; ok {
+				existEnumFields = append(existEnumFields, field)
+			}
+		}
+		return append(dia.EnumTypeExecs(existEnumFields, labels), dia.CreateTable(model, foreign)...), nil
+	}
+	schema, err := brick.tableSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	type modelIndex struct {
+		fields []Field
+		unique bool
+	}
+	indexes := map[string]modelIndex{}
+	for name, fields := range model.GetIndexMap() {
+		indexes[name] = modelIndex{fields, false}
+	}
+	for name, fields := range model.GetUniqueIndexMap() {
+		indexes[name] = modelIndex{fields, true}
+	}
+	var indexNames, liveIndexNames []string
+	for name := range indexes {
+		indexNames = append(indexNames, name)
+	}
+	for name := range schema.Indexes {
+		liveIndexNames = append(liveIndexNames, name)
+	}
+	sort.Strings(indexNames)
+	sort.Strings(liveIndexNames)
+
+	var dropIndexes, addColumns, alterColumns, createIndexes, addForeignKeys []ExecValue
+	// mysql will create index for foreign key automatically, it can't drop
+	isForeignIndex := func(index *tableIndex) bool {
+		for _, key := range schema.ForeignKeys {
+			if key.Name == index.Name || (len(index.Columns) == 1 && index.Columns[0] == key.Column) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range liveIndexNames {
+		liveIndex := schema.Indexes[name]
+		if index, ok := indexes[name]; ok {
+			if index.unique == liveIndex.Unique && indexColumnsEqual(index.fields, liveIndex.Columns) {
+				continue
+			}
+		} else if isForeignIndex(liveIndex) {
+			continue
+		}
+		if exec := dia.DropIndexExec(model, name); exec != nil {
+			dropIndexes = append(dropIndexes, exec)
+		}
+	}
+
+	for _, field := range model.GetSqlFields() {
+		if column, ok := schema.Columns[field.Column()]; ok {
+			if columnChanged(dia, field, column) {
+				if exec := dia.AlterColumnExec(model, field); exec != nil {
+					alterColumns = append(alterColumns, exec)
+				}
+			}
+		} else if exec := dia.AddColumnExec(model, field); exec != nil {
+			addColumns = append(addColumns, exec)
+		}
+	}
+
+	for _, name := range indexNames {
+		index := indexes[name]
+		if liveIndex, ok := schema.Indexes[name]; ok {
+			if index.unique == liveIndex.Unique && indexColumnsEqual(index.fields, liveIndex.Columns) {
+				continue
+			}
+		}
+		if exec := dia.CreateIndexExec(model, name, FieldList(index.fields).ToColumnList(), index.unique); exec != nil {
+			createIndexes = append(createIndexes, exec)
+		}
+	}
+
+	var foreignNames []string
+	for name := range foreign {
+		foreignNames = append(foreignNames, name)
+	}
+	sort.Strings(foreignNames)
+	for _, name := range foreignNames {
+		key := foreign[name]
+		field := model.GetFieldWithName(name)
+		exist := false
+		for _, liveKey := range schema.ForeignKeys {
+			if liveKey.Column == field.Column() && liveKey.RefTable == key.Model.Name {
+				exist = true
+				break
+			}
+		}
+		if exist == false {
+			if exec := dia.AddForeignKey(model, key.Model, field); exec != nil {
+				addForeignKeys = append(addForeignKeys, exec)
+			}
+		}
+	}
+
+	var execs []ExecValue
+	enumTypes := dia.EnumTypeExecs(model.GetSqlFields(), labels)
+	for _, list := range [][]ExecValue{dropIndexes, enumTypes, addColumns, alterColumns, createIndexes, addForeignKeys} {
+		execs = append(execs, list...)
+	}
+	return execs, nil
+}
+
+// collect the migrate plan from MigratePlan result, order is same as the preload schedule
+func migratePlanFromResult(brick *ToyBrick, result *Result) []ExecValue {
+	var execs []ExecValue
+	for _, fieldName := range sortedMapKeys(brick.BelongToPreload) {
+		if subResult := result.Preload[fieldName]; subResult != nil {
+			execs = append(execs, migratePlanFromResult(brick.MapPreloadBrick[fieldName], subResult)...)
+		}
+	}
+	for _, action := range result.ActionFlow {
+		if execAction, ok := action.(ExecAction); ok {
+			execs = append(execs, execAction.Exec)
+		}
+	}
+	for _, fieldName := range sortedMapKeys(brick.OneToOnePreload) {
+		if subResult := result.Preload[fieldName]; subResult != nil {
+			execs = append(execs, migratePlanFromResult(brick.MapPreloadBrick[fieldName], subResult)...)
+		}
+	}
+	for _, fieldName := range sortedMapKeys(brick.OneToManyPreload) {
+		if subResult := result.Preload[fieldName]; subResult != nil {
+			execs = append(execs, migratePlanFromResult(brick.MapPreloadBrick[fieldName], subResult)...)
+		}
+	}
+	for _, fieldName := range sortedMapKeys(brick.ManyToManyPreload) {
+		if subResult := result.Preload[fieldName]; subResult != nil {
+			execs = append(execs, migratePlanFromResult(brick.MapPreloadBrick[fieldName], subResult)...)
+		}
+		if middleResult := result.MiddleModelPreload[fieldName]; middleResult != nil {
+			middleBrick := NewToyBrick(brick.Toy, brick.ManyToManyPreload[fieldName].MiddleModel)
+			execs = append(execs, migratePlanFromResult(middleBrick, middleResult)...)
+		}
+	}
+	return execs
+}
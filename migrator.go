@@ -0,0 +1,366 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versioned migration step, Up/Down receive a brick without model,
+// use brick.SwitchModel to operate model or brick.Exec to exec raw sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(*ToyBrick) error
+	Down    func(*ToyBrick) error
+}
+
+type MigrationStatus struct {
+	DbIndex   int // the db index of ToyCollection, always 0 in Toy
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// the applied migration record
+type migrationRecord struct {
+	Version   int64 `toyorm:"primary key"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (m *migrationRecord) TableName() string {
+	return "toyorm_migrations"
+}
+
+// run migrations in order and record the applied version in toyorm_migrations table,
+// every migration run in a transaction when database support DDL transaction
+type Migrator struct {
+	toys       []*Toy
+	collection bool
+	migrations []Migration
+}
+
+func NewMigrator(toy *Toy, migrations ...Migration) *Migrator {
+	m := &Migrator{toys: []*Toy{toy}}
+	return m.Add(migrations...)
+}
+
+// the migrations will run on all db of collection
+func NewCollectionMigrator(toy *ToyCollection, migrations ...Migration) *Migrator {
+	m := &Migrator{collection: true}
+	for i := range toy.dbs {
+		m.toys = append(m.toys, toy.shardToy(i))
+	}
+	return m.Add(migrations...)
+}
+
+// add migrations, panic when the version is repeat
+func (m *Migrator) Add(migrations ...Migration) *Migrator {
+	for _, migration := range migrations {
+		if m.migrationIndex(migration.Version) != -1 {
+			panic(ErrRepeatMigrationVersion{migration.Version})
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return m
+}
+
+func (m *Migrator) migrationIndex(version int64) int {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// run all not applied migrations
+func (m *Migrator) Up() error {
+	return m.each(func(_ int, toy *Toy) error {
+		return m.to(toy, math.MaxInt64)
+	})
+}
+
+// rollback the last applied migration
+func (m *Migrator) Down() error {
+	return m.each(func(_ int, toy *Toy) error {
+		applied, err := m.applied(toy)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.run(toy, m.migrations[i], false)
+			}
+		}
+		return nil
+	})
+}
+
+// migrate up or down to the version, the migrations after version will rollback
+// and the migrations before it(include itself) will apply, version 0 mean rollback all
+func (m *Migrator) To(version int64) error {
+	if version != 0 && m.migrationIndex(version) == -1 {
+		return ErrMigrationVersionNotFound{version}
+	}
+	return m.each(func(_ int, toy *Toy) error {
+		return m.to(toy, version)
+	})
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statusList []MigrationStatus
+	err := m.each(func(i int, toy *Toy) error {
+		applied, err := m.applied(toy)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{DbIndex: i, Version: migration.Version, Name: migration.Name}
+			if record, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = record.AppliedAt
+			}
+			statusList = append(statusList, status)
+		}
+		return nil
+	})
+	return statusList, err
+}
+
+// run fn on every toy, the error of collection is ErrCollectionMigrate
+func (m *Migrator) each(fn func(int, *Toy) error) error {
+	if m.collection == false {
+		return fn(0, m.toys[0])
+	}
+	errs := ErrCollectionMigrate{}
+	for i, toy := range m.toys {
+		if err := fn(i, toy); err != nil {
+			errs[i] = err
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *Migrator) to(toy *Toy, version int64) error {
+	applied, err := m.applied(toy)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			if err := m.run(toy, migration, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			if err := m.run(toy, migration, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// create the migrations table if not exist and get the applied records
+func (m *Migrator) applied(toy *Toy) (map[int64]migrationRecord, error) {
+	brick := toy.Model(&migrationRecord{})
+	result, err := brick.CreateTableIfNotExist()
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	var records []migrationRecord
+	result, err = brick.Find(&records)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	applied := map[int64]migrationRecord{}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func (m *Migrator) run(toy *Toy, migration Migration, up bool) error {
+	fn := migration.Up
+	if up == false {
+		if migration.Down == nil {
+			return ErrMigrationNoDown{migration.Version, migration.Name}
+		}
+		fn = migration.Down
+	}
+	step := func(brick *ToyBrick) error {
+		if fn != nil {
+			if err := fn(brick); err != nil {
+				return err
+			}
+		}
+		recordBrick := brick.SwitchModel(&migrationRecord{})
+		var result *Result
+		var err error
+		if up {
+			result, err = recordBrick.Insert(&migrationRecord{migration.Version, migration.Name, time.Now()})
+		} else {
+			result, err = recordBrick.Delete(&migrationRecord{Version: migration.Version})
+		}
+		if err != nil {
+			return err
+		}
+		return result.Err()
+	}
+	if toy.Dialect.TransactionalDDL() {
+		return toy.Transaction(step)
+	}
+	brick := NewToyBrick(toy, nil)
+	if toy.debug {
+		brick = brick.Debug()
+	}
+	return step(brick)
+}
+
+var sqlMigrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// load migrations from the .sql files in dir, the file name format is
+// {version}_{name}.up.sql and {version}_{name}.down.sql, down file is optional
+func LoadSqlMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	migrationMap := map[int64]*Migration{}
+	var versions []int64
+	for _, entry := range entries {
+		match := sqlMigrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration := migrationMap[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			migrationMap[version] = migration
+			versions = append(versions, version)
+		} else if migration.Name != match[2] {
+			return nil, ErrRepeatMigrationVersion{version}
+		}
+		fn := sqlMigrationFunc(splitSqlStatements(string(data)))
+		if match[3] == "up" {
+			migration.Up = fn
+		} else {
+			migration.Down = fn
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	var migrations []Migration
+	for _, version := range versions {
+		migrations = append(migrations, *migrationMap[version])
+	}
+	return migrations, nil
+}
+
+func sqlMigrationFunc(statements []string) func(*ToyBrick) error {
+	return func(brick *ToyBrick) error {
+		for _, statement := range statements {
+			if _, err := brick.Exec(DefaultExec{statement, nil}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var dollarQuoteTagRe = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// split sql by semicolon, the semicolon in quote, comment or postgres dollar quote ($$ ... $$, $tag$ ... $tag$) will be ignore,
+// the comment is removed
+func splitSqlStatements(s string) []string {
+	var statements []string
+	var buf strings.Builder
+	var quote byte
+	flush := func() {
+		if statement := strings.TrimSpace(buf.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		buf.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			// skip line comment
+			for i+1 < len(s) && s[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			// skip block comment
+			end := strings.Index(s[i+2:], "*/")
+			if end == -1 {
+				i = len(s)
+			} else {
+				i += end + 3
+			}
+			buf.WriteByte(' ')
+			continue
+		case c == '$':
+			if tag := dollarQuoteTagRe.FindString(s[i:]); tag != "" {
+				end := strings.Index(s[i+len(tag):], tag)
+				if end == -1 {
+					end = len(s)
+				} else {
+					end = i + len(tag) + end + len(tag)
+				}
+				buf.WriteString(s[i:end])
+				i = end - 1
+				continue
+			}
+		case c == ';':
+			flush()
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	flush()
+	return statements
+}
@@ -40,6 +40,8 @@ type Model struct {
 	UniqueIndexFields map[string][]*modelField
 	StructFieldFields map[reflect.Type][]*modelField
 	Association       [AssociationTypeEnd]map[string]*modelField
+	// soft delete, created at, updated at and version field
+	Conventions [ConventionEnd]*modelField
 }
 
 func (m *Model) GetPosFields(pos []int) []Field {
@@ -107,6 +109,30 @@ func (m *Model) GetOnePrimary() Field {
 	return m.PrimaryFields[0]
 }
 
+// get the field of convention, return nil when model have not it
+func (m *Model) GetConventionField(c Convention) Field {
+	if field := m.Conventions[c]; field != nil {
+		return field
+	}
+	return nil
+}
+
+func (m *Model) GetSoftDeleteField() Field {
+	return m.GetConventionField(ConventionSoftDelete)
+}
+
+func (m *Model) GetCreatedAtField() Field {
+	return m.GetConventionField(ConventionCreatedAt)
+}
+
+func (m *Model) GetUpdatedAtField() Field {
+	return m.GetConventionField(ConventionUpdatedAt)
+}
+
+func (m *Model) GetVersionField() Field {
+	return m.GetConventionField(ConventionVersion)
+}
+
 func (m *Model) GetIndexMap() map[string][]Field {
 	fieldMap := make(map[string][]Field, len(m.IndexFields))
 	for s, fields := range m.IndexFields {
@@ -221,6 +247,12 @@ func newModel(val reflect.Value, modelName string) *Model {
 			}
 			model.Association[association][val] = field
 		}
+		for _, convention := range field.conventions {
+			if model.Conventions[convention] != nil {
+				panic(ErrModelDuplicateConvention{model.Name, convention, field.field.Name})
+			}
+			model.Conventions[convention] = field
+		}
 		if field.ignore == false {
 			if oldField, ok := model.SqlFieldMap[field.column]; ok {
 				panic(ErrSameColumnName{model.Name, field.column, oldField.field.Name, field.field.Name})
@@ -233,6 +265,14 @@ func newModel(val reflect.Value, modelName string) *Model {
 			model.StructFieldFields[fieldType] = append(model.StructFieldFields[fieldType], field)
 		}
 	}
+	// the convention field without tag use default name
+	for convention, name := range conventionDefaultNames {
+		if model.Conventions[convention] == nil {
+			if field := model.NameFields[name]; field != nil && field.ignore == false {
+				model.Conventions[convention] = field
+			}
+		}
+	}
 	return model
 }
 
@@ -9,6 +9,7 @@ package toyorm
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -31,9 +32,19 @@ type Field interface {
 	Index() string                    // sql index declaration
 	UniqueIndex() string              // sql unique index declaration
 	IsForeign() bool                  // sql foreign declaration
+	OnDelete() string                 // foreign key ON DELETE action
+	OnUpdate() string                 // foreign key ON UPDATE action
+	Check() string                    // check constraint expression
+	Constraint() string               // constraint name of foreign key or check
 	Attr(string) string               // extension attribute declaration
 	Attrs() map[string]string         // get all extension attribute
 	SqlType() string                  // sql type declaration
+	TypeDeclared() bool               // sql type is declared by type tag
+	Size() int                        // size tag, e.g string length
+	Precision() (int, int)            // precision tag, return precision and scale
+	Json() bool                       // field value store as json in database
+	Array() bool                      // slice field store as array in database
+	Enum() []string                   // enum values of string type field
 	StructField() reflect.StructField // model struct attribute
 	FieldValue() reflect.Value        // model meta field value
 	JoinWith() string                 // join with specified container field declaration,when call ToyBrick.Preload(<container field>) will automatic association this field
@@ -63,7 +74,7 @@ func (a *aliasField) Column() string {
 }
 
 func (a *aliasField) ToFieldValue(value reflect.Value) FieldValue {
-	return &fieldValue{a, value}
+	return newFieldValue(a, value)
 }
 
 type tempField struct {
@@ -97,6 +108,13 @@ func (m *fieldValue) Value() reflect.Value {
 type modelField struct {
 	column        string
 	sqlType       string
+	typeDeclared  bool
+	size          int
+	precision     int
+	scale         int
+	json          bool
+	array         bool
+	enum          []string
 	offset        uintptr
 	isPrimary     bool
 	index         string
@@ -105,10 +123,16 @@ type modelField struct {
 	attrs         map[string]string
 	autoIncrement bool
 	isForeign     bool
+	onDelete      string
+	onUpdate      string
+	check         string
+	constraint    string
 	field         reflect.StructField
 	fieldValue    reflect.Value
 	alias         string
 	defaultVal    string
+	joinType      JoinType
+	conventions   []Convention
 	Association   map[AssociationType]string
 }
 
@@ -147,6 +171,22 @@ func (m *modelField) IsForeign() bool {
 	return m.isForeign
 }
 
+func (m *modelField) OnDelete() string {
+	return m.onDelete
+}
+
+func (m *modelField) OnUpdate() string {
+	return m.onUpdate
+}
+
+func (m *modelField) Check() string {
+	return m.check
+}
+
+func (m *modelField) Constraint() string {
+	return m.constraint
+}
+
 func (m *modelField) StructField() reflect.StructField {
 	return m.field
 }
@@ -167,6 +207,30 @@ func (m *modelField) SqlType() string {
 	return m.sqlType
 }
 
+func (m *modelField) TypeDeclared() bool {
+	return m.typeDeclared
+}
+
+func (m *modelField) Size() int {
+	return m.size
+}
+
+func (m *modelField) Precision() (int, int) {
+	return m.precision, m.scale
+}
+
+func (m *modelField) Json() bool {
+	return m.json
+}
+
+func (m *modelField) Array() bool {
+	return m.array
+}
+
+func (m *modelField) Enum() []string {
+	return m.enum
+}
+
 func (m *modelField) Default() string {
 	return m.defaultVal
 }
@@ -200,7 +264,7 @@ func (m *modelField) ToColumnAlias(alias string) Field {
 }
 
 func (m *modelField) ToFieldValue(value reflect.Value) FieldValue {
-	return &fieldValue{m, value}
+	return newFieldValue(m, value)
 }
 
 type tagKeyValue struct {
@@ -208,8 +272,9 @@ type tagKeyValue struct {
 	Val string
 }
 
+// split key and value by the first ":", value can contain ":" e.g check:x::numeric > 0
 func newTagKeyVal(s string) *tagKeyValue {
-	sList := strings.Split(s, ":")
+	sList := strings.SplitN(s, ":", 2)
 	keyVal := new(tagKeyValue)
 	switch len(sList) {
 	case 0:
@@ -246,6 +311,7 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 		sqlType:     ToSqlType(f.Type),
 		Association: map[AssociationType]string{},
 		fieldValue:  fieldVal,
+		enum:        enumValues(f.Type),
 	}
 
 	// set attribute by tag
@@ -258,6 +324,30 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 			field.isPrimary = true
 		case "type":
 			field.sqlType = tagKeyVal.Val
+			field.typeDeclared = true
+		case "json":
+			field.json = true
+		case "array":
+			field.array = true
+		case "size":
+			size, err := strconv.Atoi(tagKeyVal.Val)
+			if err != nil {
+				panic(ErrInvalidTag)
+			}
+			field.size = size
+		case "precision":
+			// precision:10 or precision:10,2
+			var err error
+			precision, scale := tagKeyVal.Val, "0"
+			if i := strings.Index(precision, ","); i != -1 {
+				precision, scale = precision[:i], precision[i+1:]
+			}
+			if field.precision, err = strconv.Atoi(strings.TrimSpace(precision)); err != nil {
+				panic(ErrInvalidTag)
+			}
+			if field.scale, err = strconv.Atoi(strings.TrimSpace(scale)); err != nil {
+				panic(ErrInvalidTag)
+			}
 		case "index":
 			if tagKeyVal.Val == "" {
 				field.index = fmt.Sprintf("idx_%s_%s", table_name, field.column)
@@ -272,6 +362,11 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 			}
 		case "foreign key":
 			field.isForeign = true
+			field.onDelete, field.onUpdate = parseForeignKeyOption(tagKeyVal.Val)
+		case "check":
+			field.check = tagKeyVal.Val
+		case "constraint":
+			field.constraint = tagKeyVal.Val
 		case "column":
 			field.column = tagKeyVal.Val
 		case "-":
@@ -279,7 +374,17 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 		case "alias":
 			field.alias = tagKeyVal.Val
 		case "join":
-			field.Association[JoinWith] = tagKeyVal.Val
+			// join type modifier, e.g join:Detail,left
+			if i := strings.Index(tagKeyVal.Val, ","); i != -1 {
+				joinType, ok := tagJoinTypes[strings.TrimSpace(tagKeyVal.Val[i+1:])]
+				if ok == false {
+					panic(ErrInvalidTag)
+				}
+				field.joinType = joinType
+				field.Association[JoinWith] = strings.TrimSpace(tagKeyVal.Val[:i])
+			} else {
+				field.Association[JoinWith] = tagKeyVal.Val
+			}
 		case "belong to":
 			field.Association[BelongToWith] = tagKeyVal.Val
 		case "one to one":
@@ -288,6 +393,8 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 			field.Association[OneToManyWith] = tagKeyVal.Val
 		case "default":
 			field.defaultVal = tagKeyVal.Val
+		case "soft delete", "created at", "updated at", "version":
+			field.conventions = append(field.conventions, conventionTags[tagKeyVal.Key])
 		//case "middle model with":
 		//	field.Association[MiddleModelWith] = val
 		//case "left model with":
@@ -299,6 +406,19 @@ func NewField(f *reflect.StructField, fieldVal reflect.Value, table_name string)
 			field.attrs[tagKeyVal.Key] = tagKeyVal.Val
 		}
 	}
+	// the check tag can contain ";" and ":", e.g check:"start_at < '12:00'"
+	if check := strings.TrimSpace(f.Tag.Get("check")); check != "" {
+		field.check = check
+	}
+	if field.typeDeclared == false {
+		if field.json || field.array {
+			field.sqlType = "TEXT"
+		} else if typeFunc := registeredSqlType(f.Type); typeFunc != nil {
+			field.sqlType = typeFunc("", field.size, field.precision, field.scale)
+		} else if field.size > 0 && toSqlKind(f.Type) == sqlKindString {
+			field.sqlType = varcharType(field)
+		}
+	}
 	if field.column == "" || field.sqlType == "" {
 		field.ignore = true
 	}
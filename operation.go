@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Operation is the handler chain name of toy operation
+type Operation string
+
+const (
+	OpCreateTable              Operation = "CreateTable"
+	OpCreateTableIfNotExist    Operation = "CreateTableIfNotExist"
+	OpMigrate                  Operation = "Migrate"
+	OpMigratePlan              Operation = "MigratePlan"
+	OpDropTableIfExist         Operation = "DropTableIfExist"
+	OpDropTable                Operation = "DropTable"
+	OpInsert                   Operation = "Insert"
+	OpFind                     Operation = "Find"
+	OpFindOne                  Operation = "FindOne" // only collection
+	OpUpdate                   Operation = "Update"
+	OpSave                     Operation = "Save"
+	OpUSave                    Operation = "USave"
+	OpUpsert                   Operation = "Upsert"
+	OpHardDelete               Operation = "HardDelete"
+	OpSoftDelete               Operation = "SoftDelete"
+	OpHardDeleteWithPrimaryKey Operation = "HardDeleteWithPrimaryKey"
+	OpSoftDeleteWithPrimaryKey Operation = "SoftDeleteWithPrimaryKey"
+	OpRestoreWithPrimaryKey    Operation = "RestoreWithPrimaryKey"
+)
+
+// the function name of handler without package path, e.g HandlerInsert,
+// the closure handler use the name of function which create it, e.g HandlerPreloadInsertOrSave
+func HandlerName(handler interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = name[strings.Index(name, ".")+1:]
+	if i := strings.Index(name, "."); i != -1 {
+		name = name[:i]
+	}
+	return name
+}
+
+// insert handlers before or after the handler with name, return false when not found
+func insertHandlers(chain HandlersChain, name string, after bool, handlers []HandlerFunc) (HandlersChain, bool) {
+	for i, handler := range chain {
+		if HandlerName(handler) == name {
+			if after {
+				i++
+			}
+			newChain := make(HandlersChain, 0, len(chain)+len(handlers))
+			newChain = append(newChain, chain[:i]...)
+			newChain = append(newChain, handlers...)
+			newChain = append(newChain, chain[i:]...)
+			return newChain, true
+		}
+	}
+	return nil, false
+}
+
+// the handler change of model chain, empty name means append to the end,
+// it apply to the current default chain, so the later change of default chain also reach the model
+type handlerDelta struct {
+	name     string
+	after    bool
+	handlers []HandlerFunc
+}
+
+// apply the deltas to chain in order, the delta whose handler name not found is skipped
+func applyHandlerDeltas(chain HandlersChain, deltas []handlerDelta) HandlersChain {
+	for _, delta := range deltas {
+		if delta.name == "" {
+			chain = append(chain[:len(chain):len(chain)], delta.handlers...)
+		} else if newChain, ok := insertHandlers(chain, delta.name, delta.after, delta.handlers); ok {
+			chain = newChain
+		}
+	}
+	return chain
+}
+
+func insertCollectionHandlers(chain CollectionHandlersChain, name string, after bool, handlers []CollectionHandlerFunc) (CollectionHandlersChain, bool) {
+	for i, handler := range chain {
+		if HandlerName(handler) == name {
+			if after {
+				i++
+			}
+			newChain := make(CollectionHandlersChain, 0, len(chain)+len(handlers))
+			newChain = append(newChain, chain[:i]...)
+			newChain = append(newChain, handlers...)
+			newChain = append(newChain, chain[i:]...)
+			return newChain, true
+		}
+	}
+	return nil, false
+}
+
+type collectionHandlerDelta struct {
+	name     string
+	after    bool
+	handlers []CollectionHandlerFunc
+}
+
+func applyCollectionHandlerDeltas(chain CollectionHandlersChain, deltas []collectionHandlerDelta) CollectionHandlersChain {
+	for _, delta := range deltas {
+		if delta.name == "" {
+			chain = append(chain[:len(chain):len(chain)], delta.handlers...)
+		} else if newChain, ok := insertCollectionHandlers(chain, delta.name, delta.after, delta.handlers); ok {
+			chain = newChain
+		}
+	}
+	return chain
+}
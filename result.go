@@ -262,6 +262,29 @@ func (r QueryAction) Err() error {
 	return nil
 }
 
+// the model hook call of record, only record the failure hook
+type HookAction struct {
+	Hook       string
+	affectData []int
+	Error      error
+}
+
+func (r HookAction) String() string {
+	return fmt.Sprintf("hook %s error(%v)", r.Hook, r.Error)
+}
+
+func (r HookAction) AffectData() []int {
+	return r.affectData
+}
+
+func (r HookAction) SetAffectData(d []int) {
+	r.affectData = d
+}
+
+func (r HookAction) Err() error {
+	return r.Error
+}
+
 type CollectionExecAction struct {
 	//Type   ResultType
 	Exec       ExecValue
@@ -27,6 +27,8 @@ const (
 	ExprNotLike      = "NOT LIKE"
 	ExprNull         = "NULL"
 	ExprNotNull      = "NOT NULL"
+	ExprExists       = "EXISTS"
+	ExprNotExists    = "NOT EXISTS"
 )
 
 func (op SearchExpr) IsBranch() bool {
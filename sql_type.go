@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SqlTypeFunc return the sql type of registered go type,
+// dialect is the driver name("mysql", "postgres", "sqlite3"), empty dialect means the common sql type,
+// size and precision, scale is the field tag value, zero means not set
+type SqlTypeFunc func(dialect string, size, precision, scale int) string
+
+var sqlTypeRegistry = struct {
+	sync.RWMutex
+	types map[reflect.Type]SqlTypeFunc
+}{types: map[reflect.Type]SqlTypeFunc{}}
+
+// register the sql type of v's type, it will override the default type mapping
+// e.g
+//
+//	RegisterSqlType(decimal.Decimal{}, func(dialect string, size, precision, scale int) string {
+//	    return fmt.Sprintf("DECIMAL(%d,%d)", precision, scale)
+//	})
+func RegisterSqlType(v interface{}, f SqlTypeFunc) {
+	sqlTypeRegistry.Lock()
+	defer sqlTypeRegistry.Unlock()
+	sqlTypeRegistry.types[reflect.TypeOf(v)] = f
+}
+
+func registeredSqlType(_type reflect.Type) SqlTypeFunc {
+	sqlTypeRegistry.RLock()
+	defer sqlTypeRegistry.RUnlock()
+	for {
+		if f := sqlTypeRegistry.types[_type]; f != nil {
+			return f
+		}
+		if _type.Kind() != reflect.Ptr {
+			return nil
+		}
+		_type = _type.Elem()
+	}
+}
+
+// the kind of go type in sql
+type sqlKind int
+
+const (
+	sqlKindInvalid sqlKind = iota
+	sqlKindBool
+	sqlKindInt
+	sqlKindBigInt
+	sqlKindUBigInt
+	sqlKindFloat
+	sqlKindDouble
+	sqlKindString
+	sqlKindBytes
+	sqlKindTime
+	sqlKindJson
+	sqlKindArray
+	sqlKindEnum
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	nullBoolType    = reflect.TypeOf(sql.NullBool{})
+	nullInt64Type   = reflect.TypeOf(sql.NullInt64{})
+	nullFloat64Type = reflect.TypeOf(sql.NullFloat64{})
+	nullStringType  = reflect.TypeOf(sql.NullString{})
+)
+
+func toSqlKind(_type reflect.Type) sqlKind {
+	_type = LoopTypeIndirect(_type)
+	switch _type.Kind() {
+	case reflect.Bool:
+		return sqlKindBool
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return sqlKindInt
+	case reflect.Int64, reflect.Int:
+		return sqlKindBigInt
+	case reflect.Uint64, reflect.Uint:
+		return sqlKindUBigInt
+	case reflect.Float32:
+		return sqlKindFloat
+	case reflect.Float64:
+		return sqlKindDouble
+	case reflect.String:
+		return sqlKindString
+	case reflect.Slice:
+		if _type.Elem().Kind() == reflect.Uint8 {
+			return sqlKindBytes
+		}
+	case reflect.Struct:
+		switch _type {
+		case timeType:
+			return sqlKindTime
+		case nullBoolType:
+			return sqlKindBool
+		case nullInt64Type:
+			return sqlKindBigInt
+		case nullFloat64Type:
+			return sqlKindDouble
+		case nullStringType:
+			return sqlKindString
+		}
+	}
+	return sqlKindInvalid
+}
+
+// the sql type of field in dialect, the priority is type tag > json/array tag > registered type > dialect mapping
+func dialectSqlType(dialect string, field Field, mapping func(kind sqlKind, field Field) string) string {
+	if field.TypeDeclared() {
+		return field.SqlType()
+	}
+	if field.Json() || field.Array() {
+		kind := sqlKindJson
+		if field.Array() {
+			kind = sqlKindArray
+		}
+		if s := mapping(kind, field); s != "" {
+			return s
+		}
+		return field.SqlType()
+	}
+	if len(field.Enum()) != 0 {
+		if s := mapping(sqlKindEnum, field); s != "" {
+			return s
+		}
+	}
+	precision, scale := field.Precision()
+	if f := registeredSqlType(field.StructField().Type); f != nil {
+		return f(dialect, field.Size(), precision, scale)
+	}
+	if s := mapping(toSqlKind(field.StructField().Type), field); s != "" {
+		return s
+	}
+	return field.SqlType()
+}
+
+// VARCHAR(size), default size is 255
+func varcharType(field Field) string {
+	if size := field.Size(); size > 0 {
+		return "VARCHAR(" + strconv.Itoa(size) + ")"
+	}
+	return "VARCHAR(255)"
+}
+
+// the time type with fractional seconds precision, e.g TIMESTAMP(6)
+func timeSqlType(name string, field Field) string {
+	if precision, _ := field.Precision(); precision > 0 {
+		return name + "(" + strconv.Itoa(precision) + ")"
+	}
+	return name
+}
@@ -8,7 +8,9 @@ package toyorm
 
 import (
 	"encoding/json"
+	"github.com/stretchr/testify/assert"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -39,3 +41,29 @@ func TestSameModel(t *testing.T) {
 		}
 	}
 }
+
+func TestModelCache(t *testing.T) {
+	kernel := ToyKernel{models: newModelCache()}
+	kernel.RegisterModels(&TestPreloadTable{})
+	model := kernel.GetModel(reflect.ValueOf(TestPreloadTable{}))
+	// preload field model also registered
+	for _, v := range []interface{}{TestPreloadTableBelongTo{}, TestPreloadTableOneToOne{}, TestPreloadTableOneToMany{}, TestPreloadTableManyToMany{}} {
+		_, ok := kernel.models.models[modelCacheKey{reflect.TypeOf(v), ModelName(reflect.ValueOf(v))}]
+		assert.True(t, ok)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, model == kernel.GetModel(reflect.ValueOf(TestPreloadTable{})))
+		}()
+	}
+	wg.Wait()
+
+	// dynamic table name is different model
+	m1 := kernel.GetModel(reflect.ValueOf(&TestCustomTableNameBelongTo{FragNum: 1}).Elem())
+	m2 := kernel.GetModel(reflect.ValueOf(&TestCustomTableNameBelongTo{FragNum: 2}).Elem())
+	assert.Equal(t, "test_custom_table_name_belong_to_1", m1.Name)
+	assert.Equal(t, "test_custom_table_name_belong_to_2", m2.Name)
+}
@@ -10,6 +10,7 @@ import (
 	"database/sql"
 	"os"
 	"reflect"
+	"sync"
 )
 
 type Toy struct {
@@ -17,6 +18,11 @@ type Toy struct {
 	objMustAddr              bool
 	DefaultHandlerChain      map[string]HandlersChain
 	DefaultModelHandlerChain map[reflect.Type]map[string]HandlersChain
+	// the handler changes of model, they apply to the current default chain when get the model handlers
+	modelHandlerDeltas map[reflect.Type]map[string][]handlerDelta
+	handlerMu          sync.RWMutex
+	// query result cache, nil is disable
+	cache *queryCache
 	ToyKernel
 }
 
@@ -36,29 +42,39 @@ func Open(driverName, dataSourceName string) (*Toy, error) {
 	default:
 		dialect = DefaultDialect{}
 	}
+	return newToy(db, dialect), nil
+}
+
+func newToy(db *sql.DB, dialect Dialect) *Toy {
 	return &Toy{
 		db: db,
 		DefaultHandlerChain: map[string]HandlersChain{
 			"CreateTable":              {HandlerCreateTablePreload("CreateTable"), HandlerCreateTable},
 			"CreateTableIfNotExist":    {HandlerCreateTablePreload("CreateTableIfNotExist"), HandlerExistTableAbort, HandlerCreateTable},
+			"Migrate":                  {HandlerCreateTablePreload("Migrate"), HandlerMigrate},
+			"MigratePlan":              {HandlerCreateTablePreload("MigratePlan"), HandlerMigratePlan},
 			"DropTableIfExist":         {HandlerDropTablePreload("DropTableIfExist"), HandlerNotExistTableAbort, HandlerDropTable},
 			"DropTable":                {HandlerDropTablePreload("DropTable"), HandlerDropTable},
-			"Insert":                   {HandlerPreloadContainerCheck, HandlerPreloadInsertOrSave("Insert"), HandlerCasVersionPushOne, HandlerInsertTimeGenerate, HandlerInsert},
-			"Find":                     {HandlerPreloadContainerCheck, HandlerSoftDeleteCheck, HandlerFind, HandlerPreloadOnJoinFind, HandlerPreloadFind},
-			"Update":                   {HandlerSoftDeleteCheck, HandlerUpdateTimeGenerate, HandlerUpdate},
-			"Save":                     {HandlerPreloadContainerCheck, HandlerPreloadInsertOrSave("Save"), HandlerCasVersionPushOne, HandlerSaveTimeGenerate, HandlerSave},
-			"USave":                    {HandlerPreloadContainerCheck, HandlerPreloadInsertOrSave("USave"), HandlerCasVersionPushOne, HandlerUSaveTimeGenerate, HandlerUSave},
-			"HardDelete":               {HandlerPreloadDelete, HandlerHardDelete},
-			"SoftDelete":               {HandlerPreloadDelete, HandlerSoftDelete},
-			"HardDeleteWithPrimaryKey": {HandlerPreloadDelete, HandlerSearchWithPrimaryKey, HandlerHardDelete},
-			"SoftDeleteWithPrimaryKey": {HandlerPreloadDelete, HandlerSearchWithPrimaryKey, HandlerSoftDelete},
+			"Insert":                   {HandlerCacheInvalidate, HandlerPreloadContainerCheck, HandlerBeforeInsertHook, HandlerAfterInsertHook, HandlerPreloadInsertOrSave("Insert"), HandlerCasVersionPushOne, HandlerInsertTimeGenerate, HandlerInsert},
+			"Find":                     {HandlerPreloadContainerCheck, HandlerSoftDeleteCheck, HandlerAfterFindHook, HandlerCacheFind, HandlerFind, HandlerPreloadOnJoinFind, HandlerPreloadFind},
+			"Update":                   {HandlerCacheInvalidate, HandlerSoftDeleteCheck, HandlerBeforeUpdateHook, HandlerAfterUpdateHook, HandlerUpdateTimeGenerate, HandlerUpdate},
+			"Save":                     {HandlerCacheInvalidate, HandlerPreloadContainerCheck, HandlerBeforeSaveHook, HandlerAfterSaveHook, HandlerPreloadInsertOrSave("Save"), HandlerCasVersionPushOne, HandlerSaveTimeGenerate, HandlerSave},
+			"USave":                    {HandlerCacheInvalidate, HandlerPreloadContainerCheck, HandlerBeforeSaveHook, HandlerAfterSaveHook, HandlerPreloadInsertOrSave("USave"), HandlerCasVersionPushOne, HandlerUSaveTimeGenerate, HandlerUSave},
+			"Upsert":                   {HandlerCacheInvalidate, HandlerPreloadContainerCheck, HandlerBeforeInsertHook, HandlerBeforeSaveHook, HandlerAfterInsertHook, HandlerAfterSaveHook, HandlerPreloadInsertOrSave("Upsert"), HandlerInsertTimeGenerate, HandlerUpsert},
+			"HardDelete":               {HandlerCacheInvalidate, HandlerBeforeDeleteHook, HandlerAfterDeleteHook, HandlerPreloadDelete, HandlerHardDelete},
+			"SoftDelete":               {HandlerCacheInvalidate, HandlerBeforeDeleteHook, HandlerAfterDeleteHook, HandlerPreloadDelete, HandlerSoftDelete},
+			"HardDeleteWithPrimaryKey": {HandlerCacheInvalidate, HandlerBeforeDeleteHook, HandlerAfterDeleteHook, HandlerPreloadDelete, HandlerSearchWithPrimaryKey, HandlerHardDelete},
+			"SoftDeleteWithPrimaryKey": {HandlerCacheInvalidate, HandlerBeforeDeleteHook, HandlerAfterDeleteHook, HandlerPreloadDelete, HandlerSearchWithPrimaryKey, HandlerSoftDelete},
+			"RestoreWithPrimaryKey":    {HandlerCacheInvalidate, HandlerPreloadRestore, HandlerSearchWithPrimaryKey, HandlerRestore},
 		},
 		DefaultModelHandlerChain: map[reflect.Type]map[string]HandlersChain{},
+		modelHandlerDeltas:       map[reflect.Type]map[string][]handlerDelta{},
 		ToyKernel: ToyKernel{
 			Dialect: dialect,
 			Logger:  os.Stdout,
+			models:  newModelCache(),
 		},
-	}, nil
+	}
 }
 
 func (t *Toy) Model(v interface{}) *ToyBrick {
@@ -76,6 +92,16 @@ func (t *Toy) Model(v interface{}) *ToyBrick {
 	return toyBrick
 }
 
+// run fn in transaction, the brick of fn have not model, use brick.SwitchModel to get the model brick
+// commit it when fn return nil, otherwise rollback it, if fn panic, rollback and panic again
+func (t *Toy) Transaction(fn func(*ToyBrick) error) error {
+	brick := NewToyBrick(t, nil)
+	if t.debug {
+		brick = brick.Debug()
+	}
+	return brick.Transaction(fn)
+}
+
 // required object in Toy.[Model]
 // TODO Brick.[Insert,Find,Update,Save,USave,Delete] method's parameter must be point object
 func (t *Toy) MustAddr(b bool) {
@@ -91,12 +117,95 @@ func (t *Toy) MiddleModel(v, sv interface{}) *ToyBrick {
 }
 
 func (t *Toy) ModelHandlers(option string, model *Model) HandlersChain {
-	handlers := make(HandlersChain, 0, len(t.DefaultHandlerChain[option])+len(t.DefaultModelHandlerChain[model.ReflectType][option]))
+	t.handlerMu.RLock()
+	defer t.handlerMu.RUnlock()
+	chain := t.modelChain(model, option)
+	handlers := make(HandlersChain, 0, len(chain)+len(t.DefaultModelHandlerChain[model.ReflectType][option]))
 	handlers = append(handlers, t.DefaultModelHandlerChain[model.ReflectType][option]...)
-	handlers = append(handlers, t.DefaultHandlerChain[option]...)
+	handlers = append(handlers, chain...)
 	return handlers
 }
 
+// set the handlers run before the default handler chain of model
+func (t *Toy) SetModelHandlers(option string, model *Model, handlers HandlersChain) {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	if t.DefaultModelHandlerChain[model.ReflectType] == nil {
+		t.DefaultModelHandlerChain[model.ReflectType] = map[string]HandlersChain{}
+	}
+	t.DefaultModelHandlerChain[model.ReflectType][option] = handlers
+}
+
+// append handlers to the end of operation default handler chain
+func (t *Toy) Use(op Operation, handlers ...HandlerFunc) {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	chain := t.DefaultHandlerChain[string(op)]
+	t.DefaultHandlerChain[string(op)] = append(chain[:len(chain):len(chain)], handlers...)
+}
+
+// insert handlers before the handler with name in operation default handler chain
+// e.g toy.InsertBefore(OpInsert, "HandlerInsert", myHandler)
+func (t *Toy) InsertBefore(op Operation, name string, handlers ...HandlerFunc) error {
+	return t.insertHandlers(nil, op, name, false, handlers)
+}
+
+func (t *Toy) InsertAfter(op Operation, name string, handlers ...HandlerFunc) error {
+	return t.insertHandlers(nil, op, name, true, handlers)
+}
+
+// append handlers to the end of operation handler chain of model v,
+// the model handler chain is the default chain with model changes, so the later change of default chain also reach it
+func (t *Toy) UseModel(v interface{}, op Operation, handlers ...HandlerFunc) {
+	model := t.GetModel(LoopDivePtr(reflect.ValueOf(v)))
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	t.addModelDelta(model, op, handlerDelta{handlers: handlers})
+}
+
+func (t *Toy) InsertModelBefore(v interface{}, op Operation, name string, handlers ...HandlerFunc) error {
+	return t.insertHandlers(t.GetModel(LoopDivePtr(reflect.ValueOf(v))), op, name, false, handlers)
+}
+
+func (t *Toy) InsertModelAfter(v interface{}, op Operation, name string, handlers ...HandlerFunc) error {
+	return t.insertHandlers(t.GetModel(LoopDivePtr(reflect.ValueOf(v))), op, name, true, handlers)
+}
+
+// the model handler chain, need hold the handler lock
+func (t *Toy) modelChain(model *Model, option string) HandlersChain {
+	return applyHandlerDeltas(t.DefaultHandlerChain[option], t.modelHandlerDeltas[model.ReflectType][option])
+}
+
+// need hold the handler lock
+func (t *Toy) addModelDelta(model *Model, op Operation, delta handlerDelta) {
+	if t.modelHandlerDeltas[model.ReflectType] == nil {
+		t.modelHandlerDeltas[model.ReflectType] = map[string][]handlerDelta{}
+	}
+	t.modelHandlerDeltas[model.ReflectType][string(op)] = append(t.modelHandlerDeltas[model.ReflectType][string(op)], delta)
+}
+
+// nil model means default handler chain
+func (t *Toy) insertHandlers(model *Model, op Operation, name string, after bool, handlers []HandlerFunc) error {
+	t.handlerMu.Lock()
+	defer t.handlerMu.Unlock()
+	var chain HandlersChain
+	if model == nil {
+		chain = t.DefaultHandlerChain[string(op)]
+	} else {
+		chain = t.modelChain(model, string(op))
+	}
+	newChain, ok := insertHandlers(chain, name, after, handlers)
+	if ok == false {
+		return ErrHandlerNotFound{op, name}
+	}
+	if model == nil {
+		t.DefaultHandlerChain[string(op)] = newChain
+	} else {
+		t.addModelDelta(model, op, handlerDelta{name, after, handlers})
+	}
+	return nil
+}
+
 func (t *Toy) Close() error {
 	if t == nil {
 		return nil
@@ -155,7 +264,7 @@ func (t *Toy) OneToManyPreload(model *Model, field Field) *OneToManyPreload {
 }
 
 func (t *Toy) ManyToManyPreload(model *Model, field Field, isRight bool) *ManyToManyPreload {
-	return t.manyToManyPreloadWithTag(model, field, isRight, `toyorm:"primary key"`)
+	return t.manyToManyPreloadWithTag(model, field, isRight, middleModelTag(field))
 }
 
 func (t *Toy) manyToManyPreloadWithTag(model *Model, field Field, isRight bool, tag reflect.StructTag) *ManyToManyPreload {
@@ -179,17 +288,33 @@ func (t *Toy) Join(model *Model, field Field) *Join {
 	subModel := t.GetModel(val)
 	containerName := field.Name()
 	if model.Association[JoinWith][containerName] != nil && subModel.Association[JoinWith][containerName] != nil {
-		return &Join{
-			model,
-			subModel,
-			field,
-			model.Association[JoinWith][containerName],
-			subModel.Association[JoinWith][containerName],
+		onMain, onSub := model.Association[JoinWith][containerName], subModel.Association[JoinWith][containerName]
+		// main model tag join type have high priority
+		joinType := JoinInner
+		if onMain.joinType != "" {
+			joinType = onMain.joinType
+		} else if onSub.joinType != "" {
+			joinType = onSub.joinType
 		}
+		return t.JoinBind(model, subModel, field, onMain, onSub, joinType)
 	}
 	return nil
 }
 
+func (t *Toy) JoinBind(model, subModel *Model, containerField, onMain, onSub Field, joinType JoinType) *Join {
+	if LoopTypeIndirect(onMain.StructField().Type) != LoopTypeIndirect(onSub.StructField().Type) {
+		panic("join fields must have same type")
+	}
+	return &Join{
+		Model:     model,
+		SubModel:  subModel,
+		Container: containerField,
+		OnMain:    onMain,
+		OnSub:     onSub,
+		Type:      joinType,
+	}
+}
+
 func (t *Toy) BelongToBind(model, subModel *Model, containerField, relationField Field) *BelongToPreload {
 	if LoopTypeIndirect(relationField.StructField().Type) != subModel.GetOnePrimary().StructField().Type {
 		panic("relation key must have same type with sub model primary key")
@@ -7,9 +7,11 @@
 package toyorm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 type PreToyBrick struct {
@@ -31,6 +33,15 @@ type ToyBrick struct {
 	//Error             []error
 	debug bool
 	tx    *sql.Tx
+	ctx   context.Context
+	// transaction options, nil is default options
+	txOptions *sql.TxOptions
+	// nested transaction depth, Begin in transaction will create a save point
+	txDepth int
+	// the models written in transaction, invalidate their cache after commit
+	txWrites *txWrites
+	// max records size of one insert statement
+	batchSize int
 
 	orderBy  FieldList
 	Search   SearchList
@@ -47,6 +58,21 @@ type ToyBrick struct {
 	SwapMap    map[string]*JoinSwap
 	JoinMap    map[string]*Join
 
+	// upsert conflict fields and the update strategy of conflict
+	conflict         []Field
+	upsertStrategies map[string]UpsertStrategy
+	// invalid conflict fields error, Upsert will return it
+	conflictErr error
+
+	// HAVING condition of grouped query
+	having SearchList
+
+	// soft delete filter, it also affect preload brick
+	deletedScope deletedScope
+
+	// the ttl of Find/Count result cache, 0 is not use cache
+	cacheTTL time.Duration
+
 	objMustAddr bool // TODO maybe not a good way
 
 	BrickCommon
@@ -83,6 +109,14 @@ func (t *ToyBrick) Or() ToyBrickOr {
 	return ToyBrickOr{t}
 }
 
+func (t *ToyBrick) HavingAnd() ToyBrickHavingAnd {
+	return ToyBrickHavingAnd{t}
+}
+
+func (t *ToyBrick) HavingOr() ToyBrickHavingOr {
+	return ToyBrickHavingOr{t}
+}
+
 func (t *ToyBrick) Scope(fn func(*ToyBrick) *ToyBrick) *ToyBrick {
 	ret := fn(t)
 	return ret
@@ -91,7 +125,13 @@ func (t *ToyBrick) Scope(fn func(*ToyBrick) *ToyBrick) *ToyBrick {
 func (t *ToyBrick) CopyStatus(statusBrick *ToyBrick) *ToyBrick {
 	newt := *t
 	newt.tx = statusBrick.tx
+	newt.txDepth = statusBrick.txDepth
+	newt.txWrites = statusBrick.txWrites
+	newt.txOptions = statusBrick.txOptions
+	newt.ctx = statusBrick.ctx
+	newt.batchSize = statusBrick.batchSize
 	newt.debug = statusBrick.debug
+	newt.deletedScope = statusBrick.deletedScope
 	newt.ignoreModeSelector = t.ignoreModeSelector
 
 	return &newt
@@ -178,6 +218,60 @@ func (t *ToyBrick) Preload(fv FieldSelection) *ToyBrick {
 	})
 }
 
+// cache the Find/Count result with ttl when toy have set cache, ttl < 0 means never expire,
+// the Insert/Save/USave/Update/Delete of model used in query will invalidate it
+func (t *ToyBrick) Cache(ttl time.Duration) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := *t
+		newt.cacheTTL = ttl
+		return &newt
+	})
+}
+
+// skip the soft delete filter of Find/Count/Update and Delete will be hard delete,
+// the preload brick also be affected
+func (t *ToyBrick) Unscoped() *ToyBrick {
+	return t.withDeletedScope(scopeUnscoped)
+}
+
+// only Find/Count/Update the soft deleted data, the preload brick also be affected
+func (t *ToyBrick) OnlyDeleted() *ToyBrick {
+	return t.withDeletedScope(scopeOnlyDeleted)
+}
+
+func (t *ToyBrick) withDeletedScope(scope deletedScope) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := *t
+		newt.deletedScope = scope
+		newt.MapPreloadBrick = make(map[string]*ToyBrick, len(t.MapPreloadBrick))
+		for name, preloadBrick := range t.MapPreloadBrick {
+			newt.MapPreloadBrick[name] = preloadBrick.withDeletedScope(scope)
+		}
+		return &newt
+	})
+}
+
+// add the soft delete condition of current scope
+func (t *ToyBrick) softDeleteScope() *ToyBrick {
+	deletedField := t.Model.GetSoftDeleteField()
+	if deletedField == nil || t.deletedScope == scopeUnscoped {
+		return t
+	}
+	var expr SearchExpr
+	var args []interface{}
+	if t.deletedScope == scopeOnlyDeleted {
+		expr, args = deletedCondition(deletedField)
+	} else {
+		expr, args = notDeletedCondition(deletedField)
+	}
+	return t.Where(expr, deletedField, args...).And().Conditions(t.Search)
+}
+
+// delete action use soft delete
+func (t *ToyBrick) softDeleteMode() bool {
+	return t.Model.GetSoftDeleteField() != nil && t.deletedScope != scopeUnscoped
+}
+
 func (t *ToyBrick) CopyJoinSwap() map[string]*JoinSwap {
 	newMap := make(map[string]*JoinSwap, len(t.SwapMap))
 	for k, v := range t.SwapMap {
@@ -209,19 +303,7 @@ func (t *ToyBrick) Join(fv FieldSelection) *ToyBrick {
 			newt.preSwap = &PreJoinSwap{currentJoinSwap, t.preSwap, t.Model, field}
 			return &newt
 		} else if join := t.Toy.Join(t.Model, field); join != nil {
-			newt := *t
-			newt.Model = join.SubModel
-			swap := NewJoinSwap(fmt.Sprintf("%s_%d", t.alias, len(t.JoinMap)))
-			currentJoinSwap := joinSwap(swap, &newt)
-
-			// add field to pre swap
-			currentJoinSwap.JoinMap = t.CopyJoin()
-			currentJoinSwap.JoinMap[field.Name()] = join
-			currentJoinSwap.SwapMap = t.CopyJoinSwap()
-			currentJoinSwap.SwapMap[field.Name()] = swap
-
-			newt.preSwap = &PreJoinSwap{currentJoinSwap, t.preSwap, t.Model, field}
-			return &newt
+			return t.addJoin(field, join)
 		} else {
 			panic(ErrInvalidPreloadField{t.Model.ReflectType.Name(), field.Name()})
 		}
@@ -229,6 +311,101 @@ func (t *ToyBrick) Join(fv FieldSelection) *ToyBrick {
 	})
 }
 
+// join the container field model on specified field pair, it's not need join tag,
+// onSub is the field of container model, it will replace the exist join of container
+// e.g brick.JoinBy(Offsetof(User{}.Profile), Offsetof(User{}.ID), Offsetof(Profile{}.UserID))
+func (t *ToyBrick) JoinBy(container, onMain, onSub FieldSelection) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		if t.alias == "" {
+			t = t.Alias("m")
+		}
+		field := t.Model.fieldSelect(container)
+		subModel := t.Toy.GetModel(LoopDiveSliceAndPtr(field.FieldValue()))
+		join := t.Toy.JoinBind(t.Model, subModel, field, t.Model.fieldSelect(onMain), subModel.fieldSelect(onSub), JoinInner)
+		return t.addJoin(field, join)
+	})
+}
+
+func (t *ToyBrick) addJoin(field Field, join *Join) *ToyBrick {
+	newt := *t
+	newt.Model = join.SubModel
+	swap := NewJoinSwap(fmt.Sprintf("%s_%d", t.alias, len(t.JoinMap)))
+	currentJoinSwap := joinSwap(swap, &newt)
+
+	// add field to pre swap
+	currentJoinSwap.JoinMap = t.CopyJoin()
+	currentJoinSwap.JoinMap[field.Name()] = join
+	currentJoinSwap.SwapMap = t.CopyJoinSwap()
+	currentJoinSwap.SwapMap[field.Name()] = swap
+
+	newt.preSwap = &PreJoinSwap{currentJoinSwap, t.preSwap, t.Model, field}
+	return &newt
+}
+
+// add extra ON condition to current join, it will combine with join fields condition by AND
+// e.g brick.Join(Offsetof(User{}.Profile)).On(ExprNull, Offsetof(Profile{}.DeletedAt)).Swap()
+func (t *ToyBrick) On(expr SearchExpr, key FieldSelection, v ...interface{}) *ToyBrick {
+	return t.OnConditions(t.condition(expr, key, v...))
+}
+
+func (t *ToyBrick) OnConditions(search SearchList) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		if t.preSwap == nil {
+			panic("parent swap is nil")
+		}
+		if len(search) == 0 {
+			return t
+		}
+		newt := *t
+		preSwap := *t.preSwap
+		swap := *preSwap.Swap
+		name := preSwap.Field.Name()
+		join := *swap.JoinMap[name]
+		join.OnSearch, _ = andSearch(join.OnSearch, nil, search)
+		swap.JoinMap = make(map[string]*Join, len(preSwap.Swap.JoinMap))
+		for k, v := range preSwap.Swap.JoinMap {
+			swap.JoinMap[k] = v
+		}
+		swap.JoinMap[name] = &join
+		preSwap.Swap = &swap
+		newt.preSwap = &preSwap
+		return &newt
+	})
+}
+
+// join with specified join type, it will replace the join type of tag
+func (t *ToyBrick) JoinWithType(fv FieldSelection, joinType JoinType) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := t.Join(fv)
+		name := newt.preSwap.Field.Name()
+		swap := newt.preSwap.Swap
+		if join := swap.JoinMap[name]; join.Type != joinType {
+			newJoin := *join
+			newJoin.Type = joinType
+			joinMap := make(map[string]*Join, len(swap.JoinMap))
+			for k, v := range swap.JoinMap {
+				joinMap[k] = v
+			}
+			joinMap[name] = &newJoin
+			swap.JoinMap = joinMap
+		}
+		return newt
+	})
+}
+
+func (t *ToyBrick) LeftJoin(fv FieldSelection) *ToyBrick {
+	return t.JoinWithType(fv, JoinLeft)
+}
+
+func (t *ToyBrick) RightJoin(fv FieldSelection) *ToyBrick {
+	return t.JoinWithType(fv, JoinRight)
+}
+
+// mysql not support full outer join
+func (t *ToyBrick) FullJoin(fv FieldSelection) *ToyBrick {
+	return t.JoinWithType(fv, JoinFull)
+}
+
 func (t *ToyBrick) Swap() *ToyBrick {
 	if t.preSwap == nil {
 		panic("parent swap is nil")
@@ -271,12 +448,25 @@ func (t *ToyBrick) Alias(alias string) *ToyBrick {
 			newt.Search = make(SearchList, len(t.Search))
 			copy(newt.Search, t.Search)
 			for _, i := range t.OwnSearch {
-				newt.Search[i].Val = newt.Search[i].Val.Source().
+				// keep the template wrapper of field, e.g json path
+				newt.Search[i].Val = newt.Search[i].Val.
 					ToColumnAlias(alias).ToFieldValue(newt.Search[i].Val.Value())
 
 			}
 		}
 
+		// reassignment all having, keep the aggregate wrapper of field
+		if len(t.having) != 0 {
+			newt.having = make(SearchList, len(t.having))
+			copy(newt.having, t.having)
+			for i := range newt.having {
+				if newt.having[i].Type.IsBranch() == false {
+					newt.having[i].Val = newt.having[i].Val.
+						ToColumnAlias(alias).ToFieldValue(newt.having[i].Val.Value())
+				}
+			}
+		}
+
 		return &newt
 	})
 }
@@ -435,9 +625,29 @@ func (t *ToyBrick) condition(expr SearchExpr, key FieldSelection, args ...interf
 	} else {
 		value = reflect.ValueOf(args)
 	}
+	if expr == ExprExists || expr == ExprNotExists {
+		// the key of exists is sub query brick, the invalid one will return error when brick run
+		if sub, ok := key.(*ToyBrick); ok {
+			return SearchList{}.Condition(subQueryValue{value: reflect.ValueOf(sub)}, expr, ExprAnd)
+		}
+		return SearchList{}.Condition(subQueryValue{err: ErrInvalidSubQuery}, expr, ExprAnd)
+	}
 	mField := t.Model.fieldSelect(key)
+	columnField := mField.ToColumnAlias(t.alias)
+	if _, ok := jsonPathExprs[expr]; ok {
+		expr, columnField, args = jsonPathCondition(t.Toy.Dialect, expr, columnField, args)
+		if len(args) == 1 {
+			value = reflect.ValueOf(args[0])
+		} else {
+			value = reflect.ValueOf(args)
+		}
+	}
+
+	if invalid, ok := invalidSubQueryValue(expr, columnField, args); ok {
+		return SearchList{}.Condition(invalid, expr, ExprAnd)
+	}
 
-	search := SearchList{}.Condition(mField.ToColumnAlias(t.alias).ToFieldValue(value), expr, ExprAnd)
+	search := SearchList{}.Condition(columnField.ToFieldValue(value), expr, ExprAnd)
 
 	return search
 }
@@ -493,6 +703,32 @@ func (t *ToyBrick) Conditions(search SearchList) *ToyBrick {
 	})
 }
 
+// set the HAVING condition of grouped query, key can be a aggregate field
+// e.g brick.GroupBy(Offsetof(Product{}.Name)).Having(ExprGreater, brick.AggregateField(AggSum, Offsetof(Product{}.Price)), 100)
+func (t *ToyBrick) Having(expr SearchExpr, key FieldSelection, v ...interface{}) *ToyBrick {
+	return t.HavingConditions(t.condition(expr, key, v...))
+}
+
+// expr only support And/Or , group must be struct data or map[string]interface{}/map[uintptr]interface{}
+func (t *ToyBrick) HavingGroup(expr SearchExpr, group interface{}) *ToyBrick {
+	return t.HavingConditions(t.conditionGroup(expr, group))
+}
+
+func (t *ToyBrick) HavingConditions(search SearchList) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := *t
+		if len(search) == 0 {
+			newt.having = nil
+			return &newt
+		}
+		newt.having = make(SearchList, len(search), len(search)+1)
+		copy(newt.having, search)
+		// Avoid "or" condition effected by priority
+		newt.having = append(newt.having, NewSearchBranch(ExprIgnore))
+		return &newt
+	})
+}
+
 func (t *ToyBrick) Limit(i int) *ToyBrick {
 	return t.Scope(func(t *ToyBrick) *ToyBrick {
 		newt := *t
@@ -548,26 +784,151 @@ func (t *ToyBrick) Template(temp string, args ...interface{}) *ToyBrick {
 	})
 }
 
-func (t *ToyBrick) Begin() *ToyBrick {
+// all sql statement and preload statement will use this context
+// the context also used by Begin, cancel it will rollback the transaction
+func (t *ToyBrick) WithContext(ctx context.Context) *ToyBrick {
 	return t.Scope(func(t *ToyBrick) *ToyBrick {
 		newt := *t
-		tx, err := newt.Toy.db.Begin()
-		if err != nil {
-			panic(err)
+		newt.ctx = ctx
+		newt.MapPreloadBrick = make(map[string]*ToyBrick, len(t.MapPreloadBrick))
+		for name, preloadBrick := range t.MapPreloadBrick {
+			newt.MapPreloadBrick[name] = preloadBrick.WithContext(ctx)
 		}
-		newt.tx = tx
+		// join preload brick also need context
+		newt.SwapMap = swapMapPreloadScope(t.SwapMap, func(preloadBrick *ToyBrick) *ToyBrick {
+			return preloadBrick.WithContext(ctx)
+		})
 		return &newt
 	})
 }
 
+// copy the join swap map and replace all preload bricks in it with fn(preloadBrick)
+func swapMapPreloadScope(swapMap map[string]*JoinSwap, fn func(*ToyBrick) *ToyBrick) map[string]*JoinSwap {
+	newMap := make(map[string]*JoinSwap, len(swapMap))
+	for name, swap := range swapMap {
+		newSwap := swap.Copy()
+		newSwap.MapPreloadBrick = make(map[string]*ToyBrick, len(swap.MapPreloadBrick))
+		for preloadName, preloadBrick := range swap.MapPreloadBrick {
+			newSwap.MapPreloadBrick[preloadName] = fn(preloadBrick)
+		}
+		newSwap.SwapMap = swapMapPreloadScope(swap.SwapMap, fn)
+		newMap[name] = newSwap
+	}
+	return newMap
+}
+
+// set the transaction status of statusBrick to brick and all preload bricks(include join preload bricks),
+// so the preload query run in the same transaction
+func (t *ToyBrick) withTxStatus(statusBrick *ToyBrick) *ToyBrick {
+	newt := *t
+	newt.tx = statusBrick.tx
+	newt.txDepth = statusBrick.txDepth
+	newt.txWrites = statusBrick.txWrites
+	newt.txOptions = statusBrick.txOptions
+	newt.MapPreloadBrick = make(map[string]*ToyBrick, len(t.MapPreloadBrick))
+	for name, preloadBrick := range t.MapPreloadBrick {
+		newt.MapPreloadBrick[name] = preloadBrick.withTxStatus(statusBrick)
+	}
+	newt.SwapMap = swapMapPreloadScope(t.SwapMap, func(preloadBrick *ToyBrick) *ToyBrick {
+		return preloadBrick.withTxStatus(statusBrick)
+	})
+	return &newt
+}
+
+// return brick context, if not set return context.Background()
+func (t *ToyBrick) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// return a new brick of model v, it keep the current brick status(transaction, context, debug ...)
+func (t *ToyBrick) SwitchModel(v interface{}) *ToyBrick {
+	return t.Toy.Model(v).CopyStatus(t)
+}
+
+// start a transaction, if brick already in transaction, create a save point in it
+func (t *ToyBrick) Begin() (*ToyBrick, error) {
+	return t.BeginTx(nil)
+}
+
+// start a transaction with options(isolation level, read only)
+// if brick already in transaction, create a save point in it and the options will be ignored
+func (t *ToyBrick) BeginTx(opts *sql.TxOptions) (*ToyBrick, error) {
+	newt := *t
+	if t.tx == nil {
+		tx, err := newt.Toy.db.BeginTx(newt.Context(), opts)
+		if err != nil {
+			return nil, err
+		}
+		newt.tx = tx
+		newt.txDepth = 0
+		newt.txWrites = &txWrites{}
+		newt.txOptions = opts
+		return newt.withTxStatus(&newt), nil
+	}
+	newt.txDepth = t.txDepth + 1
+	_, err := newt.Exec(newt.Toy.Dialect.SavePointExec(newt.savePointName()))
+	if err != nil {
+		return nil, err
+	}
+	return newt.withTxStatus(&newt), nil
+}
+
+// commit the transaction, release the save point in nested transaction
 func (t *ToyBrick) Commit() error {
-	return t.tx.Commit()
+	if t.txDepth != 0 {
+		_, err := t.Exec(t.Toy.Dialect.ReleaseSavePointExec(t.savePointName()))
+		return err
+	}
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	if t.Toy.cache != nil {
+		t.txWrites.invalidate(t.Toy.cache)
+	}
+	return nil
 }
 
+// rollback the transaction, rollback to the save point in nested transaction
 func (t *ToyBrick) Rollback() error {
+	if t.txDepth != 0 {
+		_, err := t.Exec(t.Toy.Dialect.RollbackToSavePointExec(t.savePointName()))
+		return err
+	}
 	return t.tx.Rollback()
 }
 
+func (t *ToyBrick) savePointName() string {
+	return fmt.Sprintf("toyorm_sp_%d", t.txDepth)
+}
+
+// run fn in transaction(or save point when brick already in transaction)
+// commit it when fn return nil, otherwise rollback it, if fn panic, rollback and panic again
+// when rollback failure, return(or panic) ErrTransactionRollback with the fn error(or panic value) and rollback error
+func (t *ToyBrick) Transaction(fn func(*ToyBrick) error) (err error) {
+	txBrick, err := t.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if rbErr := txBrick.Rollback(); rbErr != nil {
+				panic(ErrTransactionRollback{Err: fmt.Errorf("panic: %v", r), RollbackErr: rbErr})
+			}
+			panic(r)
+		}
+	}()
+	if err = fn(txBrick); err != nil {
+		if rbErr := txBrick.Rollback(); rbErr != nil {
+			return ErrTransactionRollback{Err: err, RollbackErr: rbErr}
+		}
+		return err
+	}
+	return txBrick.Commit()
+}
+
 func (t *ToyBrick) Debug() *ToyBrick {
 	return t.Scope(func(t *ToyBrick) *ToyBrick {
 		newt := *t
@@ -576,6 +937,76 @@ func (t *ToyBrick) Debug() *ToyBrick {
 	})
 }
 
+// set the upsert conflict fields, it must be primary key or a unique index, default is primary key
+// Upsert will return ErrInvalidConflictFields when fields is invalid
+func (t *ToyBrick) Conflict(fvs ...FieldSelection) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		var fields []Field
+		var names []string
+		for _, fv := range fvs {
+			field := t.Model.fieldSelect(fv)
+			fields = append(fields, field)
+			names = append(names, field.Name())
+		}
+		newt := *t
+		newt.conflict = fields
+		newt.conflictErr = nil
+		if conflictFieldsCheck(t.Model, fields) == false {
+			newt.conflictErr = ErrInvalidConflictFields{t.Model.Name, names}
+		}
+		return &newt
+	})
+}
+
+func (t *ToyBrick) upsertStrategy(strategy UpsertStrategy, fvs ...FieldSelection) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := *t
+		newt.upsertStrategies = make(map[string]UpsertStrategy, len(t.upsertStrategies)+len(fvs))
+		for k, v := range t.upsertStrategies {
+			newt.upsertStrategies[k] = v
+		}
+		for _, fv := range fvs {
+			newt.upsertStrategies[t.Model.fieldSelect(fv).Name()] = strategy
+		}
+		return &newt
+	})
+}
+
+// upsert conflict will update these fields with insert value, it's default strategy
+func (t *ToyBrick) ConflictOverwrite(fvs ...FieldSelection) *ToyBrick {
+	return t.upsertStrategy(UpsertStrategy{Action: UpsertOverwrite}, fvs...)
+}
+
+// upsert conflict will keep the old value of these fields
+func (t *ToyBrick) ConflictKeep(fvs ...FieldSelection) *ToyBrick {
+	return t.upsertStrategy(UpsertStrategy{Action: UpsertKeep}, fvs...)
+}
+
+// upsert conflict will update field with expression, use EXCLUDED.column to get insert value
+// and table.column to get the old value, the bare column is ambiguous in postgres
+// e.g ConflictExpr(Offsetof(Data{}.Count), "data.count + EXCLUDED.count")
+func (t *ToyBrick) ConflictExpr(fv FieldSelection, expr string, args ...interface{}) *ToyBrick {
+	return t.upsertStrategy(UpsertStrategy{Action: UpsertExpr, Expr: expr, Args: args}, fv)
+}
+
+// set the max records size of one insert statement, size <= 1 means insert records one by one
+// it's also the chunk size of Cursor preload
+// the preload brick created after it will use same batch size
+func (t *ToyBrick) BatchSize(size int) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		newt := *t
+		newt.batchSize = size
+		return &newt
+	})
+}
+
+func (t *ToyBrick) getBatchSize() int {
+	if t.batchSize == 0 {
+		return DefaultBatchSize
+	}
+	return t.batchSize
+}
+
 func (t *ToyBrick) IgnoreMode(s Mode, ignore IgnoreMode) *ToyBrick {
 	newt := *t
 	newt.ignoreModeSelector[s] = ignore
@@ -601,6 +1032,12 @@ func (t *ToyBrick) save(records ModelRecords) (*Result, error) {
 	return ctx.Result, ctx.Next()
 }
 
+func (t *ToyBrick) upsert(records ModelRecords) (*Result, error) {
+	handlers := t.Toy.ModelHandlers("Upsert", t.Model)
+	ctx := NewContext(handlers, t, records)
+	return ctx.Result, ctx.Next()
+}
+
 func (t *ToyBrick) usave(records ModelRecords) (*Result, error) {
 	handlers := t.Toy.ModelHandlers("USave", t.Model)
 	ctx := NewContext(handlers, t, records)
@@ -608,7 +1045,7 @@ func (t *ToyBrick) usave(records ModelRecords) (*Result, error) {
 }
 
 func (t *ToyBrick) deleteWithPrimaryKey(records ModelRecords) (*Result, error) {
-	if field := t.Model.GetFieldWithName("DeletedAt"); field != nil {
+	if t.softDeleteMode() {
 		return t.softDeleteWithPrimaryKey(records)
 	} else {
 		return t.hardDeleteWithPrimaryKey(records)
@@ -628,7 +1065,7 @@ func (t *ToyBrick) hardDeleteWithPrimaryKey(records ModelRecords) (*Result, erro
 }
 
 func (t *ToyBrick) delete(records ModelRecords) (*Result, error) {
-	if field := t.Model.GetFieldWithName("DeletedAt"); field != nil {
+	if t.softDeleteMode() {
 		return t.softDelete(records)
 	} else {
 		return t.hardDelete(records)
@@ -641,6 +1078,12 @@ func (t *ToyBrick) softDelete(records ModelRecords) (*Result, error) {
 	return ctx.Result, ctx.Next()
 }
 
+func (t *ToyBrick) restoreWithPrimaryKey(records ModelRecords) (*Result, error) {
+	handlers := t.Toy.ModelHandlers("RestoreWithPrimaryKey", t.Model)
+	ctx := NewContext(handlers, t, records)
+	return ctx.Result, ctx.Next()
+}
+
 func (t *ToyBrick) hardDelete(records ModelRecords) (*Result, error) {
 	handlers := t.Toy.ModelHandlers("HardDelete", t.Model)
 	ctx := NewContext(handlers, t, records)
@@ -679,6 +1122,22 @@ func (t *ToyBrick) CreateTableIfNotExist() (*Result, error) {
 	return ctx.Result, ctx.Next()
 }
 
+// compare the model with the live table, add the missing columns, alter the changed columns,
+// rebuild the changed indexes and add the missing foreign keys, create table if it not exist
+func (t *ToyBrick) Migrate() (*Result, error) {
+	ctx := t.GetContext("Migrate", MakeRecordsWithElem(t.Model, t.Model.ReflectType))
+	return ctx.Result, ctx.Next()
+}
+
+// dry run of Migrate, return the execs it will do
+func (t *ToyBrick) MigratePlan() ([]ExecValue, error) {
+	ctx := t.GetContext("MigratePlan", MakeRecordsWithElem(t.Model, t.Model.ReflectType))
+	if err := ctx.Next(); err != nil {
+		return nil, err
+	}
+	return migratePlanFromResult(t, ctx.Result), nil
+}
+
 func (t *ToyBrick) DropTable() (*Result, error) {
 	ctx := t.GetContext("DropTable", MakeRecordsWithElem(t.Model, t.Model.ReflectType))
 	return ctx.Result, ctx.Next()
@@ -696,11 +1155,88 @@ func (t *ToyBrick) HasTable() (b bool, err error) {
 }
 
 func (t *ToyBrick) Count() (count int, err error) {
+	if err := t.searchErr(); err != nil {
+		return 0, err
+	}
 	exec := t.CountExec()
+	if t.useCache() {
+		key := t.cacheKey("Count", nil, exec)
+		if value, ok := t.Toy.cache.get(key); ok {
+			return value.(int), nil
+		}
+		versions := t.Toy.cache.snapshot(t.cacheModels(false))
+		err = t.QueryRow(exec).Scan(&count)
+		if err == nil {
+			t.Toy.cache.set(versions, key, count, t.cacheTTL)
+		}
+		return count, err
+	}
 	err = t.QueryRow(exec).Scan(&count)
 	return count, err
 }
 
+// Sum, Avg, Min and Max scan the aggregate result of field into v, v must be a pointer,
+// the result is NULL when no data match, use pointer or sql.NullXXX to receive it
+func (t *ToyBrick) Sum(fv FieldSelection, v interface{}) error {
+	return t.aggregate(AggSum, fv, v)
+}
+
+func (t *ToyBrick) Avg(fv FieldSelection, v interface{}) error {
+	return t.aggregate(AggAvg, fv, v)
+}
+
+func (t *ToyBrick) Min(fv FieldSelection, v interface{}) error {
+	return t.aggregate(AggMin, fv, v)
+}
+
+func (t *ToyBrick) Max(fv FieldSelection, v interface{}) error {
+	return t.aggregate(AggMax, fv, v)
+}
+
+func (t *ToyBrick) aggregate(fn AggregateFunc, fv FieldSelection, v interface{}) error {
+	if err := t.searchErr(); err != nil {
+		return err
+	}
+	field := t.Model.fieldSelect(fv).ToColumnAlias(t.alias)
+	exec := t.softDeleteScope().FindExec([]Column{exprColumn(aggregateExpr(fn, field))})
+	return t.QueryRow(exec).Scan(v)
+}
+
+// grouped aggregate query, select the group by fields and aggregates columns, e.g
+// SELECT category,COUNT(*) AS count,SUM(price) AS total FROM product GROUP BY category
+// v must be *[]struct, *[]*struct or *[]map[string]interface{}
+func (t *ToyBrick) Aggregate(v interface{}, aggregates ...Aggregate) error {
+	if err := t.searchErr(); err != nil {
+		return err
+	}
+	exec := t.AggregateExec(aggregates...)
+	rows, err := t.Query(exec)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanAggregateRows(rows, v)
+}
+
+func (t *ToyBrick) AggregateExec(aggregates ...Aggregate) ExecValue {
+	var columns []Column
+	for _, field := range t.groupBy {
+		columns = append(columns, field)
+	}
+	for _, agg := range aggregates {
+		var field Field
+		if agg.Field != nil {
+			field = t.Model.fieldSelect(agg.Field).ToColumnAlias(t.alias)
+		}
+		expr := aggregateExpr(agg.Func, field)
+		if agg.As != "" {
+			expr += " AS " + agg.As
+		}
+		columns = append(columns, exprColumn(expr))
+	}
+	return t.softDeleteScope().FindExec(columns)
+}
+
 // insert can receive three type data
 // struct
 // map[offset]interface{}
@@ -741,15 +1277,95 @@ func (t *ToyBrick) Find(v interface{}) (*Result, error) {
 	return ctx.Result, err
 }
 
+// find the records by primary key order in batches, v is the pointer of slice and it will be
+// replaced with the records of current batch before fn call, the next batch use
+// WHERE primary key > last primary key instead of offset, the order by of brick will be replaced
+func (t *ToyBrick) FindInBatches(v interface{}, batchSize int, fn func(batch int) error) error {
+	vValue := LoopIndirect(reflect.ValueOf(v))
+	if vValue.Kind() != reflect.Slice || vValue.CanSet() == false {
+		panic("v must be pointer of slice")
+	}
+	primaryField := t.Model.GetOnePrimary()
+	brick := t.OrderBy(primaryField).Limit(batchSize)
+	pageBrick := brick
+	for batch := 0; ; batch++ {
+		vValue.Set(reflect.MakeSlice(vValue.Type(), 0, batchSize))
+		result, err := pageBrick.Find(v)
+		if err != nil {
+			return err
+		}
+		if err := result.Err(); err != nil {
+			return err
+		}
+		size := vValue.Len()
+		if size == 0 {
+			return nil
+		}
+		// fn may change the records, get the last primary key before it
+		last := NewRecords(t.Model, vValue).GetRecord(size - 1).Field(primaryField.Name()).Interface()
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if size < batchSize {
+			return nil
+		}
+		pageBrick = brick.Seek(last)
+	}
+}
+
+// keyset pagination, find the records after the values of order by fields,
+// the DESC field made with ToDesc use < to compare
+// e.g brick.OrderBy(brick.ToDesc(Offsetof(User{}.Age)), Offsetof(User{}.ID)).Seek(20, 100)
+// WHERE (age < 20 OR age = 20 AND id > 100) ORDER BY age DESC,id
+func (t *ToyBrick) Seek(after ...interface{}) *ToyBrick {
+	return t.Scope(func(t *ToyBrick) *ToyBrick {
+		if len(after) != len(t.orderBy) {
+			panic(ErrSeekValues{len(t.orderBy), len(after)})
+		}
+		var search SearchList
+		for i := range t.orderBy {
+			var term SearchList
+			for j := 0; j < i; j++ {
+				field, _ := seekField(t.orderBy[j])
+				term, _ = andSearch(term, nil, t.condition(ExprEqual, field, after[j]))
+			}
+			field, desc := seekField(t.orderBy[i])
+			expr := SearchExpr(ExprGreater)
+			if desc {
+				expr = ExprLess
+			}
+			term, _ = andSearch(term, nil, t.condition(expr, field, after[i]))
+			search, _ = orSearch(search, nil, term)
+		}
+		return t.Conditions(search).And().Conditions(t.Search)
+	})
+}
+
+// the compare field of order by field, desc is true when it made with ToDesc
+func seekField(field Field) (Field, bool) {
+	if temp, ok := field.(*tempField); ok && temp.temp == "%s DESC" {
+		return temp.Field, true
+	}
+	return field, false
+}
+
 func (t *ToyBrick) Update(v interface{}) (*Result, error) {
 	vValue := LoopIndirect(reflect.ValueOf(v))
 	if t.objMustAddr && vValue.CanAddr() == false {
 		panic("object must can addr")
 	}
-	vValueList := reflect.MakeSlice(reflect.SliceOf(vValue.Type()), 0, 1)
-	vValueList = reflect.Append(vValueList, vValue)
+	var records ModelRecords
+	// use address to write back the version
+	if vValue.Kind() == reflect.Struct && vValue.CanAddr() {
+		records = MakeRecordsWithElem(t.Model, vValue.Addr().Type())
+		records.Add(vValue.Addr())
+	} else {
+		vValueList := reflect.MakeSlice(reflect.SliceOf(vValue.Type()), 0, 1)
+		vValueList = reflect.Append(vValueList, vValue)
+		records = NewRecords(t.Model, vValueList)
+	}
 	handlers := t.Toy.ModelHandlers("Update", t.Model)
-	ctx := NewContext(handlers, t, NewRecords(t.Model, vValueList))
+	ctx := NewContext(handlers, t, records)
 	return ctx.Result, ctx.Next()
 }
 
@@ -769,6 +1385,43 @@ func (t *ToyBrick) Save(v interface{}) (*Result, error) {
 	}
 }
 
+// insert data, if it conflict with primary key or unique index, update the conflict row
+// use Conflict to set the conflict fields, ConflictKeep/ConflictExpr to set the update strategy
+func (t *ToyBrick) Upsert(v interface{}) (*Result, error) {
+	if t.conflictErr != nil {
+		return nil, t.conflictErr
+	}
+	if err := t.upsertVersionErr(); err != nil {
+		return nil, err
+	}
+	vValue := LoopIndirect(reflect.ValueOf(v))
+	if t.objMustAddr && vValue.CanAddr() == false {
+		panic("object must can addr")
+	}
+	switch vValue.Kind() {
+	case reflect.Slice:
+		records := NewRecords(t.Model, vValue)
+		return t.upsert(records)
+	default:
+		records := MakeRecordsWithElem(t.Model, vValue.Addr().Type())
+		records.Add(vValue.Addr())
+		return t.upsert(records)
+	}
+}
+
+// the model with version field can't upsert, include the preload models
+func (t *ToyBrick) upsertVersionErr() error {
+	if t.Model.GetVersionField() != nil {
+		return ErrUpsertVersion
+	}
+	for _, preloadBrick := range t.MapPreloadBrick {
+		if err := preloadBrick.upsertVersionErr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // save with exist data
 func (t *ToyBrick) USave(v interface{}) (*Result, error) {
 	vValue := LoopIndirect(reflect.ValueOf(v))
@@ -803,6 +1456,23 @@ func (t *ToyBrick) Delete(v interface{}) (*Result, error) {
 	}
 }
 
+// clear the soft delete field of v and the preload data with primary key
+func (t *ToyBrick) Restore(v interface{}) (*Result, error) {
+	vValue := LoopIndirect(reflect.ValueOf(v))
+	if t.objMustAddr && vValue.CanAddr() == false {
+		panic("object must can addr")
+	}
+	var records ModelRecords
+	switch vValue.Kind() {
+	case reflect.Slice:
+		records = NewRecords(t.Model, vValue)
+	default:
+		records = MakeRecordsWithElem(t.Model, vValue.Addr().Type())
+		records.Add(vValue.Addr())
+	}
+	return t.restoreWithPrimaryKey(records)
+}
+
 func (t *ToyBrick) DeleteWithConditions() (*Result, error) {
 	return t.delete(nil)
 }
@@ -810,19 +1480,27 @@ func (t *ToyBrick) DeleteWithConditions() (*Result, error) {
 func (t *ToyBrick) debugPrint(exec ExecValue, err error) {
 	if t.debug {
 		if err != nil {
-			fmt.Fprintf(t.Toy.Logger, "use tx: %p, query:%s  args:%s faiure reason %s\n", t.tx, exec.Query(), exec.JsonArgs(), err)
+			fmt.Fprintf(t.Toy.Logger, "use tx: %s, query:%s  args:%s faiure reason %s\n", txDebugInfo(t.tx, t.txOptions), exec.Query(), exec.JsonArgs(), err)
 		} else {
-			fmt.Fprintf(t.Toy.Logger, "use tx: %p, query:%s  args:%s\n", t.tx, exec.Query(), exec.JsonArgs())
+			fmt.Fprintf(t.Toy.Logger, "use tx: %s, query:%s  args:%s\n", txDebugInfo(t.tx, t.txOptions), exec.Query(), exec.JsonArgs())
 		}
 	}
 }
 
+// get the executor with brick context, use by Dialect.InsertExecutor/SaveExecutor
+func (t *ToyBrick) executor() Executor {
+	if t.tx == nil {
+		return contextExecutor{t.Context(), t.Toy.db}
+	}
+	return contextExecutor{t.Context(), t.tx}
+}
+
 func (t *ToyBrick) Exec(exec ExecValue) (result sql.Result, err error) {
 	query := exec.Query()
 	if t.tx == nil {
-		result, err = t.Toy.db.Exec(query, exec.Args()...)
+		result, err = t.Toy.db.ExecContext(t.Context(), query, exec.Args()...)
 	} else {
-		result, err = t.tx.Exec(query, exec.Args()...)
+		result, err = t.tx.ExecContext(t.Context(), query, exec.Args()...)
 	}
 
 	t.debugPrint(exec, err)
@@ -832,9 +1510,9 @@ func (t *ToyBrick) Exec(exec ExecValue) (result sql.Result, err error) {
 func (t *ToyBrick) Query(exec ExecValue) (rows *sql.Rows, err error) {
 	query := exec.Query()
 	if t.tx == nil {
-		rows, err = t.Toy.db.Query(query, exec.Args()...)
+		rows, err = t.Toy.db.QueryContext(t.Context(), query, exec.Args()...)
 	} else {
-		rows, err = t.tx.Query(query, exec.Args()...)
+		rows, err = t.tx.QueryContext(t.Context(), query, exec.Args()...)
 	}
 	t.debugPrint(exec, err)
 	return
@@ -843,9 +1521,9 @@ func (t *ToyBrick) Query(exec ExecValue) (rows *sql.Rows, err error) {
 func (t *ToyBrick) QueryRow(exec ExecValue) (row *sql.Row) {
 	query := exec.Query()
 	if t.tx == nil {
-		row = t.Toy.db.QueryRow(query, exec.Args()...)
+		row = t.Toy.db.QueryRowContext(t.Context(), query, exec.Args()...)
 	} else {
-		row = t.tx.QueryRow(query, exec.Args()...)
+		row = t.tx.QueryRowContext(t.Context(), query, exec.Args()...)
 	}
 	t.debugPrint(exec, nil)
 	return
@@ -855,26 +1533,27 @@ func (t *ToyBrick) Prepare(query string) (*sql.Stmt, error) {
 	var stmt *sql.Stmt
 	var err error
 	if t.tx == nil {
-		stmt, err = t.Toy.db.Prepare(query)
+		stmt, err = t.Toy.db.PrepareContext(t.Context(), query)
 	} else {
-		stmt, err = t.tx.Prepare(query)
+		stmt, err = t.tx.PrepareContext(t.Context(), query)
 	}
 	t.debugPrint(&DefaultExec{query: query}, err)
 	return stmt, err
 }
 
 func (t *ToyBrick) CountExec() (exec ExecValue) {
+	brick := t.softDeleteScope()
 	exec = t.Toy.Dialect.CountExec(t.Model, t.alias)
 	jExec := t.Toy.Dialect.JoinExec(joinSwap(nil, t))
 	exec = exec.Append(" "+jExec.Source(), jExec.Args()...)
-	cExec := t.ConditionExec()
+	cExec := brick.ConditionExec()
 	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
 
 	return
 }
 
 func (t *ToyBrick) ConditionExec() ExecValue {
-	return t.Toy.Dialect.ConditionExec(t.Search, t.limit, t.offset, t.orderBy.ToColumnList(), t.groupBy.ToColumnList())
+	return t.Toy.Dialect.ConditionExec(t.Search, t.limit, t.offset, t.orderBy.ToColumnList(), t.groupBy.ToColumnList(), t.having)
 }
 
 func (t *ToyBrick) FindExec(columns []Column) ExecValue {
@@ -887,6 +1566,104 @@ func (t *ToyBrick) FindExec(columns []Column) ExecValue {
 	return exec
 }
 
+// use template or use default exec
+func (t *ToyBrick) findOrTemplateExec(columns []Column) (ExecValue, error) {
+	if t.template == nil {
+		return t.FindExec(columns), nil
+	}
+	tempMap := DefaultTemplateExec(t)
+	tempMap["Columns"] = getColumnExec(columns)
+	return t.Toy.Dialect.TemplateExec(*t.template, tempMap)
+}
+
+// the condition value of EXISTS, it have no field and only hold the sub query brick
+type subQueryValue struct {
+	Field
+	value reflect.Value
+	err   error
+}
+
+func (v subQueryValue) Value() reflect.Value {
+	return v.value
+}
+
+func (v subQueryValue) ToColumnAlias(alias string) Field {
+	return v
+}
+
+func (v subQueryValue) ToFieldValue(value reflect.Value) FieldValue {
+	return subQueryValue{value: value, err: v.err}
+}
+
+// the sub query brick can't be the value of BETWEEN/NULL exprs, the invalid one will return error when brick run
+func invalidSubQueryValue(expr SearchExpr, field Field, args []interface{}) (FieldValue, bool) {
+	switch expr {
+	case ExprBetween, ExprNotBetween, ExprNull, ExprNotNull:
+		if len(args) == 1 {
+			if _, ok := args[0].(*ToyBrick); ok {
+				return subQueryValue{Field: field, err: ErrInvalidSubQuery}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// the error of invalid sub query in conditions, include the conditions of sub query brick
+func (t *ToyBrick) searchErr() error {
+	for _, search := range []SearchList{t.Search, t.having} {
+		for _, cell := range search {
+			if cell.Type.IsBranch() {
+				continue
+			}
+			if v, ok := cell.Val.(subQueryValue); ok && v.err != nil {
+				return v.err
+			}
+		}
+	}
+	for _, sub := range t.subQueryBricks() {
+		if err := sub.searchErr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the sub query bricks used as condition value
+func (t *ToyBrick) subQueryBricks() []*ToyBrick {
+	var bricks []*ToyBrick
+	for _, search := range []SearchList{t.Search, t.having} {
+		for _, cell := range search {
+			if cell.Type.IsBranch() {
+				continue
+			}
+			if value := cell.Val.Value(); value.IsValid() && value.CanInterface() {
+				if sub, ok := value.Interface().(*ToyBrick); ok && sub != nil {
+					bricks = append(bricks, sub)
+				}
+			}
+		}
+	}
+	return bricks
+}
+
+// the brick used as condition value, select the ModeSelect fields and filter soft deleted data like Find
+func (t *ToyBrick) subQueryExec() ExecValue {
+	brick := t.softDeleteScope()
+	var fields []Field
+	if len(brick.FieldsSelector[ModeSelect]) > 0 {
+		fields = brick.FieldsSelector[ModeSelect]
+	} else if len(brick.FieldsSelector[ModeDefault]) > 0 {
+		fields = brick.FieldsSelector[ModeDefault]
+	} else {
+		fields = brick.Model.GetSqlFields()
+	}
+	columns := make([]Column, len(fields))
+	for i := range fields {
+		columns[i] = fields[i].ToColumnAlias(brick.alias)
+	}
+	return brick.FindExec(columns)
+}
+
 func (t *ToyBrick) UpdateExec(record ModelRecord) ExecValue {
 	exec := t.Toy.Dialect.UpdateExec(t.Model, t.getFieldValuePairWithRecord(ModeUpdate, record).ToValueList())
 	cExec := t.ConditionExec()
@@ -909,6 +1686,82 @@ func (t *ToyBrick) InsertExec(record ModelRecord) ExecValue {
 	return exec
 }
 
+func (t *ToyBrick) UpsertExec(record ModelRecord) ExecValue {
+	recorders := t.getFieldValuePairWithRecord(ModeInsert, record)
+	conflictFields := t.conflict
+	if len(conflictFields) == 0 {
+		conflictFields = t.Model.GetPrimary()
+	}
+	conflict := make([]Column, len(conflictFields))
+	for i, f := range conflictFields {
+		conflict[i] = f
+	}
+	strategies := t.upsertStrategies
+	// created time need keep when it not set strategy
+	if createdAtField := t.Model.GetCreatedAtField(); createdAtField != nil {
+		if _, ok := strategies[createdAtField.Name()]; !ok {
+			strategies = make(map[string]UpsertStrategy, len(t.upsertStrategies)+1)
+			for k, v := range t.upsertStrategies {
+				strategies[k] = v
+			}
+			strategies[createdAtField.Name()] = UpsertStrategy{Action: UpsertKeep}
+		}
+	}
+	return t.Toy.Dialect.UpsertExec(t.Model, recorders.ToNameValueList(), conflict, strategies)
+}
+
+// query the primary key of upsert row with conflict fields value,
+// it's used by the database which can't get the primary key of update row
+func (t *ToyBrick) conflictPrimaryKey(record ModelRecord) (int64, error) {
+	var search SearchList
+	for _, field := range t.conflict {
+		search = search.Condition(field.ToFieldValue(record.Field(field.Name())), ExprEqual, ExprAnd)
+	}
+	exec := t.Toy.Dialect.FindExec(t.Model, []Column{t.Model.GetOnePrimary()}, "")
+	cExec := t.Toy.Dialect.ConditionExec(search, 0, 0, nil, nil, nil)
+	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
+	var id int64
+	err := t.QueryRow(exec).Scan(&id)
+	return id, err
+}
+
+// insert multiple records in one statement, all of records must have same insert columns
+func (t *ToyBrick) InsertValuesExec(records []ModelRecord) ExecValue {
+	valuesList := make([][]ColumnNameValue, len(records))
+	for i, record := range records {
+		valuesList[i] = t.getFieldValuePairWithRecord(ModeInsert, record).ToNameValueList()
+	}
+	exec := t.Toy.Dialect.InsertValuesExec(t.Model, valuesList)
+	cExec := t.ConditionExec()
+	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
+	return exec
+}
+
+// split records to insert batches, every batch is the records index list
+func (t *ToyBrick) insertBatches(records []ModelRecord) [][]int {
+	valuesList := make([][]ColumnNameValue, len(records))
+	for i, record := range records {
+		valuesList[i] = t.getFieldValuePairWithRecord(ModeInsert, record).ToNameValueList()
+	}
+	return insertBatchGroup(t.Model, valuesList, t.getBatchSize())
+}
+
+// update the versioned record with primary key and previous version condition,
+// the Save of versioned model use it when record is not new
+func (t *ToyBrick) VersionSaveExec(record ModelRecord) ExecValue {
+	versionField := t.Model.GetVersionField()
+	conditions := map[string]interface{}{}
+	for _, field := range t.Model.GetPrimary() {
+		conditions[field.Name()] = record.Field(field.Name()).Interface()
+	}
+	conditions[versionField.Name()] = versionValue(record.Field(versionField.Name())) - 1
+	brick := t.WhereGroup(ExprAnd, conditions).And().Conditions(t.Search)
+	exec := t.Toy.Dialect.UpdateExec(t.Model, brick.getFieldValuePairWithRecord(ModeSave, record).ToValueList())
+	cExec := brick.ConditionExec()
+	exec = exec.Append(" "+cExec.Source(), cExec.Args()...)
+	return exec
+}
+
 func (t *ToyBrick) SaveExec(record ModelRecord) ExecValue {
 	recorders := t.getFieldValuePairWithRecord(ModeSave, record)
 	exec := t.Toy.Dialect.SaveExec(t.Model, recorders.ToNameValueList())
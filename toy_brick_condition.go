@@ -6,6 +6,54 @@
 
 package toyorm
 
+// append search to old search list with AND, return the new search list and own search index
+func andSearch(old SearchList, own []int, search SearchList) (SearchList, []int) {
+	newSearch := make(SearchList, len(old), len(old)+len(search)+2)
+	copy(newSearch, old)
+	newOwnSearch := make([]int, len(own), len(own)+len(search))
+	copy(newOwnSearch, own)
+	// AND have high priority
+	orTail := len(newSearch) != 0 && newSearch[len(newSearch)-1].Type == ExprOr
+	if orTail {
+		newSearch = newSearch[:len(newSearch)-1]
+	}
+	start := len(newSearch)
+	newSearch = append(newSearch, search...)
+	// add join own information
+	for i, s := range newSearch[start:] {
+		if s.Type.IsBranch() == false {
+			newOwnSearch = append(newOwnSearch, start+i)
+		}
+	}
+	if len(old) != 0 {
+		newSearch = append(newSearch, NewSearchBranch(ExprAnd))
+		if orTail {
+			newSearch = append(newSearch, NewSearchBranch(ExprOr))
+		}
+	}
+	return newSearch, newOwnSearch
+}
+
+// append search to old search list with OR, return the new search list and own search index
+func orSearch(old SearchList, own []int, search SearchList) (SearchList, []int) {
+	newSearch := make(SearchList, len(old), len(old)+len(search)+1)
+	copy(newSearch, old)
+	newOwnSearch := make([]int, len(own), len(own)+len(search))
+	copy(newOwnSearch, own)
+	start := len(newSearch)
+	newSearch = append(newSearch, search...)
+	// add join own information
+	for i, s := range newSearch[start:] {
+		if s.Type.IsBranch() == false {
+			newOwnSearch = append(newOwnSearch, start+i)
+		}
+	}
+	if len(old) != 0 {
+		newSearch = append(newSearch, NewSearchBranch(ExprOr))
+	}
+	return newSearch, newOwnSearch
+}
+
 type ToyBrickAnd struct {
 	Brick *ToyBrick
 }
@@ -25,47 +73,8 @@ func (t ToyBrickAnd) Conditions(search SearchList) *ToyBrick {
 		if len(search) == 0 {
 			return t
 		}
-		newSearch := make(SearchList, len(t.Search), len(t.Search)+len(search))
-		copy(newSearch, t.Search)
-		newOwnSearch := make([]int, len(t.OwnSearch), len(t.OwnSearch)+len(search))
-		copy(newOwnSearch, t.OwnSearch)
-		if len(t.Search) != 0 {
-			// AND have high priority
-			if newSearch[len(newSearch)-1].Type == ExprOr {
-				start := len(newSearch) - 1
-				newSearch = newSearch[:start]
-				newSearch = append(newSearch, search...)
-				// add join own information
-				for i, s := range newSearch[start:] {
-					if s.Type.IsBranch() == false {
-						newOwnSearch = append(newOwnSearch, start+i)
-					}
-				}
-				newSearch = append(newSearch, NewSearchBranch(ExprAnd), NewSearchBranch(ExprOr))
-			} else {
-				start := len(newSearch)
-				newSearch = append(newSearch, search...)
-				// add join own information
-				for i, s := range newSearch[start:] {
-					if s.Type.IsBranch() == false {
-						newOwnSearch = append(newOwnSearch, start+i)
-					}
-				}
-				newSearch = append(newSearch, NewSearchBranch(ExprAnd))
-			}
-		} else {
-			start := len(newSearch)
-			newSearch = append(newSearch, search...)
-			// add join own information
-			for i, s := range newSearch[start:] {
-				if s.Type.IsBranch() == false {
-					newOwnSearch = append(newOwnSearch, start+i)
-				}
-			}
-		}
 		newt := *t
-		newt.Search = newSearch
-		newt.OwnSearch = newOwnSearch
+		newt.Search, newt.OwnSearch = andSearch(t.Search, t.OwnSearch, search)
 		return &newt
 	})
 
@@ -90,33 +99,60 @@ func (t ToyBrickOr) Conditions(search SearchList) *ToyBrick {
 		if len(search) == 0 {
 			return t
 		}
-		newSearch := make(SearchList, len(t.Search), len(t.Search)+len(search))
-		copy(newSearch, t.Search)
-		newOwnSearch := make([]int, len(t.OwnSearch), len(t.OwnSearch)+len(search))
-		copy(newOwnSearch, t.OwnSearch)
-		if len(newSearch) != 0 {
-			start := len(newSearch)
-			newSearch = append(newSearch, search...)
-			// add join own information
-			for i, s := range newSearch[start:] {
-				if s.Type.IsBranch() == false {
-					newOwnSearch = append(newOwnSearch, start+i)
-				}
-			}
-			newSearch = append(newSearch, NewSearchBranch(ExprOr))
-		} else {
-			start := len(newSearch)
-			newSearch = append(newSearch, search...)
-			// add join own information
-			for i, s := range newSearch[start:] {
-				if s.Type.IsBranch() == false {
-					newOwnSearch = append(newOwnSearch, start+i)
-				}
-			}
+		newt := *t
+		newt.Search, newt.OwnSearch = orSearch(t.Search, t.OwnSearch, search)
+		return &newt
+	})
+}
+
+// HAVING condition builder, it's same as ToyBrickAnd but work on having search list
+type ToyBrickHavingAnd struct {
+	Brick *ToyBrick
+}
+
+func (t ToyBrickHavingAnd) Condition(expr SearchExpr, key FieldSelection, v ...interface{}) *ToyBrick {
+	search := t.Brick.condition(expr, key, v...)
+	return t.Conditions(search)
+}
+
+func (t ToyBrickHavingAnd) ConditionGroup(expr SearchExpr, group interface{}) *ToyBrick {
+	search := t.Brick.conditionGroup(expr, group)
+	return t.Conditions(search)
+}
+
+func (t ToyBrickHavingAnd) Conditions(search SearchList) *ToyBrick {
+	return t.Brick.Scope(func(t *ToyBrick) *ToyBrick {
+		if len(search) == 0 {
+			return t
+		}
+		newt := *t
+		newt.having, _ = andSearch(t.having, nil, search)
+		return &newt
+	})
+}
+
+// HAVING condition builder, it's same as ToyBrickOr but work on having search list
+type ToyBrickHavingOr struct {
+	Brick *ToyBrick
+}
+
+func (t ToyBrickHavingOr) Condition(expr SearchExpr, key FieldSelection, v ...interface{}) *ToyBrick {
+	search := t.Brick.condition(expr, key, v...)
+	return t.Conditions(search)
+}
+
+func (t ToyBrickHavingOr) ConditionGroup(expr SearchExpr, group interface{}) *ToyBrick {
+	search := t.Brick.conditionGroup(expr, group)
+	return t.Conditions(search)
+}
+
+func (t ToyBrickHavingOr) Conditions(search SearchList) *ToyBrick {
+	return t.Brick.Scope(func(t *ToyBrick) *ToyBrick {
+		if len(search) == 0 {
+			return t
 		}
 		newt := *t
-		newt.Search = newSearch
-		newt.OwnSearch = newOwnSearch
+		newt.having, _ = orSearch(t.having, nil, search)
 		return &newt
 	})
 }
@@ -7,12 +7,19 @@
 package toyorm
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 	. "unsafe"
@@ -29,7 +36,9 @@ func TestCreateTable(t *testing.T) {
 		SqlTypeTable{},
 	} {
 		// start a session
-		brick := TestDB.Model(tab).Begin()
+		brick := TestDB.Model(tab)
+		brick, err = brick.Begin()
+		require.NoError(t, err)
 		hastable, err = brick.HasTable()
 		assert.Nil(t, err)
 		t.Logf("table %s exist:%v\n", brick.Model.Name, hastable)
@@ -2891,3 +2900,1866 @@ func TestTempField(t *testing.T) {
 		}
 	}
 }
+
+func TestBrickWithContext(t *testing.T) {
+	type ctxKey struct{}
+	brick := TestDB.Model(&TestPreloadTable{}).
+		Preload(Offsetof(TestPreloadTable{}.BelongTo)).Enter().
+		Preload(Offsetof(TestPreloadTable{}.OneToMany)).Enter()
+	valCtx := context.WithValue(context.Background(), ctxKey{}, "toyorm")
+	ctxBrick := brick.WithContext(valCtx)
+	// preload brick need inherit context
+	for name, preloadBrick := range ctxBrick.MapPreloadBrick {
+		assert.Equal(t, preloadBrick.Context(), valCtx, name)
+	}
+	// handler context forward brick context
+	handlerCtx := NewContext(nil, ctxBrick, nil)
+	assert.Equal(t, handlerCtx.Value(ctxKey{}), "toyorm")
+	assert.Nil(t, handlerCtx.Err())
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var data []TestPreloadTable
+	_, err := brick.WithContext(cancelCtx).Find(&data)
+	assert.Equal(t, err, context.Canceled)
+	assert.Equal(t, NewContext(nil, brick.WithContext(cancelCtx), nil).Err(), context.Canceled)
+}
+
+func TestBatchInsert(t *testing.T) {
+	type TestBatchInsertTable struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Data string
+	}
+	brick := TestDB.Model(&TestBatchInsertTable{})
+	createTableUnit(brick)(t)
+
+	var data []TestBatchInsertTable
+	for i := 0; i < 25; i++ {
+		data = append(data, TestBatchInsertTable{Data: fmt.Sprintf("batch data %d", i)})
+	}
+	// record with primary key cannot insert with auto increment records in same statement
+	data[20].ID = 1000
+	result, err := brick.BatchSize(10).Insert(&data)
+	resultProcessor(result, err)(t)
+	t.Logf("report:\n%s\n", result.Report())
+	// [0,10) [10,20) [20] [21,25)
+	require.Equal(t, len(result.ActionFlow), 4)
+	for i := range data {
+		require.Equal(t, len(result.RecordsActions[i]), 1)
+	}
+	assert.Equal(t, result.RecordsActions[0][0], 0)
+	assert.Equal(t, result.RecordsActions[19][0], 1)
+	assert.Equal(t, result.RecordsActions[20][0], 2)
+	assert.Equal(t, result.RecordsActions[24][0], 3)
+	assert.Equal(t, result.ActionFlow[0].(ExecAction).AffectData(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	var scanData []TestBatchInsertTable
+	result, err = brick.OrderBy(Offsetof(TestBatchInsertTable{}.ID)).Find(&scanData)
+	resultProcessor(result, err)(t)
+	require.Equal(t, len(scanData), len(data))
+	dataMap := map[uint32]string{}
+	for _, d := range data {
+		require.NotZero(t, d.ID)
+		dataMap[d.ID] = d.Data
+	}
+	for _, d := range scanData {
+		assert.Equal(t, dataMap[d.ID], d.Data)
+	}
+}
+
+func TestNestedTransaction(t *testing.T) {
+	type TestNestedTransactionTable struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Data string
+	}
+	brick := TestDB.Model(&TestNestedTransactionTable{})
+	createTableUnit(brick)(t)
+
+	err := TestDB.Transaction(func(tx *ToyBrick) error {
+		txBrick := tx.SwitchModel(&TestNestedTransactionTable{})
+		result, err := txBrick.Insert(&TestNestedTransactionTable{Data: "outer"})
+		resultProcessor(result, err)(t)
+		// rollback to save point
+		subBrick, err := txBrick.Begin()
+		require.NoError(t, err)
+		result, err = subBrick.Insert(&TestNestedTransactionTable{Data: "rollback"})
+		resultProcessor(result, err)(t)
+		require.NoError(t, subBrick.Rollback())
+		// release save point
+		err = txBrick.Transaction(func(subBrick *ToyBrick) error {
+			result, err := subBrick.Insert(&TestNestedTransactionTable{Data: "inner"})
+			if err != nil {
+				return err
+			}
+			return result.Err()
+		})
+		require.NoError(t, err)
+		// nested transaction error only rollback to save point
+		err = txBrick.Transaction(func(subBrick *ToyBrick) error {
+			result, err := subBrick.Insert(&TestNestedTransactionTable{Data: "inner error"})
+			resultProcessor(result, err)(t)
+			return errors.New("inner error")
+		})
+		require.Error(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// error and panic will rollback whole transaction
+	err = TestDB.Transaction(func(tx *ToyBrick) error {
+		result, err := tx.SwitchModel(&TestNestedTransactionTable{}).Insert(&TestNestedTransactionTable{Data: "error"})
+		resultProcessor(result, err)(t)
+		return errors.New("outer error")
+	})
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		TestDB.Transaction(func(tx *ToyBrick) error {
+			result, err := tx.SwitchModel(&TestNestedTransactionTable{}).Insert(&TestNestedTransactionTable{Data: "panic"})
+			resultProcessor(result, err)(t)
+			panic("outer panic")
+		})
+	})
+
+	// rollback failure return the fn error with rollback error
+	fnErr := errors.New("fn error")
+	err = TestDB.Transaction(func(tx *ToyBrick) error {
+		require.NoError(t, tx.Rollback())
+		return fnErr
+	})
+	if assert.IsType(t, ErrTransactionRollback{}, err) {
+		assert.Equal(t, fnErr, err.(ErrTransactionRollback).Err)
+		assert.Equal(t, sql.ErrTxDone, err.(ErrTransactionRollback).RollbackErr)
+	}
+	err = TestDB.Transaction(func(tx *ToyBrick) error {
+		// the save point is released, rollback to it will failure
+		err := tx.Transaction(func(subBrick *ToyBrick) error {
+			_, err := subBrick.Exec(subBrick.Toy.Dialect.ReleaseSavePointExec(subBrick.savePointName()))
+			require.NoError(t, err)
+			return fnErr
+		})
+		if assert.IsType(t, ErrTransactionRollback{}, err) {
+			assert.Equal(t, fnErr, err.(ErrTransactionRollback).Err)
+			assert.Error(t, err.(ErrTransactionRollback).RollbackErr)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	func() {
+		defer func() {
+			r := recover()
+			assert.IsType(t, ErrTransactionRollback{}, r)
+		}()
+		TestDB.Transaction(func(tx *ToyBrick) error {
+			require.NoError(t, tx.Rollback())
+			panic("panic after rollback")
+		})
+	}()
+
+	var data []TestNestedTransactionTable
+	result, err := brick.OrderBy(Offsetof(TestNestedTransactionTable{}.ID)).Find(&data)
+	resultProcessor(result, err)(t)
+	var dataList []string
+	for _, d := range data {
+		dataList = append(dataList, d.Data)
+	}
+	assert.Equal(t, dataList, []string{"outer", "inner"})
+}
+
+func TestBeginTxOptions(t *testing.T) {
+	type TestBeginTxTable struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Data string
+	}
+	brick := TestDB.Model(&TestBeginTxTable{})
+	createTableUnit(brick)(t)
+	result, err := brick.Insert([]TestBeginTxTable{{Data: "tx data 1"}, {Data: "tx data 2"}})
+	resultProcessor(result, err)(t)
+
+	isolation := sql.LevelRepeatableRead
+	buff := bytes.Buffer{}
+	oldLogger := brick.Toy.Logger
+	brick.Toy.Logger = &buff
+	defer func() { brick.Toy.Logger = oldLogger }()
+
+	txBrick, err := brick.Debug().BeginTx(&sql.TxOptions{Isolation: isolation, ReadOnly: true})
+	require.NoError(t, err)
+	var data []TestBeginTxTable
+	result, err = txBrick.Find(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, len(data), 2)
+	count, err := txBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, count, 2)
+	require.NoError(t, txBrick.Commit())
+
+	t.Log(buff.String())
+	assert.Contains(t, buff.String(), "isolation:"+isolation.String()+" read only")
+}
+
+func TestPreloadBeginTx(t *testing.T) {
+	type TestPreloadTxChild struct {
+		ID                   uint32 `toyorm:"primary key;auto_increment"`
+		Data                 string
+		TestPreloadTxTableID uint32 `toyorm:"index"`
+	}
+	type TestPreloadTxTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Data     string
+		Children []TestPreloadTxChild
+	}
+	brick := TestDB.Model(&TestPreloadTxTable{}).Preload(Offsetof(TestPreloadTxTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+
+	txBrick, err := brick.BeginTx(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	require.NoError(t, err)
+	// the preload brick created before BeginTx also in transaction
+	preloadBrick := txBrick.MapPreloadBrick["Children"]
+	assert.True(t, preloadBrick.tx == txBrick.tx)
+	assert.True(t, preloadBrick.txWrites == txBrick.txWrites)
+	assert.Equal(t, txBrick.txOptions, preloadBrick.txOptions)
+
+	result, err := txBrick.Insert(&TestPreloadTxTable{
+		Data:     "tx data",
+		Children: []TestPreloadTxChild{{Data: "tx child 1"}, {Data: "tx child 2"}},
+	})
+	resultProcessor(result, err)(t)
+	var data []TestPreloadTxTable
+	result, err = txBrick.Find(&data)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(data))
+	assert.Equal(t, 2, len(data[0].Children))
+
+	// save point brick also push it status to preload brick
+	spBrick, err := txBrick.Begin()
+	require.NoError(t, err)
+	assert.Equal(t, 1, spBrick.MapPreloadBrick["Children"].txDepth)
+	require.NoError(t, spBrick.Rollback())
+	require.NoError(t, txBrick.Rollback())
+
+	count, err := TestDB.Model(&TestPreloadTxChild{}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestUpsert(t *testing.T) {
+	type TestUpsertTable struct {
+		ID        uint32 `toyorm:"primary key;auto_increment"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		Name      string `toyorm:"unique index"`
+		Count     int
+		Data      string
+		Remark    string
+	}
+	brick := TestDB.Model(&TestUpsertTable{})
+	createTableUnit(brick)(t)
+
+	oldData := TestUpsertTable{Name: "upsert", Count: 1, Data: "old data", Remark: "old remark"}
+	result, err := brick.Insert(&oldData)
+	resultProcessor(result, err)(t)
+
+	upsertBrick := brick.Conflict(Offsetof(TestUpsertTable{}.Name)).
+		ConflictKeep(Offsetof(TestUpsertTable{}.Remark)).
+		ConflictExpr(Offsetof(TestUpsertTable{}.Count), "test_upsert_table.count + EXCLUDED.count")
+	newData := TestUpsertTable{Name: "upsert", Count: 2, Data: "new data", Remark: "new remark"}
+	result, err = upsertBrick.Upsert(&newData)
+	resultProcessor(result, err)(t)
+	t.Logf("report:\n%s\n", result.Report())
+	switch TestDriver {
+	case "mysql":
+		assert.Equal(t, result.ActionFlow[0].(ExecAction).Exec.Query(), "INSERT INTO `test_upsert_table`(created_at,updated_at,name,count,data,remark) VALUES(?,?,?,?,?,?) ON DUPLICATE KEY UPDATE updated_at = VALUES(updated_at),count = test_upsert_table.count + VALUES(count),data = VALUES(data),id = LAST_INSERT_ID(id)")
+	case "postgres":
+		assert.Equal(t, result.ActionFlow[0].(ExecAction).Exec.Query(), `INSERT INTO "test_upsert_table"(created_at,updated_at,name,count,data,remark) VALUES($1,$2,$3,$4,$5,$6) ON CONFLICT(name) DO UPDATE SET updated_at = EXCLUDED.updated_at,count = test_upsert_table.count + EXCLUDED.count,data = EXCLUDED.data RETURNING id`)
+	case "sqlite3":
+		assert.Equal(t, result.ActionFlow[0].(ExecAction).Exec.Query(), "INSERT INTO `test_upsert_table`(created_at,updated_at,name,count,data,remark) VALUES(?,?,?,?,?,?) ON CONFLICT(name) DO UPDATE SET updated_at = EXCLUDED.updated_at,count = test_upsert_table.count + EXCLUDED.count,data = EXCLUDED.data")
+	}
+
+	otherData := TestUpsertTable{Name: "upsert other", Count: 3, Data: "other data"}
+	result, err = upsertBrick.Upsert(&otherData)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, newData.ID, oldData.ID)
+	assert.NotZero(t, otherData.ID)
+	assert.NotEqual(t, otherData.ID, oldData.ID)
+
+	// the conflict is primary key, zero primary key record always be inserted
+	primaryData := TestUpsertTable{Name: "upsert primary", Count: 4}
+	result, err = brick.Upsert(&primaryData)
+	resultProcessor(result, err)(t)
+	assert.NotZero(t, primaryData.ID)
+	assert.NotEqual(t, primaryData.ID, otherData.ID)
+
+	var data []TestUpsertTable
+	result, err = brick.OrderBy(Offsetof(TestUpsertTable{}.ID)).Find(&data)
+	resultProcessor(result, err)(t)
+	require.Equal(t, len(data), 3)
+	assert.Equal(t, data[0].ID, oldData.ID)
+	assert.Equal(t, data[0].Count, 3)
+	assert.Equal(t, data[0].Data, "new data")
+	assert.Equal(t, data[0].Remark, "old remark")
+	assert.Equal(t, data[0].CreatedAt.Unix(), oldData.CreatedAt.Unix())
+	assert.Equal(t, data[1].Name, "upsert other")
+	assert.Equal(t, data[1].Count, 3)
+
+	// conflict fields must be primary key or unique index
+	_, err = brick.Conflict(Offsetof(TestUpsertTable{}.Data)).Upsert(&TestUpsertTable{Name: "invalid"})
+	assert.Equal(t, ErrInvalidConflictFields{brick.Model.Name, []string{"Data"}}, err)
+}
+
+func TestMigrate(t *testing.T) {
+	oldBrick := TestDB.Model(&TestMigrateOldTable{})
+	createTableUnit(oldBrick)(t)
+	oldData := TestMigrateOldTable{Name: "old", Age: 18}
+	result, err := oldBrick.Insert(&oldData)
+	resultProcessor(result, err)(t)
+
+	brick := TestDB.Model(&TestMigrateTable{})
+	plan, err := brick.MigratePlan()
+	require.NoError(t, err)
+	var queries []string
+	for _, exec := range plan {
+		queries = append(queries, exec.Query())
+	}
+	switch TestDriver {
+	case "mysql":
+		assert.Equal(t, []string{
+			"DROP INDEX idx_migrate_name ON `test_migrate_table`",
+			"ALTER TABLE `test_migrate_table` ADD COLUMN email VARCHAR(255) DEFAULT ''",
+			"ALTER TABLE `test_migrate_table` MODIFY COLUMN age BIGINT",
+			"CREATE INDEX idx_migrate_email ON `test_migrate_table`(email)",
+			"CREATE UNIQUE INDEX udx_migrate_name ON `test_migrate_table`(name)",
+		}, queries)
+	case "postgres":
+		assert.Equal(t, []string{
+			`DROP INDEX idx_migrate_name`,
+			`ALTER TABLE "test_migrate_table" ADD COLUMN email VARCHAR(255) DEFAULT ''`,
+			`ALTER TABLE "test_migrate_table" ALTER COLUMN age TYPE BIGINT USING age::BIGINT, ALTER COLUMN age DROP NOT NULL, ALTER COLUMN age DROP DEFAULT`,
+			`CREATE INDEX idx_migrate_email ON "test_migrate_table"(email)`,
+			`CREATE UNIQUE INDEX udx_migrate_name ON "test_migrate_table"(name)`,
+		}, queries)
+	case "sqlite3":
+		// sqlite3 can't modify column type
+		assert.Equal(t, []string{
+			"DROP INDEX idx_migrate_name",
+			"ALTER TABLE `test_migrate_table` ADD COLUMN email VARCHAR(255) DEFAULT ''",
+			"CREATE INDEX idx_migrate_email ON `test_migrate_table`(email)",
+			"CREATE UNIQUE INDEX udx_migrate_name ON `test_migrate_table`(name)",
+		}, queries)
+	}
+
+	result, err = brick.Migrate()
+	resultProcessor(result, err)(t)
+	t.Logf("report:\n%s\n", result.Report())
+
+	// table is same as model now
+	plan, err = brick.MigratePlan()
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	newData := TestMigrateTable{Name: "new", Age: 20, Email: "new@example.com"}
+	result, err = brick.Insert(&newData)
+	resultProcessor(result, err)(t)
+
+	var list []TestMigrateTable
+	result, err = brick.OrderBy(Offsetof(TestMigrateTable{}.ID)).Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, TestMigrateTable{ID: oldData.ID, Name: "old", Age: 18}, list[0])
+	assert.Equal(t, newData, list[1])
+
+	// create table when it not exist
+	result, err = brick.DropTable()
+	resultProcessor(result, err)(t)
+	plan, err = brick.MigratePlan()
+	require.NoError(t, err)
+	require.NotEmpty(t, plan)
+	assert.True(t, strings.HasPrefix(plan[0].Query(), "CREATE TABLE"))
+	result, err = brick.Migrate()
+	resultProcessor(result, err)(t)
+	hasTable, err := brick.HasTable()
+	require.NoError(t, err)
+	assert.True(t, hasTable)
+
+	// the plan of preload is ordered by field name
+	type TestMigratePlanSubA struct {
+		ID                    uint32 `toyorm:"primary key;auto_increment"`
+		TestMigratePlanMainID uint32
+	}
+	type TestMigratePlanSubB struct {
+		ID                    uint32 `toyorm:"primary key;auto_increment"`
+		TestMigratePlanMainID uint32
+	}
+	type TestMigratePlanSubC struct {
+		ID                    uint32 `toyorm:"primary key;auto_increment"`
+		TestMigratePlanMainID uint32
+	}
+	type TestMigratePlanMain struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		SubC []TestMigratePlanSubC
+		SubA []TestMigratePlanSubA
+		SubB []TestMigratePlanSubB
+	}
+	planBrick := TestDB.Model(&TestMigratePlanMain{}).
+		Preload(Offsetof(TestMigratePlanMain{}.SubC)).Enter().
+		Preload(Offsetof(TestMigratePlanMain{}.SubA)).Enter().
+		Preload(Offsetof(TestMigratePlanMain{}.SubB)).Enter()
+	result, err = planBrick.DropTableIfExist()
+	resultProcessor(result, err)(t)
+	for i := 0; i < 5; i++ {
+		plan, err = planBrick.MigratePlan()
+		require.NoError(t, err)
+		var tables []string
+		for _, exec := range plan {
+			if strings.HasPrefix(exec.Query(), "CREATE TABLE") {
+				tables = append(tables, strings.Fields(exec.Query())[2])
+			}
+		}
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, []string{
+				"`test_migrate_plan_main`", "`test_migrate_plan_sub_a`", "`test_migrate_plan_sub_b`", "`test_migrate_plan_sub_c`",
+			}, tables)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	type TestMigratorUser struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Name string
+	}
+	for _, v := range []interface{}{&migrationRecord{}, &TestMigratorUser{}} {
+		result, err := TestDB.Model(v).DropTableIfExist()
+		resultProcessor(result, err)(t)
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2_add_user.up.sql"), []byte(
+		"-- insert users;\nINSERT INTO test_migrator_user(name) VALUES('a;b');\n/* the second;\nuser */INSERT INTO test_migrator_user(name) VALUES('c');\n",
+	), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2_add_user.down.sql"), []byte("DELETE FROM test_migrator_user;"), 0644))
+	sqlMigrations, err := LoadSqlMigrations(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sqlMigrations))
+
+	migrator := NewMigrator(TestDB, Migration{
+		Version: 1,
+		Name:    "create_user",
+		Up: func(brick *ToyBrick) error {
+			result, err := brick.SwitchModel(&TestMigratorUser{}).CreateTable()
+			if err != nil {
+				return err
+			}
+			return result.Err()
+		},
+		Down: func(brick *ToyBrick) error {
+			result, err := brick.SwitchModel(&TestMigratorUser{}).DropTable()
+			if err != nil {
+				return err
+			}
+			return result.Err()
+		},
+	}).Add(sqlMigrations...)
+	brick := TestDB.Model(&TestMigratorUser{})
+	appliedList := func() []bool {
+		statusList, err := migrator.Status()
+		require.NoError(t, err)
+		var list []bool
+		for _, status := range statusList {
+			list = append(list, status.Applied)
+		}
+		return list
+	}
+	assert.Equal(t, []bool{false, false}, appliedList())
+
+	require.NoError(t, migrator.Up())
+	assert.Equal(t, []bool{true, true}, appliedList())
+	var users []TestMigratorUser
+	result, err := brick.OrderBy(Offsetof(TestMigratorUser{}.ID)).Find(&users)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(users))
+	assert.Equal(t, "a;b", users[0].Name)
+	assert.Equal(t, "c", users[1].Name)
+
+	require.NoError(t, migrator.Down())
+	assert.Equal(t, []bool{true, false}, appliedList())
+	count, err := brick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	require.NoError(t, migrator.To(0))
+	assert.Equal(t, []bool{false, false}, appliedList())
+	hasTable, err := brick.HasTable()
+	require.NoError(t, err)
+	assert.False(t, hasTable)
+
+	require.NoError(t, migrator.To(2))
+	assert.Equal(t, []bool{true, true}, appliedList())
+	assert.Equal(t, ErrMigrationVersionNotFound{5}, migrator.To(5))
+
+	migrator.Add(Migration{
+		Version: 3,
+		Name:    "failure",
+		Up: func(brick *ToyBrick) error {
+			result, err := brick.SwitchModel(&TestMigratorUser{}).Insert(&TestMigratorUser{Name: "failure"})
+			if err != nil {
+				return err
+			}
+			if err := result.Err(); err != nil {
+				return err
+			}
+			return errors.New("migrate failure")
+		},
+	})
+	assert.EqualError(t, migrator.Up(), "migrate failure")
+	assert.Equal(t, []bool{true, true, false}, appliedList())
+	// mysql can't rollback in migration
+	if TestDriver != "mysql" {
+		count, err = brick.Count()
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	}
+	assert.Panics(t, func() {
+		migrator.Add(Migration{Version: 3, Name: "repeat"})
+	})
+}
+
+func TestSubQuery(t *testing.T) {
+	type TestSubQueryUser struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Name string
+	}
+	type TestSubQueryBlog struct {
+		ID     uint32 `toyorm:"primary key;auto_increment"`
+		UserID uint32
+		Title  string
+	}
+	userBrick := TestDB.Model(&TestSubQueryUser{})
+	blogBrick := TestDB.Model(&TestSubQueryBlog{})
+	createTableUnit(userBrick)(t)
+	createTableUnit(blogBrick)(t)
+	users := []TestSubQueryUser{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	result, err := userBrick.Insert(&users)
+	resultProcessor(result, err)(t)
+	blogs := []TestSubQueryBlog{
+		{UserID: users[0].ID, Title: "go tutorial"},
+		{UserID: users[0].ID, Title: "python tutorial"},
+		{UserID: users[2].ID, Title: "go orm"},
+	}
+	result, err = blogBrick.Insert(&blogs)
+	resultProcessor(result, err)(t)
+
+	// IN sub query
+	{
+		sub := blogBrick.Where(ExprLike, Offsetof(TestSubQueryBlog{}.Title), "go%").
+			BindFields(ModeSelect, Offsetof(TestSubQueryBlog{}.UserID))
+		var list []TestSubQueryUser
+		result, err = userBrick.Where(ExprNotEqual, Offsetof(TestSubQueryUser{}.Name), "c").
+			And().Condition(ExprIn, Offsetof(TestSubQueryUser{}.ID), sub).
+			Find(&list)
+		resultProcessor(result, err)(t)
+		exec := result.ActionFlow[0].(QueryAction).Exec
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, "SELECT id,name FROM `test_sub_query_user`   WHERE name <> ? AND id IN (SELECT user_id FROM `test_sub_query_blog`   WHERE title LIKE ?)", exec.Query())
+		case "postgres":
+			assert.Equal(t, `SELECT id,name FROM "test_sub_query_user"   WHERE name <> $1 AND id IN (SELECT user_id FROM "test_sub_query_blog"   WHERE title LIKE $2)`, exec.Query())
+		}
+		assert.Equal(t, []interface{}{"c", "go%"}, exec.Args())
+		require.Equal(t, 1, len(list))
+		assert.Equal(t, "a", list[0].Name)
+	}
+	// EXISTS with correlated column
+	{
+		userAlias := userBrick.Alias("u")
+		sub := blogBrick.Alias("b").Where(ExprEqual, Offsetof(TestSubQueryBlog{}.UserID),
+			userAlias.Model.GetFieldWithName("ID").ToColumnAlias("u"),
+		).BindFields(ModeSelect, Offsetof(TestSubQueryBlog{}.ID))
+		var list []TestSubQueryUser
+		result, err = userAlias.Where(ExprExists, sub).OrderBy(Offsetof(TestSubQueryUser{}.ID)).Find(&list)
+		resultProcessor(result, err)(t)
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, "SELECT u.id,u.name FROM `test_sub_query_user` as `u`   WHERE EXISTS (SELECT b.id FROM `test_sub_query_blog` as `b`   WHERE b.user_id = u.id) ORDER BY u.id", result.ActionFlow[0].(QueryAction).Exec.Query())
+		}
+		require.Equal(t, 2, len(list))
+		assert.Equal(t, "a", list[0].Name)
+		assert.Equal(t, "c", list[1].Name)
+
+		var notExistsList []TestSubQueryUser
+		result, err = userAlias.Where(ExprNotExists, sub).Find(&notExistsList)
+		resultProcessor(result, err)(t)
+		require.Equal(t, 1, len(notExistsList))
+		assert.Equal(t, "b", notExistsList[0].Name)
+	}
+	// EXISTS in model without primary key
+	{
+		type TestSubQueryLog struct {
+			UserID uint32
+			Action string
+		}
+		logBrick := TestDB.Model(&TestSubQueryLog{}).Alias("l")
+		sub := blogBrick.Where(ExprEqual, Offsetof(TestSubQueryBlog{}.UserID),
+			logBrick.Model.GetFieldWithName("UserID").ToColumnAlias("l"),
+		).BindFields(ModeSelect, Offsetof(TestSubQueryBlog{}.ID))
+		exec := logBrick.Where(ExprExists, sub).ConditionExec()
+		assert.Contains(t, exec.Query(), "WHERE EXISTS (SELECT id FROM")
+	}
+	// invalid sub query return error
+	{
+		var list []TestSubQueryUser
+		_, err := userBrick.Where(ExprExists, Offsetof(TestSubQueryUser{}.ID)).Find(&list)
+		assert.Equal(t, ErrInvalidSubQuery, err)
+		_, err = userBrick.Where(ExprIn, Offsetof(TestSubQueryUser{}.ID),
+			blogBrick.Where(ExprNotExists, "blog").BindFields(ModeSelect, Offsetof(TestSubQueryBlog{}.UserID)),
+		).Count()
+		assert.Equal(t, ErrInvalidSubQuery, err)
+		subBlog := blogBrick.BindFields(ModeSelect, Offsetof(TestSubQueryBlog{}.UserID))
+		for _, expr := range []SearchExpr{ExprBetween, ExprNotBetween, ExprNull, ExprNotNull} {
+			_, err = userBrick.Where(expr, Offsetof(TestSubQueryUser{}.ID), subBlog).Find(&list)
+			assert.Equal(t, ErrInvalidSubQuery, err)
+		}
+		_, err = userBrick.Where(ExprNull, Offsetof(TestSubQueryUser{}.Name), subBlog).Count()
+		assert.Equal(t, ErrInvalidSubQuery, err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	type TestAggregateTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Category string
+		Price    float64
+		Stock    int
+	}
+	brick := TestDB.Model(&TestAggregateTable{})
+	createTableUnit(brick)(t)
+	data := []TestAggregateTable{
+		{Category: "fruit", Price: 1.5, Stock: 10},
+		{Category: "fruit", Price: 2.5, Stock: 20},
+		{Category: "meat", Price: 10, Stock: 5},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var sum int
+	require.NoError(t, brick.Sum(Offsetof(TestAggregateTable{}.Stock), &sum))
+	assert.Equal(t, 35, sum)
+	var avg float64
+	require.NoError(t, brick.Where(ExprEqual, Offsetof(TestAggregateTable{}.Category), "fruit").
+		Avg(Offsetof(TestAggregateTable{}.Price), &avg))
+	assert.Equal(t, 2.0, avg)
+	var min, max float64
+	require.NoError(t, brick.Min(Offsetof(TestAggregateTable{}.Price), &min))
+	require.NoError(t, brick.Max(Offsetof(TestAggregateTable{}.Price), &max))
+	assert.Equal(t, 1.5, min)
+	assert.Equal(t, 10.0, max)
+	// no data match
+	var nullSum sql.NullInt64
+	require.NoError(t, brick.Where(ExprEqual, Offsetof(TestAggregateTable{}.Category), "none").
+		Sum(Offsetof(TestAggregateTable{}.Stock), &nullSum))
+	assert.False(t, nullSum.Valid)
+
+	groupBrick := brick.GroupBy(Offsetof(TestAggregateTable{}.Category)).OrderBy(Offsetof(TestAggregateTable{}.Category))
+	aggregates := []Aggregate{
+		{AggCount, nil, "count"},
+		{AggSum, Offsetof(TestAggregateTable{}.Stock), "total_stock"},
+		{AggMax, Offsetof(TestAggregateTable{}.Price), "max_price"},
+	}
+	switch TestDriver {
+	case "mysql", "sqlite3":
+		assert.Equal(t, "SELECT category,COUNT(*) AS count,SUM(stock) AS total_stock,MAX(price) AS max_price FROM `test_aggregate_table`   GROUP BY category ORDER BY category", groupBrick.AggregateExec(aggregates...).Query())
+	case "postgres":
+		assert.Equal(t, `SELECT category,COUNT(*) AS count,SUM(stock) AS total_stock,MAX(price) AS max_price FROM "test_aggregate_table"   GROUP BY category ORDER BY category`, groupBrick.AggregateExec(aggregates...).Query())
+	}
+	type CategoryStat struct {
+		Category   string
+		Count      int
+		TotalStock int
+		MaxPrice   float64
+	}
+	var stats []CategoryStat
+	require.NoError(t, groupBrick.Aggregate(&stats, aggregates...))
+	assert.Equal(t, []CategoryStat{
+		{"fruit", 2, 30, 2.5},
+		{"meat", 1, 5, 10},
+	}, stats)
+
+	var mapStats []map[string]interface{}
+	require.NoError(t, groupBrick.Aggregate(&mapStats, aggregates[0]))
+	require.Equal(t, 2, len(mapStats))
+	assert.Equal(t, "fruit", mapStats[0]["category"])
+	assert.EqualValues(t, 2, mapStats[0]["count"])
+}
+
+func TestAggregateSoftDelete(t *testing.T) {
+	type TestAggregateSoftDeleteTable struct {
+		ID        uint32 `toyorm:"primary key;auto_increment"`
+		Category  string
+		Stock     int
+		DeletedAt *time.Time
+	}
+	brick := TestDB.Model(&TestAggregateSoftDeleteTable{})
+	createTableUnit(brick)(t)
+	data := []TestAggregateSoftDeleteTable{
+		{Category: "fruit", Stock: 10},
+		{Category: "fruit", Stock: 20},
+		{Category: "meat", Stock: 100},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	result, err = brick.Delete(&data[2])
+	resultProcessor(result, err)(t)
+
+	var sum, max int
+	require.NoError(t, brick.Sum(Offsetof(TestAggregateSoftDeleteTable{}.Stock), &sum))
+	require.NoError(t, brick.Max(Offsetof(TestAggregateSoftDeleteTable{}.Stock), &max))
+	assert.Equal(t, 30, sum)
+	assert.Equal(t, 20, max)
+	require.NoError(t, brick.Unscoped().Sum(Offsetof(TestAggregateSoftDeleteTable{}.Stock), &sum))
+	assert.Equal(t, 130, sum)
+
+	type CategoryStat struct {
+		Category string
+		Total    int
+	}
+	var stats []CategoryStat
+	require.NoError(t, brick.GroupBy(Offsetof(TestAggregateSoftDeleteTable{}.Category)).
+		Aggregate(&stats, Aggregate{AggSum, Offsetof(TestAggregateSoftDeleteTable{}.Stock), "total"}))
+	assert.Equal(t, []CategoryStat{{"fruit", 30}}, stats)
+}
+
+func TestHaving(t *testing.T) {
+	type TestHavingTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Category string
+		Price    float64
+		Stock    int
+	}
+	brick := TestDB.Model(&TestHavingTable{})
+	createTableUnit(brick)(t)
+	data := []TestHavingTable{
+		{Category: "fruit", Price: 1.5, Stock: 10},
+		{Category: "fruit", Price: 2.5, Stock: 20},
+		{Category: "meat", Price: 10, Stock: 5},
+		{Category: "drink", Price: 3, Stock: 50},
+		{Category: "drink", Price: 4, Stock: 1},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	aggregates := []Aggregate{
+		{AggCount, nil, "count"},
+		{AggSum, Offsetof(TestHavingTable{}.Stock), "total_stock"},
+	}
+	type CategoryStat struct {
+		Category   string
+		Count      int
+		TotalStock int
+	}
+	groupBrick := brick.Where(ExprGreater, Offsetof(TestHavingTable{}.Price), 1).
+		GroupBy(Offsetof(TestHavingTable{}.Category)).OrderBy(Offsetof(TestHavingTable{}.Category))
+	countField := brick.AggregateField(AggCount, nil)
+	stockField := brick.AggregateField(AggSum, Offsetof(TestHavingTable{}.Stock))
+	{
+		havingBrick := groupBrick.Having(ExprGreater, countField, 1).
+			HavingAnd().Condition(ExprGreaterEqual, stockField, 30)
+		exec := havingBrick.AggregateExec(aggregates...)
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, "SELECT category,COUNT(*) AS count,SUM(stock) AS total_stock FROM `test_having_table`   WHERE price > ? GROUP BY category HAVING COUNT(id) > ? AND SUM(stock) >= ? ORDER BY category", exec.Query())
+		case "postgres":
+			assert.Equal(t, `SELECT category,COUNT(*) AS count,SUM(stock) AS total_stock FROM "test_having_table"   WHERE price > $1 GROUP BY category HAVING COUNT(id) > $2 AND SUM(stock) >= $3 ORDER BY category`, exec.Query())
+		}
+		assert.Equal(t, []interface{}{1, 1, 30}, exec.Args())
+		var stats []CategoryStat
+		require.NoError(t, havingBrick.Aggregate(&stats, aggregates...))
+		assert.Equal(t, []CategoryStat{
+			{"drink", 2, 51},
+			{"fruit", 2, 30},
+		}, stats)
+	}
+	{
+		havingBrick := groupBrick.Having(ExprGreater, stockField, 40).
+			HavingOr().Condition(ExprLess, stockField, 10)
+		var stats []CategoryStat
+		require.NoError(t, havingBrick.Aggregate(&stats, aggregates...))
+		assert.Equal(t, []CategoryStat{
+			{"drink", 2, 51},
+			{"meat", 1, 5},
+		}, stats)
+	}
+	// having condition with alias
+	{
+		havingBrick := groupBrick.Having(ExprGreater, countField, 1).Alias("m")
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, "SELECT m.category,COUNT(*) AS count FROM `test_having_table` as `m`   WHERE m.price > ? GROUP BY m.category HAVING COUNT(m.id) > ? ORDER BY m.category", havingBrick.AggregateExec(aggregates[0]).Query())
+		}
+		// clean having
+		var stats []CategoryStat
+		require.NoError(t, havingBrick.HavingConditions(nil).Aggregate(&stats, aggregates...))
+		assert.Equal(t, 3, len(stats))
+	}
+}
+
+func TestOuterJoin(t *testing.T) {
+	type TestOuterJoinDetailTable struct {
+		ID     uint32 `toyorm:"primary key;auto_increment"`
+		MainID uint32 `toyorm:"index;join:Detail"`
+		Color  string
+		Stock  *int
+	}
+	type TestOuterJoinExtraTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		MainName string `toyorm:"index;join:Extra"`
+		Note     string
+	}
+	type TestOuterJoinTable struct {
+		ID     uint32 `toyorm:"primary key;auto_increment;join:Detail,left"`
+		Name   string `toyorm:"join:Extra"`
+		Detail *TestOuterJoinDetailTable
+		Extra  TestOuterJoinExtraTable
+	}
+	brick := TestDB.Model(&TestOuterJoinTable{})
+	detailBrick := TestDB.Model(&TestOuterJoinDetailTable{})
+	extraBrick := TestDB.Model(&TestOuterJoinExtraTable{})
+	createTableUnit(brick)(t)
+	createTableUnit(detailBrick)(t)
+	createTableUnit(extraBrick)(t)
+
+	data := []TestOuterJoinTable{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	stock := 10
+	details := []TestOuterJoinDetailTable{
+		{MainID: data[0].ID, Color: "red", Stock: &stock},
+		{MainID: data[1].ID, Color: "blue"},
+		{MainID: 0, Color: "orphan"},
+	}
+	result, err = detailBrick.Insert(&details)
+	resultProcessor(result, err)(t)
+	result, err = extraBrick.Insert(&TestOuterJoinExtraTable{MainName: "a", Note: "extra a"})
+	resultProcessor(result, err)(t)
+
+	// left join from tag and LeftJoin
+	{
+		joinBrick := brick.OrderBy(Offsetof(TestOuterJoinTable{}.ID)).
+			Join(Offsetof(TestOuterJoinTable{}.Detail)).Swap().
+			LeftJoin(Offsetof(TestOuterJoinTable{}.Extra)).Swap()
+		assert.Equal(t, JoinLeft, joinBrick.JoinMap["Detail"].Type)
+		assert.Equal(t, JoinLeft, joinBrick.JoinMap["Extra"].Type)
+		var list []TestOuterJoinTable
+		result, err := joinBrick.Find(&list)
+		resultProcessor(result, err)(t)
+		require.Equal(t, 3, len(list))
+		require.NotNil(t, list[0].Detail)
+		assert.Equal(t, "red", list[0].Detail.Color)
+		require.NotNil(t, list[0].Detail.Stock)
+		assert.Equal(t, 10, *list[0].Detail.Stock)
+		assert.Equal(t, "extra a", list[0].Extra.Note)
+		require.NotNil(t, list[1].Detail)
+		assert.Equal(t, "blue", list[1].Detail.Color)
+		assert.Nil(t, list[1].Detail.Stock)
+		assert.Equal(t, TestOuterJoinExtraTable{}, list[1].Extra)
+		// missing detail and extra
+		assert.Equal(t, "c", list[2].Name)
+		assert.Nil(t, list[2].Detail)
+		assert.Equal(t, TestOuterJoinExtraTable{}, list[2].Extra)
+	}
+	// inner join will replace the tag join type
+	{
+		joinBrick := brick.JoinWithType(Offsetof(TestOuterJoinTable{}.Detail), JoinInner).Swap()
+		assert.Equal(t, JoinLeft, brick.Join(Offsetof(TestOuterJoinTable{}.Detail)).Swap().JoinMap["Detail"].Type)
+		var list []TestOuterJoinTable
+		result, err := joinBrick.Find(&list)
+		resultProcessor(result, err)(t)
+		assert.Equal(t, 2, len(list))
+	}
+	// right join
+	{
+		joinBrick := brick.RightJoin(Offsetof(TestOuterJoinTable{}.Detail)).
+			OrderBy(Offsetof(TestOuterJoinDetailTable{}.ID)).Swap()
+		var columns []Column
+		for _, field := range brick.Model.GetSqlFields() {
+			columns = append(columns, field.ToColumnAlias("m"))
+		}
+		switch TestDriver {
+		case "mysql", "sqlite3":
+			assert.Equal(t, "SELECT m.id,m.name FROM `test_outer_join_table` as `m` RIGHT JOIN `test_outer_join_detail_table` AS `m_0` ON m.id = m_0.main_id   ORDER BY m_0.id", joinBrick.FindExec(columns).Query())
+		case "postgres":
+			assert.Equal(t, `SELECT m.id,m.name FROM "test_outer_join_table" as "m" RIGHT JOIN "test_outer_join_detail_table" as "m_0" on m.id = m_0.main_id   ORDER BY m_0.id`, joinBrick.FindExec(columns).Query())
+		}
+		// sqlite3 support right join since 3.39
+		if TestDriver != "sqlite3" {
+			var list []TestOuterJoinTable
+			result, err := joinBrick.Find(&list)
+			resultProcessor(result, err)(t)
+			require.Equal(t, 3, len(list))
+			assert.Equal(t, "orphan", list[2].Detail.Color)
+			assert.Equal(t, uint32(0), list[2].ID)
+			assert.Equal(t, "", list[2].Name)
+		}
+	}
+}
+
+func TestJoinOn(t *testing.T) {
+	type TestJoinOnDetailTable struct {
+		ID        uint32 `toyorm:"primary key;auto_increment"`
+		MainID    uint32 `toyorm:"index;join:Detail"`
+		Kind      string
+		DeletedAt *time.Time
+	}
+	type TestJoinOnProfileTable struct {
+		ID     uint32 `toyorm:"primary key;auto_increment"`
+		UserID uint32 `toyorm:"index"`
+		Bio    string
+	}
+	type TestJoinOnTable struct {
+		ID      uint32 `toyorm:"primary key;auto_increment;join:Detail,left"`
+		Name    string
+		Detail  *TestJoinOnDetailTable
+		Profile TestJoinOnProfileTable
+	}
+	brick := TestDB.Model(&TestJoinOnTable{})
+	detailBrick := TestDB.Model(&TestJoinOnDetailTable{})
+	profileBrick := TestDB.Model(&TestJoinOnProfileTable{})
+	createTableUnit(brick)(t)
+	createTableUnit(detailBrick)(t)
+	createTableUnit(profileBrick)(t)
+
+	data := []TestJoinOnTable{{Name: "a"}, {Name: "b"}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	now := time.Now()
+	details := []TestJoinOnDetailTable{
+		{MainID: data[0].ID, Kind: "x"},
+		{MainID: data[1].ID, Kind: "y"},
+		{MainID: data[1].ID, Kind: "x", DeletedAt: &now},
+	}
+	result, err = detailBrick.Insert(&details)
+	resultProcessor(result, err)(t)
+	result, err = profileBrick.Insert(&[]TestJoinOnProfileTable{{UserID: data[0].ID, Bio: "bio a"}, {UserID: data[1].ID, Bio: "bio b"}})
+	resultProcessor(result, err)(t)
+
+	joinBrick := brick.Where(ExprNotEqual, Offsetof(TestJoinOnTable{}.Name), "c").OrderBy(Offsetof(TestJoinOnTable{}.ID)).
+		Join(Offsetof(TestJoinOnTable{}.Detail)).
+		On(ExprNull, Offsetof(TestJoinOnDetailTable{}.DeletedAt)).
+		On(ExprEqual, Offsetof(TestJoinOnDetailTable{}.Kind), "x").Swap().
+		JoinBy(Offsetof(TestJoinOnTable{}.Profile), Offsetof(TestJoinOnTable{}.ID), Offsetof(TestJoinOnProfileTable{}.UserID)).
+		On(ExprNotEqual, Offsetof(TestJoinOnProfileTable{}.Bio), "").Swap()
+	// On not change the origin join
+	assert.Nil(t, brick.Join(Offsetof(TestJoinOnTable{}.Detail)).Swap().JoinMap["Detail"].OnSearch)
+
+	exec := joinBrick.FindExec([]Column{joinBrick.Model.GetFieldWithName("Name").ToColumnAlias("m")})
+	switch TestDriver {
+	case "mysql", "sqlite3":
+		assert.Equal(t, "SELECT m.name FROM `test_join_on_table` as `m` LEFT JOIN `test_join_on_detail_table` AS `m_0` ON m.id = m_0.main_id AND m_0.deleted_at IS NULL AND m_0.kind = ? JOIN `test_join_on_profile_table` AS `m_1` ON m.id = m_1.user_id AND m_1.bio <> ?    WHERE m.name <> ? ORDER BY m.id", exec.Query())
+	case "postgres":
+		assert.Equal(t, `SELECT m.name FROM "test_join_on_table" as "m" LEFT JOIN "test_join_on_detail_table" as "m_0" on m.id = m_0.main_id AND m_0.deleted_at IS NULL AND m_0.kind = $1 JOIN "test_join_on_profile_table" as "m_1" on m.id = m_1.user_id AND m_1.bio <> $2    WHERE m.name <> $3 ORDER BY m.id`, exec.Query())
+	}
+	assert.Equal(t, []interface{}{"x", "", "c"}, exec.Args())
+
+	var list []TestJoinOnTable
+	result, err = joinBrick.Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(list))
+	require.NotNil(t, list[0].Detail)
+	assert.Equal(t, "x", list[0].Detail.Kind)
+	assert.Equal(t, "bio a", list[0].Profile.Bio)
+	// the detail of b is deleted or not match kind
+	assert.Nil(t, list[1].Detail)
+	assert.Equal(t, "bio b", list[1].Profile.Bio)
+}
+
+func TestConventionTag(t *testing.T) {
+	type TestConventionTable struct {
+		ID        uint32 `toyorm:"primary key;auto_increment"`
+		Name      string
+		Created   time.Time `toyorm:"created at"`
+		Modified  int64     `toyorm:"updated at"`
+		Version   int       `toyorm:"version"`
+		IsDeleted bool      `toyorm:"soft delete"`
+	}
+	type TestConventionUnixTable struct {
+		ID        uint32 `toyorm:"primary key;auto_increment"`
+		Name      string
+		RemovedOn int64 `toyorm:"soft delete"`
+		// tag have high priority than field name
+		DeletedAt *time.Time
+	}
+	brick := TestDB.Model(&TestConventionTable{})
+	assert.Equal(t, "IsDeleted", brick.Model.GetSoftDeleteField().Name())
+	assert.Equal(t, "Created", brick.Model.GetCreatedAtField().Name())
+	assert.Equal(t, "Modified", brick.Model.GetUpdatedAtField().Name())
+	assert.Equal(t, "Version", brick.Model.GetVersionField().Name())
+	assert.Equal(t, "DeletedAt", TestDB.Model(&TestSoftDeleteTable{}).Model.GetSoftDeleteField().Name())
+	assert.Nil(t, TestDB.Model(&TestSoftDeleteTable{}).Model.GetVersionField())
+	createTableUnit(brick)(t)
+
+	data := []TestConventionTable{{Name: "a"}, {Name: "b"}}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	for _, d := range data {
+		assert.False(t, d.Created.IsZero())
+		assert.NotZero(t, d.Modified)
+		assert.Equal(t, 1, d.Version)
+	}
+	data[0].Name = "a2"
+	data[0].Modified = 0
+	result, err = brick.USave(&data[0])
+	resultProcessor(result, err)(t)
+	assert.NotZero(t, data[0].Modified)
+	assert.Equal(t, 2, data[0].Version)
+
+	result, err = brick.Delete(&data[1])
+	resultProcessor(result, err)(t)
+	var list []TestConventionTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(list))
+	assert.Equal(t, "a2", list[0].Name)
+	assert.Equal(t, 2, list[0].Version)
+	// check the soft delete flag with raw condition
+	var deleted []TestConventionTable
+	result, err = brick.Template("SELECT $Columns FROM $ModelName WHERE is_deleted = ?", true).Find(&deleted)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(deleted))
+	assert.Equal(t, "b", deleted[0].Name)
+
+	unixBrick := TestDB.Model(&TestConventionUnixTable{})
+	assert.Equal(t, "RemovedOn", unixBrick.Model.GetSoftDeleteField().Name())
+	createTableUnit(unixBrick)(t)
+	unixData := []TestConventionUnixTable{{Name: "a"}, {Name: "b"}}
+	result, err = unixBrick.Insert(&unixData)
+	resultProcessor(result, err)(t)
+	result, err = unixBrick.Where(ExprEqual, Offsetof(TestConventionUnixTable{}.Name), "a").DeleteWithConditions()
+	resultProcessor(result, err)(t)
+	var unixList []TestConventionUnixTable
+	result, err = unixBrick.Find(&unixList)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(unixList))
+	assert.Equal(t, "b", unixList[0].Name)
+	var unixDeleted []TestConventionUnixTable
+	result, err = unixBrick.Template("SELECT $Columns FROM $ModelName WHERE removed_on <> 0").Find(&unixDeleted)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(unixDeleted))
+	assert.InDelta(t, time.Now().Unix(), unixDeleted[0].RemovedOn, 10)
+
+	// repeat convention tag
+	type TestConventionRepeatTable struct {
+		ID        uint32 `toyorm:"primary key"`
+		IsDeleted bool   `toyorm:"soft delete"`
+		Removed   bool   `toyorm:"soft delete"`
+	}
+	assert.Panics(t, func() {
+		TestDB.Model(&TestConventionRepeatTable{})
+	})
+}
+
+func TestOptimisticLock(t *testing.T) {
+	type TestOptimisticLockTable struct {
+		ID      uint32 `toyorm:"primary key;auto_increment"`
+		Name    string
+		Version int `toyorm:"version"`
+	}
+	brick := TestDB.Model(&TestOptimisticLockTable{})
+	createTableUnit(brick)(t)
+	lockConflict := func(result *Result) {
+		require.NotNil(t, result.Err())
+		conflict, ok := result.ActionFlow[len(result.ActionFlow)-1].Err().(ErrOptimisticLockConflict)
+		require.True(t, ok)
+		assert.Equal(t, brick.Model.Name, conflict.Model)
+	}
+
+	data := TestOptimisticLockTable{Name: "a"}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 1, data.Version)
+	stale := data
+
+	data.Name = "b"
+	result, err = brick.Update(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, data.Version)
+
+	// update with stale version
+	staleUpdate := stale
+	staleUpdate.Name = "c"
+	result, err = brick.Update(&staleUpdate)
+	require.NoError(t, err)
+	lockConflict(result)
+	// conflict update keep the stale version
+	assert.Equal(t, 1, staleUpdate.Version)
+
+	staleUSave := stale
+	result, err = brick.USave(&staleUSave)
+	require.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleUSave.Version)
+
+	staleSave := stale
+	result, err = brick.Save(&staleSave)
+	require.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleSave.Version)
+	// retry save after conflict still use the stale version
+	result, err = brick.Save(&staleSave)
+	require.NoError(t, err)
+	lockConflict(result)
+	assert.Equal(t, 1, staleSave.Version)
+
+	// upsert can't check the version
+	_, err = brick.Upsert(&data)
+	assert.Equal(t, ErrUpsertVersion, err)
+
+	// save with latest version
+	data.Name = "d"
+	result, err = brick.Save(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 3, data.Version)
+
+	var scanData TestOptimisticLockTable
+	result, err = brick.Find(&scanData)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, "d", scanData.Name)
+	assert.Equal(t, 3, scanData.Version)
+}
+
+func TestUnscopedAndRestore(t *testing.T) {
+	type TestRestoreChild struct {
+		ModelDefault
+		Data               string
+		TestRestoreTableID uint32 `toyorm:"index"`
+	}
+	type TestRestoreTable struct {
+		ModelDefault
+		Data     string
+		Children []TestRestoreChild
+	}
+	brick := TestDB.Model(&TestRestoreTable{}).Preload(Offsetof(TestRestoreTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+
+	data := []TestRestoreTable{
+		{Data: "a", Children: []TestRestoreChild{{Data: "a1"}, {Data: "a2"}}},
+		{Data: "b", Children: []TestRestoreChild{{Data: "b1"}}},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	result, err = brick.Delete(&data[0])
+	resultProcessor(result, err)(t)
+
+	count, err := brick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = brick.Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// unscoped affect preload brick
+	var all []TestRestoreTable
+	result, err = brick.Unscoped().Find(&all)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(all))
+	assert.Equal(t, 2, len(all[0].Children))
+	assert.NotNil(t, all[0].DeletedAt)
+
+	var deleted []TestRestoreTable
+	result, err = brick.OnlyDeleted().Find(&deleted)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(deleted))
+	assert.Equal(t, "a", deleted[0].Data)
+	require.Equal(t, 2, len(deleted[0].Children))
+	assert.NotNil(t, deleted[0].Children[0].DeletedAt)
+
+	result, err = brick.Restore(&deleted[0])
+	resultProcessor(result, err)(t)
+	assert.Nil(t, deleted[0].DeletedAt)
+	assert.Nil(t, deleted[0].Children[0].DeletedAt)
+
+	var restored []TestRestoreTable
+	result, err = brick.Find(&restored)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(restored))
+	assert.Equal(t, 2, len(restored[0].Children))
+
+	// unscoped delete is hard delete
+	result, err = brick.Unscoped().Delete(&data[1])
+	resultProcessor(result, err)(t)
+	count, err = brick.Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = TestDB.Model(&TestRestoreChild{}).Unscoped().Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCursor(t *testing.T) {
+	type TestCursorChild struct {
+		ID                uint32 `toyorm:"primary key;auto_increment"`
+		Data              string
+		TestCursorTableID uint32 `toyorm:"index"`
+	}
+	type TestCursorTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Data     string
+		Score    int
+		Children []TestCursorChild
+	}
+	brick := TestDB.Model(&TestCursorTable{}).Preload(Offsetof(TestCursorTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+	var data []TestCursorTable
+	for i := 0; i < 5; i++ {
+		data = append(data, TestCursorTable{
+			Data:     fmt.Sprintf("d%d", i),
+			Score:    i,
+			Children: []TestCursorChild{{Data: fmt.Sprintf("c%d", i)}},
+		})
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	// the sqlite memory database belong to connection, keep cursor and preload query in one transaction
+	cursorBrick := TestDB.Model(&TestCursorTable{})
+	if TestDriver == "sqlite3" {
+		cursorBrick, err = cursorBrick.Begin()
+		require.NoError(t, err)
+	}
+	// chunk size smaller than records size
+	cursor, err := cursorBrick.Preload(Offsetof(TestCursorTable{}.Children)).Enter().
+		BatchSize(2).OrderBy(Offsetof(TestCursorTable{}.ID)).Cursor(&TestCursorTable{})
+	require.NoError(t, err)
+	var list []TestCursorTable
+	for cursor.Next() {
+		var elem TestCursorTable
+		require.NoError(t, cursor.Scan(&elem))
+		list = append(list, elem)
+	}
+	require.NoError(t, cursor.Err())
+	require.NoError(t, cursor.Close())
+	if TestDriver == "sqlite3" {
+		require.NoError(t, cursorBrick.Commit())
+	}
+	require.Equal(t, len(data), len(list))
+	for i := range list {
+		assert.Equal(t, data[i].Data, list[i].Data)
+		require.Equal(t, 1, len(list[i].Children))
+		assert.Equal(t, data[i].Children[0].Data, list[i].Children[0].Data)
+	}
+
+	// negative batch size read one by one
+	var ids []uint32
+	err = TestDB.Model(&TestCursorTable{}).BatchSize(-1).OrderBy(Offsetof(TestCursorTable{}.ID)).
+		Iterate(func(record *TestCursorTable) error {
+			ids = append(ids, record.ID)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, len(data), len(ids))
+
+	// preload in transaction of single connection driver
+	mysqlBrick, err := newToy(TestDB.db, MySqlDialect{}).Model(&TestCursorTable{}).
+		Preload(Offsetof(TestCursorTable{}.Children)).Enter().Begin()
+	require.NoError(t, err)
+	_, err = mysqlBrick.Cursor(&TestCursorTable{})
+	assert.Equal(t, ErrCursorPreloadInTx, err)
+	require.NoError(t, mysqlBrick.Rollback())
+
+	// iterate with BindFields
+	brick = TestDB.Model(&TestCursorTable{})
+	var scores []int
+	var names []string
+	err = brick.BatchSize(3).BindFields(ModeDefault, Offsetof(TestCursorTable{}.ID), Offsetof(TestCursorTable{}.Score)).
+		Where(ExprGreaterEqual, Offsetof(TestCursorTable{}.Score), 1).
+		OrderBy(Offsetof(TestCursorTable{}.ID)).
+		Iterate(func(record *TestCursorTable) error {
+			scores = append(scores, record.Score)
+			names = append(names, record.Data)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, scores)
+	assert.Equal(t, []string{"", "", "", ""}, names)
+
+	// stop with error
+	stopErr := errors.New("stop")
+	var count int
+	err = brick.Iterate(func(record *TestCursorTable) error {
+		count++
+		if count == 2 {
+			return stopErr
+		}
+		return nil
+	})
+	assert.Equal(t, stopErr, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestFindInBatchesAndSeek(t *testing.T) {
+	type TestBatchesChild struct {
+		ID                 uint32 `toyorm:"primary key;auto_increment"`
+		Data               string
+		TestBatchesTableID uint32 `toyorm:"index"`
+	}
+	type TestBatchesTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Score    int
+		Children []TestBatchesChild
+	}
+	brick := TestDB.Model(&TestBatchesTable{}).Preload(Offsetof(TestBatchesTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+	var data []TestBatchesTable
+	for i := 0; i < 7; i++ {
+		data = append(data, TestBatchesTable{
+			Score:    i % 3,
+			Children: []TestBatchesChild{{Data: fmt.Sprintf("c%d", i)}},
+		})
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var batch []TestBatchesTable
+	var sizes []int
+	var ids []uint32
+	err = brick.FindInBatches(&batch, 3, func(n int) error {
+		assert.Equal(t, len(sizes), n)
+		sizes = append(sizes, len(batch))
+		for _, b := range batch {
+			ids = append(ids, b.ID)
+			assert.Equal(t, 1, len(b.Children))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 3, 1}, sizes)
+	for i := range data {
+		assert.Equal(t, data[i].ID, ids[i])
+	}
+
+	// mixed ASC/DESC keyset
+	seekBrick := TestDB.Model(&TestBatchesTable{})
+	seekBrick = seekBrick.OrderBy(seekBrick.ToDesc(Offsetof(TestBatchesTable{}.Score)), Offsetof(TestBatchesTable{}.ID))
+	var all []TestBatchesTable
+	result, err = seekBrick.Find(&all)
+	resultProcessor(result, err)(t)
+	require.Equal(t, len(data), len(all))
+	var page []TestBatchesTable
+	result, err = seekBrick.Where(ExprNotEqual, Offsetof(TestBatchesTable{}.Score), -1).
+		Seek(all[2].Score, all[2].ID).Limit(3).Find(&page)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 3, len(page))
+	for i := range page {
+		assert.Equal(t, all[i+3].ID, page[i].ID)
+	}
+
+	assert.Panics(t, func() {
+		seekBrick.Seek(1)
+	})
+}
+
+func TestQueryCache(t *testing.T) {
+	lru := NewLRUCache(2)
+	lru.Set("a", 1, 0)
+	lru.Set("b", 2, 0)
+	lru.Get("a")
+	lru.Set("c", 3, 0)
+	_, ok := lru.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, lru.Len())
+	lru.Set("d", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = lru.Get("d")
+	assert.False(t, ok)
+
+	type TestCacheChild struct {
+		ID               uint32 `toyorm:"primary key;auto_increment"`
+		Data             string
+		TestCacheTableID uint32 `toyorm:"index"`
+	}
+	type TestCacheTable struct {
+		ID       uint32 `toyorm:"primary key;auto_increment"`
+		Data     string
+		Children []TestCacheChild
+	}
+	TestDB.SetCache(NewLRUCache(100))
+	defer TestDB.SetCache(nil)
+	brick := TestDB.Model(&TestCacheTable{}).Preload(Offsetof(TestCacheTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+	data := []TestCacheTable{
+		{Data: "a", Children: []TestCacheChild{{Data: "a1"}}},
+		{Data: "b"},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	cacheBrick := brick.Cache(time.Minute)
+	var list []TestCacheTable
+	result, err = cacheBrick.Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(list))
+	count, err := cacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	// modify the cached data will not effect cache
+	list[0].Children[0].Data = "modified"
+
+	// write without handlers will not invalidate the cache
+	_, err = TestDB.db.Exec("INSERT INTO test_cache_table(data) VALUES('c')")
+	require.NoError(t, err)
+	var cachedList []TestCacheTable
+	result, err = cacheBrick.Find(&cachedList)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(cachedList))
+	assert.Equal(t, "a1", cachedList[0].Children[0].Data)
+	count, err = cacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	// brick without cache
+	count, err = brick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// write the preload model invalidate the main model query
+	result, err = TestDB.Model(&TestCacheChild{}).Insert(&TestCacheChild{Data: "a2", TestCacheTableID: data[0].ID})
+	resultProcessor(result, err)(t)
+	var newList []TestCacheTable
+	result, err = cacheBrick.Find(&newList)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 3, len(newList))
+	assert.Equal(t, 2, len(newList[0].Children))
+	// count only use main model, it's still cached
+	count, err = cacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	result, err = brick.Delete(&newList[2])
+	resultProcessor(result, err)(t)
+	count, err = cacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	var deletedList []TestCacheTable
+	result, err = cacheBrick.Find(&deletedList)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, len(deletedList))
+
+	// the cache entry of models only record the model version, evicted entry not leave any index
+	assert.Equal(t, 2, len(TestDB.cache.versions))
+
+	// transaction write invalidate cache after commit
+	tx, err := brick.Begin()
+	require.NoError(t, err)
+	version := TestDB.cache.versions[brick.Model.Name]
+	result, err = tx.Insert(&TestCacheTable{Data: "tx"})
+	resultProcessor(result, err)(t)
+	assert.Equal(t, version, TestDB.cache.versions[brick.Model.Name])
+	require.NoError(t, tx.Commit())
+	assert.NotEqual(t, version, TestDB.cache.versions[brick.Model.Name])
+	count, err = cacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// write the sub query model invalidate the query use it as condition value
+	sub := TestDB.Model(&TestCacheChild{}).BindFields(ModeSelect, Offsetof(TestCacheChild{}.TestCacheTableID))
+	subCacheBrick := TestDB.Model(&TestCacheTable{}).Cache(time.Minute).Where(ExprIn, Offsetof(TestCacheTable{}.ID), sub)
+	var subList []TestCacheTable
+	result, err = subCacheBrick.Find(&subList)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 1, len(subList))
+	count, err = subCacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	result, err = TestDB.Model(&TestCacheChild{}).Insert(&TestCacheChild{Data: "b1", TestCacheTableID: data[1].ID})
+	resultProcessor(result, err)(t)
+	subList = nil
+	result, err = subCacheBrick.Find(&subList)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, len(subList))
+	count, err = subCacheBrick.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestModelHooks(t *testing.T) {
+	brick := TestDB.Model(&TestHookTable{}).Preload(Offsetof(TestHookTable{}.Children)).Enter()
+	createTableUnit(brick)(t)
+	hookCounter = map[string]int{}
+
+	data := []TestHookTable{
+		{Data: "a", Children: []TestHookChild{{Data: "a1"}}},
+		{Data: "b"},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 2, hookCounter["BeforeInsert"])
+	assert.Equal(t, 2, hookCounter["AfterInsert"])
+
+	// hook error abort the chain and show in result
+	result, err = brick.Insert(&TestHookTable{})
+	assert.Error(t, err)
+	require.Error(t, result.Err())
+	_, ok := result.ActionFlow[len(result.ActionFlow)-1].(HookAction)
+	assert.True(t, ok)
+	assert.Equal(t, 2, hookCounter["AfterInsert"])
+
+	// the after find hook also run with preload records
+	var list []TestHookTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, "display a", list[0].Display)
+	require.Equal(t, 1, len(list[0].Children))
+	assert.Equal(t, "child a1", list[0].Children[0].Display)
+
+	var one TestHookTable
+	result, err = brick.Where(ExprEqual, Offsetof(TestHookTable{}.ID), data[1].ID).Find(&one)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, "display b", one.Display)
+
+	result, err = brick.Where(ExprEqual, Offsetof(TestHookTable{}.ID), data[1].ID).Update(&TestHookTable{Data: "b"})
+	resultProcessor(result, err)(t)
+	var updated TestHookTable
+	result, err = brick.Where(ExprEqual, Offsetof(TestHookTable{}.ID), data[1].ID).Find(&updated)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, "b updated", updated.Data)
+
+	// upsert call the insert and save hooks
+	result, err = brick.Upsert(&TestHookTable{})
+	assert.Error(t, err)
+	require.Error(t, result.Err())
+	result, err = brick.Upsert(&TestHookTable{ID: data[1].ID, Data: "b upsert"})
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 3, hookCounter["BeforeInsert"])
+	assert.Equal(t, 3, hookCounter["AfterInsert"])
+	assert.Equal(t, 1, hookCounter["BeforeSave"])
+
+	result, err = brick.Delete(&data[0])
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 1, hookCounter["BeforeDelete"])
+}
+
+func TestHandlerRegistration(t *testing.T) {
+	type TestHandlerRegisterTable struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Data string
+	}
+	brick := TestDB.Model(&TestHandlerRegisterTable{})
+	createTableUnit(brick)(t)
+
+	assert.Equal(t, "HandlerInsert", HandlerName(HandlerInsert))
+	assert.Equal(t, "HandlerPreloadInsertOrSave", HandlerName(HandlerPreloadInsertOrSave("Insert")))
+
+	var called []string
+	record := func(name string) HandlerFunc {
+		return func(ctx *Context) error {
+			called = append(called, name)
+			return nil
+		}
+	}
+	require.NoError(t, TestDB.InsertModelBefore(&TestHandlerRegisterTable{}, OpInsert, "HandlerInsert", record("before")))
+	require.NoError(t, TestDB.InsertModelAfter(&TestHandlerRegisterTable{}, OpInsert, "HandlerInsert", record("after")))
+	TestDB.UseModel(&TestHandlerRegisterTable{}, OpInsert, record("last"))
+
+	err := TestDB.InsertModelBefore(&TestHandlerRegisterTable{}, OpInsert, "HandlerNotExist", record("none"))
+	assert.Equal(t, ErrHandlerNotFound{OpInsert, "HandlerNotExist"}, err)
+
+	var names []string
+	for _, handler := range TestDB.ModelHandlers("Insert", brick.Model) {
+		names = append(names, HandlerName(handler))
+	}
+	insertIdx := -1
+	for i, name := range names {
+		if name == "HandlerInsert" {
+			insertIdx = i
+		}
+	}
+	require.True(t, insertIdx > 0)
+	assert.Equal(t, "TestHandlerRegistration", names[insertIdx-1])
+	assert.Equal(t, "TestHandlerRegistration", names[insertIdx+1])
+	// default handler chain not changed
+	assert.Equal(t, len(TestDB.DefaultHandlerChain["Insert"])+3, len(names))
+
+	result, err := brick.Insert(&TestHandlerRegisterTable{Data: "a"})
+	resultProcessor(result, err)(t)
+	assert.Equal(t, []string{"before", "after", "last"}, called)
+
+	// register handler concurrently with running operation
+	called = nil
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			TestDB.UseModel(&TestHandlerRegisterTable{}, OpFind, func(ctx *Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				called = append(called, "find")
+				return nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			TestDB.ModelHandlers("Find", brick.Model)
+		}()
+	}
+	wg.Wait()
+	var list []TestHandlerRegisterTable
+	result, err = brick.Find(&list)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, 10, len(called))
+
+	// the default handler change after model registration also reach the model
+	toy := newToy(TestDB.db, TestDB.Dialect)
+	toy.UseModel(&TestHandlerRegisterTable{}, OpInsert, record("model"))
+	toy.Use(OpInsert, record("global"))
+	require.NoError(t, toy.InsertBefore(OpInsert, "HandlerInsert", record("global before")))
+	called = nil
+	result, err = toy.Model(&TestHandlerRegisterTable{}).Insert(&TestHandlerRegisterTable{Data: "b"})
+	resultProcessor(result, err)(t)
+	assert.Equal(t, []string{"global before", "global", "model"}, called)
+}
+
+func TestJsonField(t *testing.T) {
+	type TestJsonAddress struct {
+		City   string
+		Street string
+	}
+	type TestJsonTable struct {
+		ID      uint32 `toyorm:"primary key;auto_increment"`
+		Name    string
+		Extra   map[string]interface{} `toyorm:"json"`
+		Address TestJsonAddress        `toyorm:"json"`
+		Tags    []string               `toyorm:"json"`
+		Detail  *TestJsonAddress       `toyorm:"json"`
+	}
+	brick := TestDB.Model(&TestJsonTable{})
+	createTableUnit(brick)(t)
+
+	data := []TestJsonTable{
+		{Name: "a", Extra: map[string]interface{}{"score": 10, "level": "high"}, Address: TestJsonAddress{City: "Paris"}, Tags: []string{"x", "y"}},
+		{Name: "b", Extra: map[string]interface{}{"score": 5}, Address: TestJsonAddress{City: "London"}, Detail: &TestJsonAddress{Street: "Baker"}},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var list []TestJsonTable
+	result, err = brick.OrderBy(Offsetof(TestJsonTable{}.ID)).Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(list))
+	assert.Equal(t, map[string]interface{}{"score": float64(10), "level": "high"}, list[0].Extra)
+	assert.Equal(t, data[0].Address, list[0].Address)
+	assert.Equal(t, data[0].Tags, list[0].Tags)
+	assert.Nil(t, list[0].Detail)
+	assert.Nil(t, list[1].Tags)
+	assert.Equal(t, data[1].Detail, list[1].Detail)
+
+	result, err = brick.Where(ExprEqual, Offsetof(TestJsonTable{}.ID), data[0].ID).
+		Update(&TestJsonTable{Tags: []string{"z"}})
+	resultProcessor(result, err)(t)
+	var one TestJsonTable
+	result, err = brick.Where(ExprEqual, Offsetof(TestJsonTable{}.ID), data[0].ID).Find(&one)
+	resultProcessor(result, err)(t)
+	assert.Equal(t, []string{"z"}, one.Tags)
+
+	// the json path is bind argument, quote and backslash can't break the sql
+	for _, d := range []struct {
+		dialect Dialect
+		numeric bool
+		column  string
+		path    string
+	}{
+		{MySqlDialect{}, false, `JSON_UNQUOTE(JSON_EXTRACT(extra, ?))`, `$.a[0]."b c"."it's \\' OR 1=1 -- \""`},
+		{PostgreSqlDialect{}, true, `(extra #>> ?::text[])::numeric`, `{"a","0","b c","it's \\' OR 1=1 -- \""}`},
+		{Sqlite3Dialect{}, false, `json_extract(extra, ?)`, `$.a[0]."b c"."it's \\' OR 1=1 -- \""`},
+	} {
+		exec := d.dialect.JsonExtract("extra", `a.0.b c.it's \' OR 1=1 -- "`, d.numeric)
+		assert.Equal(t, d.column, exec.Source(), "%T", d.dialect)
+		assert.Equal(t, []interface{}{d.path}, exec.Args(), "%T", d.dialect)
+	}
+	{
+		search := brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Extra), `a\' OR 1=1 -- `, "x").Search
+		exec := TestDB.Dialect.SearchExec(search)
+		assert.Equal(t, 2, len(exec.Args()))
+		assert.Equal(t, "x", exec.Args()[1])
+		assert.NotContains(t, exec.Source(), "OR 1=1")
+	}
+	assert.Panics(t, func() {
+		brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Extra), 1, 2)
+	})
+
+	// sqlite json path condition need json1 extension(build with sqlite_json tag)
+	if _, ok := TestDB.Dialect.(Sqlite3Dialect); ok {
+		if _, err := TestDB.db.Exec("SELECT json('{}')"); err != nil {
+			t.Skip("sqlite json1 extension not found")
+		}
+	}
+	var found []TestJsonTable
+	result, err = brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Address), "City", "London").Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "b", found[0].Name)
+
+	found = nil
+	result, err = brick.Where(ExprJsonGreater, Offsetof(TestJsonTable{}.Extra), "score", 6).Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "a", found[0].Name)
+
+	found = nil
+	result, err = brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Tags), "0", "z").Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "a", found[0].Name)
+
+	found = nil
+	result, err = brick.Where(ExprJsonNull, Offsetof(TestJsonTable{}.Extra), "level").Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "b", found[0].Name)
+
+	// the key have quote and backslash
+	result, err = brick.Insert(&TestJsonTable{Name: "c", Extra: map[string]interface{}{`it's \' OR 1=1 -- `: "v"}})
+	resultProcessor(result, err)(t)
+	found = nil
+	result, err = brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Extra), `it's \' OR 1=1 -- `, "v").Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "c", found[0].Name)
+
+	// json path condition keep in alias brick
+	found = nil
+	result, err = brick.Where(ExprJsonEqual, Offsetof(TestJsonTable{}.Address), "City", "Paris").Alias("m").Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "a", found[0].Name)
+
+}
+
+func TestArrayField(t *testing.T) {
+	type TestArrayTable struct {
+		ID     uint32 `toyorm:"primary key;auto_increment"`
+		Name   string
+		Tags   []string `toyorm:"array"`
+		Scores []int64  `toyorm:"array"`
+	}
+	brick := TestDB.Model(&TestArrayTable{})
+	createTableUnit(brick)(t)
+
+	data := []TestArrayTable{
+		{Name: "a", Tags: []string{"go", "sql"}, Scores: []int64{1, 2}},
+		{Name: "b", Tags: []string{"rust", `quote"d`}},
+		{Name: "c"},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var list []TestArrayTable
+	result, err = brick.OrderBy(Offsetof(TestArrayTable{}.ID)).Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 3, len(list))
+	for i := range data {
+		assert.Equal(t, data[i].Tags, list[i].Tags)
+		assert.Equal(t, data[i].Scores, list[i].Scores)
+	}
+
+	if _, ok := TestDB.Dialect.(PostgreSqlDialect); !ok {
+		assert.Panics(t, func() {
+			brick.Where(ExprContains, Offsetof(TestArrayTable{}.Tags), []string{"go"}).Find(&list)
+		})
+		t.Skip("array condition only support postgres")
+	}
+	var found []TestArrayTable
+	result, err = brick.Where(ExprContains, Offsetof(TestArrayTable{}.Tags), []string{"go"}).Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "a", found[0].Name)
+
+	found = nil
+	result, err = brick.Where(ExprOverlap, Offsetof(TestArrayTable{}.Tags), []string{"sql", "rust"}).
+		OrderBy(Offsetof(TestArrayTable{}.ID)).Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 2, len(found))
+
+	found = nil
+	result, err = brick.Where(ExprAny, Offsetof(TestArrayTable{}.Tags), `quote"d`).Find(&found)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "b", found[0].Name)
+}
+
+func TestEnumField(t *testing.T) {
+	brick := TestDB.Model(&TestEnumTable{})
+	createTableUnit(brick)(t)
+
+	data := []TestEnumTable{
+		{Name: "a", Status: "active"},
+		{Name: "b", Status: "disabled"},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	var list []TestEnumTable
+	result, err = brick.Where(ExprEqual, Offsetof(TestEnumTable{}.Status), TestEnumStatus("disabled")).Find(&list)
+	resultProcessor(result, err)(t)
+	require.Equal(t, 1, len(list))
+	assert.Equal(t, "b", list[0].Name)
+
+	result, err = brick.Insert(&TestEnumTable{Name: "c", Status: "unknown"})
+	assert.Nil(t, err)
+	assert.NotNil(t, result.Err())
+}
+
+func TestConstraintCreateTable(t *testing.T) {
+	type TestConstraintTableDetail struct {
+		ID                    uint32 `toyorm:"primary key;auto_increment"`
+		TestConstraintTableID uint32 `toyorm:"foreign key:on_delete=cascade"`
+		Count                 int    `toyorm:"check:count >= 0"`
+	}
+	type TestConstraintTableTag struct {
+		ID   uint32 `toyorm:"primary key;auto_increment"`
+		Name string
+	}
+	type TestConstraintTable struct {
+		ID      uint32  `toyorm:"primary key;auto_increment"`
+		Price   float64 `check:"price > 0"`
+		Details []TestConstraintTableDetail
+		Tags    []TestConstraintTableTag `toyorm:"foreign key:on_delete=cascade"`
+	}
+	var tab TestConstraintTable
+	brick := TestDB.Model(&tab).
+		Preload(Offsetof(tab.Details)).Enter().
+		Preload(Offsetof(tab.Tags)).Enter()
+	// association table have the container referential action
+	middleModel := brick.ManyToManyPreload["Tags"].MiddleModel
+	for _, field := range middleModel.GetSqlFields() {
+		assert.True(t, field.IsForeign())
+		assert.Equal(t, "CASCADE", field.OnDelete())
+	}
+	createTableUnit(brick)(t)
+
+	data := TestConstraintTable{
+		Price:   10,
+		Details: []TestConstraintTableDetail{{Count: 1}, {Count: 2}},
+		Tags:    []TestConstraintTableTag{{Name: "a"}},
+	}
+	result, err := brick.Insert(&data)
+	resultProcessor(result, err)(t)
+
+	result, err = brick.Insert(&TestConstraintTable{Price: -1})
+	assert.Nil(t, err)
+	assert.NotNil(t, result.Err())
+	result, err = TestDB.Model(&TestConstraintTableDetail{}).Insert(&TestConstraintTableDetail{TestConstraintTableID: data.ID, Count: -1})
+	assert.Nil(t, err)
+	assert.NotNil(t, result.Err())
+
+	// sqlite foreign key only work with foreign_keys pragma
+	if _, ok := TestDB.Dialect.(Sqlite3Dialect); ok {
+		t.Skip("sqlite foreign key constraint is disabled by default")
+	}
+	result, err = TestDB.Model(&tab).Delete(&data)
+	resultProcessor(result, err)(t)
+	count, err := TestDB.Model(&TestConstraintTableDetail{}).Where(ExprEqual, Offsetof(TestConstraintTableDetail{}.TestConstraintTableID), data.ID).Count()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+	count, err = NewToyBrick(TestDB, middleModel).Count()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
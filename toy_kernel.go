@@ -9,6 +9,7 @@ package toyorm
 import (
 	"io"
 	"reflect"
+	"sync"
 )
 
 type CacheMeta struct {
@@ -20,15 +21,96 @@ type ToyKernel struct {
 	// map[model][container_field_name]
 	Dialect Dialect
 	Logger  io.Writer
+	// model meta cache, nil will create model every time
+	models *modelCache
+}
+
+type modelCacheKey struct {
+	Type reflect.Type
+	Name string
+}
+
+var tablerType = reflect.TypeOf((*tabler)(nil)).Elem()
+
+// model meta cache, the same struct type with different TableName() is different model
+type modelCache struct {
+	mu     sync.RWMutex
+	models map[modelCacheKey]*Model
+	// the model have preload field which sub model name is dynamic, it cannot cache
+	dynamic map[reflect.Type]bool
+}
+
+func newModelCache() *modelCache {
+	return &modelCache{
+		models:  map[modelCacheKey]*Model{},
+		dynamic: map[reflect.Type]bool{},
+	}
+}
+
+func (c *modelCache) get(val reflect.Value, name string) *Model {
+	key := modelCacheKey{val.Type(), name}
+	c.mu.RLock()
+	model, dynamic := c.models[key], c.dynamic[key.Type]
+	c.mu.RUnlock()
+	if model != nil {
+		return model
+	}
+	if dynamic {
+		return newModel(val, name)
+	}
+	// sub model name of preload is got from the preload field value, so
+	// use it to check dynamic name
+	model = newModel(val, name)
+	for fieldType := range model.StructFieldFields {
+		if fieldType.Implements(tablerType) || reflect.PtrTo(fieldType).Implements(tablerType) {
+			c.mu.Lock()
+			c.dynamic[key.Type] = true
+			c.mu.Unlock()
+			return model
+		}
+	}
+	// use zero value to create cache model, avoid the model hold the user's data
+	model = newModel(reflect.New(key.Type).Elem(), name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached := c.models[key]; cached != nil {
+		return cached
+	}
+	c.models[key] = model
+	return model
 }
 
-// TODO testing thread safe? if not add lock
 func (t *ToyKernel) GetModel(val reflect.Value) *Model {
 	if val.Kind() != reflect.Struct {
 		panic(ErrInvalidModelType("invalid struct type " + val.Type().Name()))
 	}
 	name := ModelName(val)
-	return newModel(val, name)
+	if t.models == nil {
+		return newModel(val, name)
+	}
+	return t.models.get(val, name)
+}
+
+// create the model meta of v and it's preload container field's model in advance,
+// v can be struct or slice of struct
+func (t *ToyKernel) RegisterModels(v ...interface{}) {
+	registered := map[reflect.Type]bool{}
+	for _, m := range v {
+		t.registerModel(LoopDiveSliceAndPtr(reflect.ValueOf(m)), registered)
+	}
+}
+
+func (t *ToyKernel) registerModel(val reflect.Value, registered map[reflect.Type]bool) {
+	if registered[val.Type()] {
+		return
+	}
+	registered[val.Type()] = true
+	model := t.GetModel(val)
+	for fieldType := range model.StructFieldFields {
+		if fieldType.Kind() == reflect.Struct && fieldType.Name() != "" {
+			t.registerModel(reflect.New(fieldType).Elem(), registered)
+		}
+	}
 }
 
 func (t *ToyKernel) SetDebug(debug bool) {
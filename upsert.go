@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018. bigpigeon. All rights reserved.
+ * Use of this source code is governed by a MIT style
+ * license that can be found in the LICENSE file.
+ */
+
+package toyorm
+
+import (
+	"regexp"
+)
+
+type UpsertAction int
+
+const (
+	// update column with the insert value
+	UpsertOverwrite UpsertAction = iota
+	// keep the old column value
+	UpsertKeep
+	// update column with expression, use EXCLUDED.column to get the insert value,
+	// the old value must be qualified with table name, otherwise it's ambiguous in postgres
+	// e.g "data.count + EXCLUDED.count"
+	UpsertExpr
+)
+
+// the update strategy of column when upsert conflict
+type UpsertStrategy struct {
+	Action UpsertAction
+	Expr   string
+	Args   []interface{}
+}
+
+var excludedColumnRegexp = regexp.MustCompile(`(?i)\bEXCLUDED\.(\w+)`)
+
+// mysql have not EXCLUDED, use VALUES(column) to replace it
+func excludedToValues(expr string) string {
+	return excludedColumnRegexp.ReplaceAllString(expr, "VALUES($1)")
+}
+
+// conflict fields must be primary key or a unique index
+func conflictFieldsCheck(model *Model, fields []Field) bool {
+	sameFields := func(indexFields []Field) bool {
+		if len(indexFields) != len(fields) {
+			return false
+		}
+		nameSet := map[string]bool{}
+		for _, f := range indexFields {
+			nameSet[f.Name()] = true
+		}
+		for _, f := range fields {
+			if nameSet[f.Name()] == false {
+				return false
+			}
+		}
+		return true
+	}
+	if sameFields(model.GetPrimary()) {
+		return true
+	}
+	for _, indexFields := range model.GetUniqueIndexMap() {
+		if sameFields(indexFields) {
+			return true
+		}
+	}
+	return false
+}